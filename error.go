@@ -0,0 +1,15 @@
+package main
+
+import "github.com/giantswarm/microerror"
+
+// failedError is returned by the commands which ran but found problems they
+// already printed, like violations or differences, so that main exits with
+// status 1 instead of panicking.
+var failedError = &microerror.Error{
+	Kind: "failedError",
+}
+
+// IsFailed asserts failedError.
+func IsFailed(err error) bool {
+	return microerror.Cause(err) == failedError
+}
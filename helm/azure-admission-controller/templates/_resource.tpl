@@ -22,6 +22,10 @@ room for such suffix.
 {{- include "resource.default.name" . -}}-pull-secret
 {{- end -}}
 
+{{- define "resource.vmSKUSnapshot.name" -}}
+{{- .Values.azure.vmSKUSnapshot.persistentVolumeClaim | default (printf "%s-vm-skus" (include "resource.default.name" .)) -}}
+{{- end -}}
+
 {{- define "resource.default.namespace" -}}
 giantswarm
 {{- end -}}
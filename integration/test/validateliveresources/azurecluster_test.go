@@ -71,7 +71,6 @@ func TestAzureClusterWebhookHandler(t *testing.T) {
 		c := azureclusterpkg.WebhookHandlerConfig{
 			BaseDomain: env.BaseDomain(),
 			Decoder:    NewDecoder(),
-			CtrlClient: ctrlClient,
 			Location:   env.Location(),
 			CtrlReader: ctrlClient,
 			Logger:     logger,
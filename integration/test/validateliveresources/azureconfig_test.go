@@ -68,7 +68,7 @@ func TestAzureConfigWebhookHandler(t *testing.T) {
 	{
 		c := azureupdate.AzureConfigWebhookHandlerConfig{
 			Decoder:    NewDecoder(),
-			CtrlClient: ctrlClient,
+			CtrlReader: ctrlClient,
 			Logger:     logger,
 		}
 		azureConfigWebhookHandler, err = azureupdate.NewAzureConfigWebhookHandler(c)
@@ -70,7 +70,7 @@ func TestAzureMachineWebhookHandler(t *testing.T) {
 	{
 		c := azuremachinepkg.WebhookHandlerConfig{
 			Decoder:    NewDecoder(),
-			CtrlClient: ctrlClient,
+			CtrlReader: ctrlClient,
 			Location:   env.Location(),
 			Logger:     logger,
 			VMcaps:     NewVMCapabilities(t, logger),
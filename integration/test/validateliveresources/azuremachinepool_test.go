@@ -68,7 +68,7 @@ func TestAzureMachinePoolWebhookHandler(t *testing.T) {
 	var azureMachinePoolWebhookHandler *azuremachinepoolpkg.WebhookHandler
 	{
 		c := azuremachinepoolpkg.WebhookHandlerConfig{
-			CtrlClient: ctrlClient,
+			CtrlReader: ctrlClient,
 			Decoder:    NewDecoder(),
 			Location:   env.Location(),
 			Logger:     logger,
@@ -62,7 +62,6 @@ func TestClusterWebhookHandler(t *testing.T) {
 		c := clusterpkg.WebhookHandlerConfig{
 			BaseDomain: env.BaseDomain(),
 			Decoder:    NewDecoder(),
-			CtrlClient: ctrlClient,
 			CtrlReader: ctrlClient,
 			Logger:     logger,
 		}
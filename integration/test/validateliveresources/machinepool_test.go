@@ -68,7 +68,7 @@ func TestMachinePoolWebhookHandler(t *testing.T) {
 	var machinePoolWebhookHandler *machinepoolpkg.WebhookHandler
 	{
 		c := machinepoolpkg.WebhookHandlerConfig{
-			CtrlClient: ctrlClient,
+			CtrlReader: ctrlClient,
 			Decoder:    NewDecoder(),
 			Logger:     logger,
 			VMcaps:     NewVMCapabilities(t, logger),
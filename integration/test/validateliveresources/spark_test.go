@@ -69,7 +69,7 @@ func TestSparkWebhookHandler(t *testing.T) {
 	{
 		c := sparkpkg.WebhookHandlerConfig{
 			Decoder:    NewDecoder(),
-			CtrlClient: ctrlClient,
+			CtrlReader: ctrlClient,
 			Logger:     logger,
 		}
 		sparkWebhookHandler, err = sparkpkg.NewWebhookHandler(c)
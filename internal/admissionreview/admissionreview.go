@@ -0,0 +1,151 @@
+// Package admissionreview decodes and encodes AdmissionReview objects of all
+// the admission.k8s.io versions the webhooks are registered for.
+//
+// Requests are always handed out as admission.k8s.io/v1 types, so that the
+// HTTP handlers do not have to care about the version the API server sent.
+// Responses are encoded with the apiVersion of the request, as required by
+// the API server.
+package admissionreview
+
+import (
+	"encoding/json"
+
+	"github.com/giantswarm/microerror"
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+)
+
+const (
+	kind = "AdmissionReview"
+)
+
+var (
+	// V1 is the apiVersion of admission.k8s.io/v1 AdmissionReview objects.
+	V1 = admissionv1.SchemeGroupVersion.String()
+	// V1beta1 is the apiVersion of admission.k8s.io/v1beta1 AdmissionReview
+	// objects.
+	V1beta1 = admissionv1beta1.SchemeGroupVersion.String()
+)
+
+var (
+	scheme       = runtime.NewScheme()
+	codecs       = serializer.NewCodecFactory(scheme)
+	deserializer = codecs.UniversalDeserializer()
+)
+
+func init() {
+	utilruntime.Must(admissionv1.AddToScheme(scheme))
+	utilruntime.Must(admissionv1beta1.AddToScheme(scheme))
+}
+
+// Decode decodes the AdmissionReview in data and returns its request together
+// with the apiVersion the review was sent with. admission.k8s.io/v1beta1
+// requests are converted to admission.k8s.io/v1.
+func Decode(data []byte) (*admissionv1.AdmissionRequest, string, error) {
+	obj, gvk, err := deserializer.Decode(data, nil, nil)
+	if err != nil {
+		return nil, "", microerror.Mask(err)
+	}
+
+	var request *admissionv1.AdmissionRequest
+	switch review := obj.(type) {
+	case *admissionv1.AdmissionReview:
+		request = review.Request
+	case *admissionv1beta1.AdmissionReview:
+		request = fromV1beta1(review.Request)
+	default:
+		return nil, "", microerror.Maskf(unsupportedVersionError, "unsupported object %s", gvk.String())
+	}
+
+	if request == nil {
+		return nil, "", microerror.Maskf(invalidReviewError, "%s has no request", gvk.String())
+	}
+
+	return request, gvk.GroupVersion().String(), nil
+}
+
+// Encode returns the JSON encoding of an AdmissionReview with the specified
+// apiVersion carrying the specified response and warnings. The wire format of
+// the response is the same for admission.k8s.io/v1 and
+// admission.k8s.io/v1beta1, so only the apiVersion differs.
+func Encode(version string, response *admissionv1.AdmissionResponse, warnings []string) ([]byte, error) {
+	if version != V1 && version != V1beta1 {
+		return nil, microerror.Maskf(unsupportedVersionError, "unsupported apiVersion %q", version)
+	}
+
+	data, err := json.Marshal(review{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       kind,
+			APIVersion: version,
+		},
+		Response: &responseWithWarnings{
+			AdmissionResponse: response,
+			Warnings:          warnings,
+		},
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return data, nil
+}
+
+// DecodeResponse decodes the AdmissionReview response in data, as encoded by
+// Encode, and returns its response together with its warnings.
+func DecodeResponse(data []byte) (*admissionv1.AdmissionResponse, []string, error) {
+	var r review
+	err := json.Unmarshal(data, &r)
+	if err != nil {
+		return nil, nil, microerror.Maskf(invalidReviewError, "%s", err.Error())
+	}
+
+	if r.APIVersion != V1 && r.APIVersion != V1beta1 {
+		return nil, nil, microerror.Maskf(unsupportedVersionError, "unsupported apiVersion %q", r.APIVersion)
+	}
+	if r.Response == nil || r.Response.AdmissionResponse == nil {
+		return nil, nil, microerror.Maskf(invalidReviewError, "%s has no response", r.APIVersion)
+	}
+
+	return r.Response.AdmissionResponse, r.Response.Warnings, nil
+}
+
+// review is the admission.k8s.io/v1 AdmissionReview as it is sent back to
+// the API server. The k8s.io/api version we depend on predates the warnings
+// field of the AdmissionResponse, so it is added here.
+type review struct {
+	metav1.TypeMeta `json:",inline"`
+	Response        *responseWithWarnings `json:"response,omitempty"`
+}
+
+type responseWithWarnings struct {
+	*admissionv1.AdmissionResponse
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+func fromV1beta1(request *admissionv1beta1.AdmissionRequest) *admissionv1.AdmissionRequest {
+	if request == nil {
+		return nil
+	}
+
+	return &admissionv1.AdmissionRequest{
+		UID:                request.UID,
+		Kind:               request.Kind,
+		Resource:           request.Resource,
+		SubResource:        request.SubResource,
+		RequestKind:        request.RequestKind,
+		RequestResource:    request.RequestResource,
+		RequestSubResource: request.RequestSubResource,
+		Name:               request.Name,
+		Namespace:          request.Namespace,
+		Operation:          admissionv1.Operation(request.Operation),
+		UserInfo:           request.UserInfo,
+		Object:             request.Object,
+		OldObject:          request.OldObject,
+		DryRun:             request.DryRun,
+		Options:            request.Options,
+	}
+}
@@ -0,0 +1,219 @@
+package admissionreview
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func Test_Decode(t *testing.T) {
+	testCases := []struct {
+		name            string
+		review          string
+		expectedVersion string
+		errorMatcher    func(error) bool
+	}{
+		{
+			name:            "case 0: admission.k8s.io/v1 review",
+			review:          `{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview","request":{"uid":"abc","operation":"CREATE","name":"ab123","dryRun":true}}`,
+			expectedVersion: V1,
+		},
+		{
+			name:            "case 1: admission.k8s.io/v1beta1 review",
+			review:          `{"apiVersion":"admission.k8s.io/v1beta1","kind":"AdmissionReview","request":{"uid":"abc","operation":"CREATE","name":"ab123","dryRun":true}}`,
+			expectedVersion: V1beta1,
+		},
+		{
+			name:         "case 2: review without request",
+			review:       `{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview"}`,
+			errorMatcher: IsInvalidReview,
+		},
+		{
+			name:         "case 3: review without apiVersion",
+			review:       `{"kind":"AdmissionReview","request":{"uid":"abc"}}`,
+			errorMatcher: func(err error) bool { return err != nil },
+		},
+		{
+			name:         "case 4: unknown apiVersion",
+			review:       `{"apiVersion":"admission.k8s.io/v2","kind":"AdmissionReview","request":{"uid":"abc"}}`,
+			errorMatcher: func(err error) bool { return err != nil },
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			request, version, err := Decode([]byte(tc.review))
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if tc.errorMatcher != nil {
+				return
+			}
+
+			if version != tc.expectedVersion {
+				t.Fatalf("version == %q, want %q", version, tc.expectedVersion)
+			}
+			if request.UID != types.UID("abc") {
+				t.Fatalf("request.UID == %q, want %q", request.UID, "abc")
+			}
+			if request.Operation != admissionv1.Create {
+				t.Fatalf("request.Operation == %q, want %q", request.Operation, admissionv1.Create)
+			}
+			if request.Name != "ab123" {
+				t.Fatalf("request.Name == %q, want %q", request.Name, "ab123")
+			}
+			if request.DryRun == nil || !*request.DryRun {
+				t.Fatalf("request.DryRun == %v, want true", request.DryRun)
+			}
+		})
+	}
+}
+
+func Test_Encode(t *testing.T) {
+	testCases := []struct {
+		name         string
+		version      string
+		warnings     []string
+		errorMatcher func(error) bool
+	}{
+		{
+			name:    "case 0: admission.k8s.io/v1 response",
+			version: V1,
+		},
+		{
+			name:     "case 1: admission.k8s.io/v1 response with warnings",
+			version:  V1,
+			warnings: []string{"VM size is small"},
+		},
+		{
+			name:    "case 2: admission.k8s.io/v1beta1 response",
+			version: V1beta1,
+		},
+		{
+			name:         "case 3: unsupported apiVersion",
+			version:      "admission.k8s.io/v2",
+			errorMatcher: IsUnsupportedVersion,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := Encode(tc.version, &admissionv1.AdmissionResponse{UID: "abc", Allowed: true}, tc.warnings)
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if tc.errorMatcher != nil {
+				return
+			}
+
+			var review admissionv1.AdmissionReview
+			err = json.Unmarshal(data, &review)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var warnings struct {
+				Response struct {
+					Warnings []string `json:"warnings"`
+				} `json:"response"`
+			}
+			err = json.Unmarshal(data, &warnings)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(warnings.Response.Warnings, tc.warnings) {
+				t.Fatalf("warnings == %v, want %v", warnings.Response.Warnings, tc.warnings)
+			}
+
+			if review.APIVersion != tc.version {
+				t.Fatalf("apiVersion == %q, want %q", review.APIVersion, tc.version)
+			}
+			if review.Kind != "AdmissionReview" {
+				t.Fatalf("kind == %q, want %q", review.Kind, "AdmissionReview")
+			}
+			if review.Response == nil || review.Response.UID != "abc" || !review.Response.Allowed {
+				t.Fatalf("response == %#v, want allowed response for uid abc", review.Response)
+			}
+		})
+	}
+}
+
+func Test_DecodeResponse(t *testing.T) {
+	testCases := []struct {
+		name         string
+		data         string
+		allowed      bool
+		warnings     []string
+		errorMatcher func(error) bool
+	}{
+		{
+			name:     "case 0: admission.k8s.io/v1 response with warnings",
+			data:     `{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview","response":{"uid":"abc","allowed":true,"warnings":["VM size is small"]}}`,
+			allowed:  true,
+			warnings: []string{"VM size is small"},
+		},
+		{
+			name: "case 1: admission.k8s.io/v1beta1 denial",
+			data: `{"apiVersion":"admission.k8s.io/v1beta1","kind":"AdmissionReview","response":{"uid":"abc","allowed":false,"status":{"code":400}}}`,
+		},
+		{
+			name:         "case 2: no response",
+			data:         `{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview"}`,
+			errorMatcher: IsInvalidReview,
+		},
+		{
+			name:         "case 3: unsupported apiVersion",
+			data:         `{"apiVersion":"admission.k8s.io/v2","kind":"AdmissionReview","response":{"uid":"abc","allowed":true}}`,
+			errorMatcher: IsUnsupportedVersion,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			response, warnings, err := DecodeResponse([]byte(tc.data))
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if tc.errorMatcher != nil {
+				return
+			}
+
+			if response.Allowed != tc.allowed {
+				t.Fatalf("Allowed == %t, want %t", response.Allowed, tc.allowed)
+			}
+			if !reflect.DeepEqual(warnings, tc.warnings) {
+				t.Fatalf("warnings == %#v, want %#v", warnings, tc.warnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package admissionreview
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+var invalidReviewError = &microerror.Error{
+	Kind: "invalidReviewError",
+}
+
+// IsInvalidReview asserts invalidReviewError.
+func IsInvalidReview(err error) bool {
+	return microerror.Cause(err) == invalidReviewError
+}
+
+var unsupportedVersionError = &microerror.Error{
+	Kind: "unsupportedVersionError",
+}
+
+// IsUnsupportedVersion asserts unsupportedVersionError.
+func IsUnsupportedVersion(err error) bool {
+	return microerror.Cause(err) == unsupportedVersionError
+}
@@ -0,0 +1,86 @@
+package errors
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/giantswarm/microerror"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// statusReasons maps error kinds to the status reason a request denied with
+// them is answered with. It is only written from init functions, so it is
+// not guarded.
+var statusReasons = map[*microerror.Error]metav1.StatusReason{}
+
+// RegisterStatusReason sets the status reason for requests denied with any
+// of the specified error kinds. Error kinds which are not registered are
+// reported as metav1.StatusReasonInvalid. It must only be called from init
+// functions.
+func RegisterStatusReason(reason metav1.StatusReason, kinds ...*microerror.Error) {
+	for _, kind := range kinds {
+		statusReasons[kind] = reason
+	}
+}
+
+func init() {
+	RegisterStatusReason(metav1.StatusReasonBadRequest, ParsingFailedError, WrongTypeError)
+	RegisterStatusReason(metav1.StatusReasonForbidden, InvalidConditionModificationError)
+	RegisterStatusReason(metav1.StatusReasonConflict, InvalidOperationError)
+}
+
+// StatusReason returns the status reason for a request denied with the
+// specified error. Errors of a known kind are reported with the registered
+// reason or metav1.StatusReasonInvalid, API status errors keep their own
+// reason and all other errors are reported as internal errors.
+func StatusReason(err error) metav1.StatusReason {
+	if kind, ok := microerror.Cause(err).(*microerror.Error); ok {
+		if reason, ok := statusReasons[kind]; ok {
+			return reason
+		}
+		return metav1.StatusReasonInvalid
+	}
+
+	var status apierrors.APIStatus
+	if errors.As(err, &status) && status.Status().Reason != metav1.StatusReasonUnknown {
+		return status.Status().Reason
+	}
+
+	return metav1.StatusReasonInternalError
+}
+
+// StatusCode returns the HTTP status code matching the specified status
+// reason.
+func StatusCode(reason metav1.StatusReason) int32 {
+	switch reason {
+	case metav1.StatusReasonBadRequest:
+		return http.StatusBadRequest
+	case metav1.StatusReasonForbidden:
+		return http.StatusForbidden
+	case metav1.StatusReasonNotFound:
+		return http.StatusNotFound
+	case metav1.StatusReasonConflict:
+		return http.StatusConflict
+	case metav1.StatusReasonInvalid:
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// ErrorCode returns a stable, machine-readable code for the specified error.
+// It is the microerror kind for errors of a known kind, the status reason for
+// API status errors and empty otherwise.
+func ErrorCode(err error) string {
+	if kind, ok := microerror.Cause(err).(*microerror.Error); ok {
+		return kind.Kind
+	}
+
+	var status apierrors.APIStatus
+	if errors.As(err, &status) {
+		return string(status.Status().Reason)
+	}
+
+	return ""
+}
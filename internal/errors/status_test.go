@@ -0,0 +1,83 @@
+package errors
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/giantswarm/microerror"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var testWasChangedError = &microerror.Error{
+	Kind: "testWasChangedError",
+}
+
+func Test_StatusReason(t *testing.T) {
+	RegisterStatusReason(metav1.StatusReasonForbidden, testWasChangedError)
+
+	testCases := []struct {
+		name           string
+		err            error
+		expectedReason metav1.StatusReason
+		expectedCode   int32
+		expectedError  string
+	}{
+		{
+			name:           "case 0: registered error kind",
+			err:            microerror.Maskf(testWasChangedError, "field can't be changed"),
+			expectedReason: metav1.StatusReasonForbidden,
+			expectedCode:   http.StatusForbidden,
+			expectedError:  "testWasChangedError",
+		},
+		{
+			name:           "case 1: unregistered error kind",
+			err:            microerror.Maskf(NotFoundError, "release not found"),
+			expectedReason: metav1.StatusReasonInvalid,
+			expectedCode:   http.StatusUnprocessableEntity,
+			expectedError:  "notFoundError",
+		},
+		{
+			name:           "case 2: state-based refusal",
+			err:            microerror.Maskf(InvalidOperationError, "cluster is already being upgraded"),
+			expectedReason: metav1.StatusReasonConflict,
+			expectedCode:   http.StatusConflict,
+			expectedError:  "invalidOperationError",
+		},
+		{
+			name:           "case 3: API status error",
+			err:            microerror.Mask(apierrors.NewForbidden(schema.GroupResource{Resource: "clusters"}, "ab123", errors.New("forbidden"))),
+			expectedReason: metav1.StatusReasonForbidden,
+			expectedCode:   http.StatusForbidden,
+			expectedError:  "Forbidden",
+		},
+		{
+			name:           "case 4: arbitrary error",
+			err:            microerror.Mask(errors.New("connection refused")),
+			expectedReason: metav1.StatusReasonInternalError,
+			expectedCode:   http.StatusInternalServerError,
+			expectedError:  "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reason := StatusReason(tc.err)
+			if reason != tc.expectedReason {
+				t.Fatalf("reason == %q, want %q", reason, tc.expectedReason)
+			}
+
+			code := StatusCode(reason)
+			if code != tc.expectedCode {
+				t.Fatalf("code == %d, want %d", code, tc.expectedCode)
+			}
+
+			errorCode := ErrorCode(tc.err)
+			if errorCode != tc.expectedError {
+				t.Fatalf("error code == %q, want %q", errorCode, tc.expectedError)
+			}
+		})
+	}
+}
@@ -2,6 +2,9 @@ package releaseversion
 
 import (
 	"github.com/giantswarm/microerror"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/azure-admission-controller/internal/errors"
 )
 
 var releaseNotFoundError = &microerror.Error{
@@ -39,3 +42,11 @@ var skippingReleaseError = &microerror.Error{
 func IsSkippingReleaseError(err error) bool {
 	return microerror.Cause(err) == skippingReleaseError
 }
+
+func init() {
+	errors.RegisterStatusReason(metav1.StatusReasonForbidden,
+		downgradingIsNotAllowedError,
+		upgradingToOrFromAlphaReleaseError,
+		skippingReleaseError,
+	)
+}
@@ -16,12 +16,12 @@ const (
 	ignoreReleaseAnnotation = "release.giantswarm.io/ignore"
 )
 
-func Validate(ctx context.Context, ctrlCLient client.Client, oldVersion semver.Version, newVersion semver.Version) error {
+func Validate(ctx context.Context, ctrlReader client.Reader, oldVersion semver.Version, newVersion semver.Version) error {
 	if oldVersion.Equals(newVersion) {
 		return nil
 	}
 
-	availableReleases, err := availableReleases(ctx, ctrlCLient)
+	availableReleases, err := availableReleases(ctx, ctrlReader)
 	if err != nil {
 		return err
 	}
@@ -68,10 +68,10 @@ func Validate(ctx context.Context, ctrlCLient client.Client, oldVersion semver.V
 	return nil
 }
 
-func availableReleases(ctx context.Context, ctrlClient client.Client) ([]*release, error) {
+func availableReleases(ctx context.Context, ctrlReader client.Reader) ([]*release, error) {
 	var releases []*release
 	releaseList := &v1alpha1.ReleaseList{}
-	err := ctrlClient.List(ctx, releaseList)
+	err := ctrlReader.List(ctx, releaseList)
 	if err != nil {
 		return []*release{}, microerror.Mask(err)
 	}
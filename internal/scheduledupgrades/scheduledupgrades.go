@@ -15,9 +15,14 @@ import (
 	"github.com/giantswarm/azure-admission-controller/internal/errors"
 	"github.com/giantswarm/azure-admission-controller/internal/releaseversion"
 	"github.com/giantswarm/azure-admission-controller/internal/semverhelper"
+	"github.com/giantswarm/azure-admission-controller/pkg/generic"
 )
 
-func ValidateClusterAnnotationUpgradeTime(oldCluster *capi.Cluster, newCluster *capi.Cluster) error {
+// upgradeTimeWarningThreshold is how far in the future a scheduled upgrade
+// has to be to not get a warning about it happening soon.
+const upgradeTimeWarningThreshold = 24 * time.Hour
+
+func ValidateClusterAnnotationUpgradeTime(ctx context.Context, oldCluster *capi.Cluster, newCluster *capi.Cluster) error {
 	if updateTime, ok := newCluster.GetAnnotations()[annotation.UpdateScheduleTargetTime]; ok {
 		if oldCluster != nil {
 			if updateTimeOld, ok := oldCluster.GetAnnotations()[annotation.UpdateScheduleTargetTime]; ok {
@@ -33,6 +38,12 @@ func ValidateClusterAnnotationUpgradeTime(oldCluster *capi.Cluster, newCluster *
 					updateTime),
 			)
 		}
+
+		// The time has already been validated above, so it can be parsed.
+		t, _ := time.Parse(time.RFC822, updateTime)
+		if t.Before(time.Now().UTC().Add(upgradeTimeWarningThreshold)) {
+			generic.AddWarning(ctx, "Cluster annotation '%s' value '%s' schedules the upgrade less than a day from now.", annotation.UpdateScheduleTargetTime, updateTime)
+		}
 	}
 	return nil
 }
@@ -58,7 +69,7 @@ func ValidateUpgradeScheduleTime(updateTime string) bool {
 	return true
 }
 
-func ValidateClusterAnnotationUpgradeRelease(ctx context.Context, client client.Client, cluster *capi.Cluster) error {
+func ValidateClusterAnnotationUpgradeRelease(ctx context.Context, ctrlReader client.Reader, cluster *capi.Cluster) error {
 	if targetRelease, ok := cluster.GetAnnotations()[annotation.UpdateScheduleTargetRelease]; ok {
 		oldVersion, err := semverhelper.GetSemverFromLabels(cluster.Labels)
 		if err != nil {
@@ -70,7 +81,7 @@ func ValidateClusterAnnotationUpgradeRelease(ctx context.Context, client client.
 			return microerror.Mask(err)
 		}
 
-		err = releaseversion.Validate(ctx, client, oldVersion, newVersion)
+		err = releaseversion.Validate(ctx, ctrlReader, oldVersion, newVersion)
 		if err != nil {
 			return microerror.Maskf(notAllowedError,
 				fmt.Sprintf("Cluster annotation '%s' value '%s' is not valid. Value must be an existing giant swarm release version above the current release version %s and must not have a v prefix. %v",
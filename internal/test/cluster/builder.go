@@ -31,6 +31,16 @@ func Labels(labels map[string]string) BuilderOption {
 	}
 }
 
+func Annotation(name, val string) BuilderOption {
+	return func(cluster *capi.Cluster) *capi.Cluster {
+		if cluster.Annotations == nil {
+			cluster.Annotations = map[string]string{}
+		}
+		cluster.Annotations[name] = val
+		return cluster
+	}
+}
+
 func ControlPlaneEndpoint(controlPlaneEndpointHost string, controlPlaneEndpointPort int32) BuilderOption {
 	return func(cluster *capi.Cluster) *capi.Cluster {
 		cluster.Spec.ControlPlaneEndpoint.Host = controlPlaneEndpointHost
@@ -5,6 +5,8 @@ import (
 
 	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2019-07-01/compute"
 	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/azure-admission-controller/pkg/tracing"
 )
 
 type Azure struct {
@@ -20,10 +22,14 @@ func NewAzureAPI(c AzureConfig) API {
 }
 
 func (a *Azure) List(ctx context.Context, filter string) (map[string]compute.ResourceSku, error) {
+	ctx, span := tracing.StartClient(ctx, "azure ResourceSkus.List", tracing.String("azure.filter", filter))
+	defer span.End()
+
 	skus := map[string]compute.ResourceSku{}
 
 	iterator, err := a.resourceSkuClient.ListComplete(ctx, filter)
 	if err != nil {
+		span.RecordError(err)
 		return skus, microerror.Mask(err)
 	}
 
@@ -33,6 +39,7 @@ func (a *Azure) List(ctx context.Context, filter string) (map[string]compute.Res
 
 		err := iterator.NextWithContext(ctx)
 		if err != nil {
+			span.RecordError(err)
 			return skus, microerror.Mask(err)
 		}
 	}
@@ -0,0 +1,118 @@
+package vmcapabilities_test
+
+import (
+	"context"
+	"io/ioutil"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2019-07-01/compute"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/azure-admission-controller/internal/vmcapabilities"
+	"github.com/giantswarm/azure-admission-controller/pkg/unittest"
+)
+
+// slowAPI counts the calls to the Azure API, and takes a while to answer
+// them, so that concurrent requests overlap.
+type slowAPI struct {
+	api   vmcapabilities.API
+	calls int64
+}
+
+func (a *slowAPI) List(ctx context.Context, filter string) (map[string]compute.ResourceSku, error) {
+	atomic.AddInt64(&a.calls, 1)
+	time.Sleep(50 * time.Millisecond)
+	return a.api.List(ctx, filter)
+}
+
+// Test_VMSKU_Concurrency must be run with -race.
+func Test_VMSKU_Concurrency(t *testing.T) {
+	testCases := []struct {
+		name string
+		// refresh makes every other goroutine refresh the SKUs instead of
+		// reading them.
+		refresh       bool
+		expectedCalls int64
+	}{
+		{
+			name:          "case 0: concurrent first reads are collapsed into one call",
+			expectedCalls: 1,
+		},
+		{
+			name:    "case 1: concurrent reads and refreshes",
+			refresh: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			logger, err := micrologger.New(micrologger.Config{IOWriter: ioutil.Discard})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			api := &slowAPI{
+				api: unittest.NewResourceSkuStubAPI(map[string]compute.ResourceSku{
+					"Standard_D4s_v3": {
+						Name: to.StringPtr("Standard_D4s_v3"),
+						Capabilities: &[]compute.ResourceSkuCapabilities{
+							{Name: to.StringPtr("vCPUs"), Value: to.StringPtr("4")},
+							{Name: to.StringPtr("MemoryGB"), Value: to.StringPtr("16")},
+						},
+						LocationInfo: &[]compute.ResourceSkuLocationInfo{
+							{Location: to.StringPtr("westeurope"), Zones: &[]string{"1", "2", "3"}},
+						},
+					},
+				}),
+			}
+			vmcaps, err := vmcapabilities.New(vmcapabilities.Config{Azure: api, Logger: logger})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := context.Background()
+			start := make(chan struct{})
+			var wg sync.WaitGroup
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					<-start
+
+					if tc.refresh && i%2 == 1 {
+						err := vmcaps.Refresh(ctx, "westeurope")
+						if err != nil {
+							t.Error(err)
+						}
+						vmcaps.Locations()
+						return
+					}
+
+					cpus, err := vmcaps.CPUs(ctx, "westeurope", "Standard_D4s_v3")
+					if err != nil || cpus != 4 {
+						t.Errorf("cpus == %d, error == %#v, want 4", cpus, err)
+					}
+					memory, err := vmcaps.Memory(ctx, "westeurope", "Standard_D4s_v3")
+					if err != nil || memory != 16 {
+						t.Errorf("memory == %d, error == %#v, want 16", memory, err)
+					}
+					azs, err := vmcaps.SupportedAZs(ctx, "westeurope", "Standard_D4s_v3")
+					if err != nil || len(azs) != 3 {
+						t.Errorf("azs == %v, error == %#v, want 3 zones", azs, err)
+					}
+				}(i)
+			}
+			close(start)
+			wg.Wait()
+
+			calls := atomic.LoadInt64(&api.calls)
+			if tc.expectedCalls > 0 && calls != tc.expectedCalls {
+				t.Fatalf("%d calls to the Azure API, want %d", calls, tc.expectedCalls)
+			}
+		})
+	}
+}
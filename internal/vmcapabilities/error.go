@@ -1,6 +1,20 @@
 package vmcapabilities
 
-import "github.com/giantswarm/microerror"
+import (
+	"github.com/giantswarm/microerror"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/azure-admission-controller/internal/errors"
+)
+
+var executionFailedError = &microerror.Error{
+	Kind: "executionFailedError",
+}
+
+// IsExecutionFailed asserts executionFailedError.
+func IsExecutionFailed(err error) bool {
+	return microerror.Cause(err) == executionFailedError
+}
 
 var invalidRequestError = &microerror.Error{
 	Kind: "invalidRequestError",
@@ -37,3 +51,25 @@ var skuNotFoundError = &microerror.Error{
 func IsSkuNotFoundError(err error) bool {
 	return microerror.Cause(err) == skuNotFoundError
 }
+
+var skuRestrictedError = &microerror.Error{
+	Kind: "skuRestrictedError",
+}
+
+// IsSkuRestrictedError asserts skuRestrictedError.
+func IsSkuRestrictedError(err error) bool {
+	return microerror.Cause(err) == skuRestrictedError
+}
+
+var snapshotNotFoundError = &microerror.Error{
+	Kind: "snapshotNotFoundError",
+}
+
+// IsSnapshotNotFound asserts snapshotNotFoundError.
+func IsSnapshotNotFound(err error) bool {
+	return microerror.Cause(err) == snapshotNotFoundError
+}
+
+func init() {
+	errors.RegisterStatusReason(metav1.StatusReasonInternalError, invalidUpstreamResponseError)
+}
@@ -0,0 +1,165 @@
+package vmcapabilities
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2019-07-01/compute"
+	"github.com/giantswarm/microerror"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// CatalogFormatJSON is the format of JSON catalogs.
+	CatalogFormatJSON = "json"
+	// CatalogFormatYAML is the format of YAML catalogs.
+	CatalogFormatYAML = "yaml"
+)
+
+var locationFilterRegexp = regexp.MustCompile(`^location eq '([^']*)'$`)
+
+// Catalog is the SKUs of every location, as written by MarshalCatalog.
+type Catalog map[string][]compute.ResourceSku
+
+// File serves the SKUs of a catalog file instead of the Azure API, e.g. to
+// validate manifests offline or in air-gapped environments. The catalog is a
+// JSON or YAML file, either with an array of SKUs like the one printed by
+// `az vm list-skus`, or with a Catalog of the SKUs of every location like the
+// one printed by the dump-sku-catalog command.
+type File struct {
+	// skus are the SKUs of an array catalog, which are matched with a
+	// location by their locations.
+	skus []compute.ResourceSku
+	// catalog is the SKUs of a Catalog by lowercase location.
+	catalog Catalog
+}
+
+// NewFileAPI returns an API serving the SKUs of the catalog file at the
+// specified path.
+func NewFileAPI(path string) (API, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	// JSON is valid YAML, so every catalog is converted to JSON, for the
+	// SKUs to be unmarshaled like the Azure API responses are.
+	data, err = yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "unable to parse SKU catalog %s: %s", path, err.Error())
+	}
+
+	f := &File{}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		var catalog Catalog
+		err = json.Unmarshal(data, &catalog)
+		if err == nil {
+			f.catalog = Catalog{}
+			for location, skus := range catalog {
+				f.catalog[strings.ToLower(location)] = skus
+			}
+		}
+	} else {
+		err = json.Unmarshal(data, &f.skus)
+	}
+	if err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "unable to parse SKU catalog %s: %s", path, err.Error())
+	}
+
+	return f, nil
+}
+
+// List returns the SKUs available in the location of the specified filter,
+// which must be of the form used by VMSKU, "location eq '<location>'".
+func (f *File) List(_ context.Context, filter string) (map[string]compute.ResourceSku, error) {
+	matches := locationFilterRegexp.FindStringSubmatch(filter)
+	if matches == nil {
+		return nil, microerror.Maskf(invalidRequestError, "unsupported filter %q", filter)
+	}
+	location := matches[1]
+
+	skus := map[string]compute.ResourceSku{}
+	if f.catalog != nil {
+		for _, sku := range f.catalog[strings.ToLower(location)] {
+			if sku.Name != nil {
+				skus[*sku.Name] = sku
+			}
+		}
+
+		return skus, nil
+	}
+
+	for _, sku := range f.skus {
+		if sku.Name == nil || sku.Locations == nil {
+			continue
+		}
+
+		for _, l := range *sku.Locations {
+			if strings.EqualFold(l, location) {
+				skus[*sku.Name] = sku
+				break
+			}
+		}
+	}
+
+	return skus, nil
+}
+
+// DumpCatalog returns the catalog of the SKUs the specified API serves for
+// the specified locations, e.g. to export the live catalog of the Azure API
+// for NewFileAPI.
+func DumpCatalog(ctx context.Context, api API, locations []string) (Catalog, error) {
+	catalog := Catalog{}
+	for _, location := range locations {
+		if location == "" {
+			return nil, microerror.Maskf(invalidRequestError, "location can't be empty")
+		}
+
+		skus, err := api.List(ctx, fmt.Sprintf("location eq '%s'", location))
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		var names []string
+		for name := range skus {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		catalog[location] = []compute.ResourceSku{}
+		for _, name := range names {
+			catalog[location] = append(catalog[location], skus[name])
+		}
+	}
+
+	return catalog, nil
+}
+
+// MarshalCatalog returns the specified catalog in the specified format,
+// CatalogFormatJSON or CatalogFormatYAML.
+func MarshalCatalog(catalog Catalog, format string) ([]byte, error) {
+	data, err := json.MarshalIndent(catalog, "", "  ")
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	switch format {
+	case CatalogFormatJSON:
+		return append(data, '\n'), nil
+	case CatalogFormatYAML:
+		data, err = yaml.JSONToYAML(data)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		return data, nil
+	}
+
+	return nil, microerror.Maskf(invalidRequestError, "unsupported catalog format %q", format)
+}
@@ -0,0 +1,138 @@
+package vmcapabilities
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2019-07-01/compute"
+)
+
+func Test_NewFileAPI(t *testing.T) {
+	testCases := []struct {
+		name         string
+		file         string
+		catalog      string
+		expectedSKUs map[string]int
+		expectedErr  bool
+	}{
+		{
+			name: "case 0: JSON array as printed by az vm list-skus",
+			file: "skus.json",
+			catalog: `[
+  {"name": "Standard_D4s_v3", "locations": ["westeurope"], "capabilities": [{"name": "vCPUs", "value": "4"}]},
+  {"name": "Standard_D8s_v3", "locations": ["westeurope", "germanywestcentral"]}
+]`,
+			expectedSKUs: map[string]int{"westeurope": 2, "germanywestcentral": 1, "eastus": 0},
+		},
+		{
+			name: "case 1: YAML catalog by location",
+			file: "skus.yaml",
+			catalog: `westeurope:
+- name: Standard_D4s_v3
+  capabilities:
+  - name: vCPUs
+    value: "4"
+- name: Standard_D8s_v3
+germanyWestCentral:
+- name: Standard_D8s_v3
+`,
+			expectedSKUs: map[string]int{"westeurope": 2, "germanywestcentral": 1, "eastus": 0},
+		},
+		{
+			name:        "case 2: invalid catalog",
+			file:        "skus.yaml",
+			catalog:     "westeurope: [",
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tc.file)
+			err := ioutil.WriteFile(path, []byte(tc.catalog), 0600)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			api, err := NewFileAPI(path)
+			if tc.expectedErr {
+				if !IsInvalidConfig(err) {
+					t.Fatalf("error == %#v, want invalid config", err)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			for location, expected := range tc.expectedSKUs {
+				skus, err := api.List(context.Background(), "location eq '"+location+"'")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(skus) != expected {
+					t.Fatalf("%d SKUs for location %s, want %d", len(skus), location, expected)
+				}
+			}
+
+			skus, err := api.List(context.Background(), "location eq 'westeurope'")
+			if err != nil {
+				t.Fatal(err)
+			}
+			capabilities := skus["Standard_D4s_v3"].Capabilities
+			if capabilities == nil || *(*capabilities)[0].Value != "4" {
+				t.Fatalf("capabilities == %#v, want 4 vCPUs", capabilities)
+			}
+		})
+	}
+}
+
+func Test_DumpCatalog(t *testing.T) {
+	for _, format := range []string{CatalogFormatJSON, CatalogFormatYAML} {
+		t.Run(format, func(t *testing.T) {
+			live := &fakeAPI{skus: map[string]map[string]compute.ResourceSku{
+				"westeurope":         skus("Standard_D8s_v3", "Standard_D4s_v3"),
+				"germanywestcentral": skus("Standard_D4s_v3"),
+			}}
+
+			catalog, err := DumpCatalog(context.Background(), live, []string{"westeurope", "germanywestcentral"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *catalog["westeurope"][0].Name != "Standard_D4s_v3" {
+				t.Fatalf("catalog == %#v, want SKUs sorted by name", catalog)
+			}
+
+			data, err := MarshalCatalog(catalog, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(t.TempDir(), "skus."+format)
+			err = ioutil.WriteFile(path, data, 0600)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The dumped catalog serves the same SKUs as the live one.
+			api, err := NewFileAPI(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for location, expected := range live.skus {
+				skus, err := api.List(context.Background(), "location eq '"+location+"'")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(skus) != len(expected) {
+					t.Fatalf("%d SKUs for location %s, want %d", len(skus), location, len(expected))
+				}
+				for name := range expected {
+					if _, ok := skus[name]; !ok {
+						t.Fatalf("SKU %s is missing for location %s", name, location)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package vmcapabilities
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+)
+
+// minManualRefreshInterval is how long after the SKUs of a location were
+// fetched the refresh handler refuses to fetch them again, so it can't be
+// used to flood the Azure API.
+const minManualRefreshInterval = time.Minute
+
+type RefreshHandlerConfig struct {
+	Logger micrologger.Logger
+	VMSKU  *VMSKU
+}
+
+// RefreshHandler refreshes the SKUs of the cached locations listed in the
+// location query parameters, or of every cached location when there is none,
+// on POST requests. It answers with the number of SKUs and the refresh time
+// of every location.
+type RefreshHandler struct {
+	logger micrologger.Logger
+	vmsku  *VMSKU
+}
+
+type refreshResult struct {
+	Location  string    `json:"location"`
+	SKUs      int       `json:"skus"`
+	Refreshed time.Time `json:"refreshed"`
+	Stale     bool      `json:"stale,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+func NewRefreshHandler(config RefreshHandlerConfig) (*RefreshHandler, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.VMSKU == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.VMSKU must not be empty", config)
+	}
+
+	h := &RefreshHandler{
+		logger: config.Logger,
+		vmsku:  config.VMSKU,
+	}
+
+	return h, nil
+}
+
+func (h *RefreshHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		writer.Header().Set("Allow", http.MethodPost)
+		http.Error(writer, "only POST requests refresh the VM SKU cache", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := request.Context()
+	locations := request.URL.Query()["location"]
+	if len(locations) == 0 {
+		locations = h.vmsku.Locations()
+	}
+	if len(locations) == 0 {
+		http.Error(writer, "no VM SKUs are cached yet", http.StatusNotFound)
+		return
+	}
+
+	now := time.Now()
+	for _, location := range locations {
+		refreshed, ok := h.vmsku.Refreshed(location)
+		if !ok {
+			http.Error(writer, fmt.Sprintf("VM SKUs for location %s are not cached", location), http.StatusNotFound)
+			return
+		}
+		if now.Sub(refreshed) < minManualRefreshInterval {
+			retryAfter := refreshed.Add(minManualRefreshInterval).Sub(now)
+			writer.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+			http.Error(writer, fmt.Sprintf("VM SKUs for location %s were refreshed at %s", location, refreshed.UTC().Format(time.RFC3339)), http.StatusTooManyRequests)
+			return
+		}
+	}
+
+	status := http.StatusOK
+	var results []refreshResult
+	for _, location := range locations {
+		err := h.vmsku.Refresh(ctx, location)
+		if err != nil {
+			h.logger.LogCtx(ctx, "level", "error", "message", fmt.Sprintf("unable to refresh VM SKU cache for location %s", location), "stack", microerror.JSON(err))
+			status = http.StatusBadGateway
+		} else {
+			h.logger.LogCtx(ctx, "level", "info", "message", fmt.Sprintf("refreshed VM SKU cache for location %s on request", location))
+		}
+
+		result := refreshResult{Location: location}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		if skus, ok := h.vmsku.cached(location); ok {
+			result.SKUs = len(skus)
+			result.Refreshed, _ = h.vmsku.Refreshed(location)
+			result.Stale = h.vmsku.Stale(location)
+		}
+		results = append(results, result)
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	err := json.NewEncoder(writer).Encode(results)
+	if err != nil {
+		h.logger.LogCtx(ctx, "level", "error", "message", "unable to write VM SKU cache refresh response", "stack", microerror.JSON(err))
+	}
+}
@@ -0,0 +1,144 @@
+package vmcapabilities
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2019-07-01/compute"
+	"github.com/giantswarm/micrologger"
+)
+
+func Test_RefreshHandler(t *testing.T) {
+	testCases := []struct {
+		name   string
+		method string
+		url    string
+		// cached is how long ago the SKUs of every cached location were
+		// fetched.
+		cached         map[string]time.Duration
+		err            error
+		expectedStatus int
+		expectedCalls  int
+		expectedResult []refreshResult
+	}{
+		{
+			name:           "case 0: every cached location is refreshed",
+			method:         http.MethodPost,
+			url:            "/vm-skus/refresh",
+			cached:         map[string]time.Duration{"westeurope": time.Hour, "germanywestcentral": time.Hour},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  2,
+			expectedResult: []refreshResult{
+				{Location: "germanywestcentral", SKUs: 0},
+				{Location: "westeurope", SKUs: 2},
+			},
+		},
+		{
+			name:           "case 1: the requested location is refreshed",
+			method:         http.MethodPost,
+			url:            "/vm-skus/refresh?location=westeurope",
+			cached:         map[string]time.Duration{"westeurope": time.Hour, "germanywestcentral": time.Hour},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  1,
+			expectedResult: []refreshResult{
+				{Location: "westeurope", SKUs: 2},
+			},
+		},
+		{
+			name:           "case 2: GET is not allowed",
+			method:         http.MethodGet,
+			url:            "/vm-skus/refresh",
+			cached:         map[string]time.Duration{"westeurope": time.Hour},
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:           "case 3: nothing is cached",
+			method:         http.MethodPost,
+			url:            "/vm-skus/refresh",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "case 4: the requested location is not cached",
+			method:         http.MethodPost,
+			url:            "/vm-skus/refresh?location=eastus",
+			cached:         map[string]time.Duration{"westeurope": time.Hour},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "case 5: SKUs were just refreshed",
+			method:         http.MethodPost,
+			url:            "/vm-skus/refresh",
+			cached:         map[string]time.Duration{"westeurope": time.Second},
+			expectedStatus: http.StatusTooManyRequests,
+		},
+		{
+			name:           "case 6: the Azure API fails",
+			method:         http.MethodPost,
+			url:            "/vm-skus/refresh",
+			cached:         map[string]time.Duration{"westeurope": time.Hour},
+			err:            errors.New("service unavailable"),
+			expectedStatus: http.StatusBadGateway,
+			expectedCalls:  1,
+			expectedResult: []refreshResult{
+				{Location: "westeurope", SKUs: 1, Error: "service unavailable"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			logger, err := micrologger.New(micrologger.Config{IOWriter: ioutil.Discard})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			api := &fakeAPI{
+				skus: map[string]map[string]compute.ResourceSku{"westeurope": skus("Standard_D4s_v3", "Standard_D8s_v3")},
+				err:  tc.err,
+			}
+			v := newTestVMSKU(t, api, 0)
+			for location, age := range tc.cached {
+				v.skus[location] = skus("Standard_D4s_v3")
+				v.refreshed[location] = time.Now().Add(-age)
+			}
+
+			h, err := NewRefreshHandler(RefreshHandlerConfig{Logger: logger, VMSKU: v})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			recorder := httptest.NewRecorder()
+			h.ServeHTTP(recorder, httptest.NewRequest(tc.method, tc.url, nil))
+
+			if recorder.Code != tc.expectedStatus {
+				t.Fatalf("status == %d, want %d: %s", recorder.Code, tc.expectedStatus, recorder.Body.String())
+			}
+			if api.calls != tc.expectedCalls {
+				t.Fatalf("%d calls to the Azure API, want %d", api.calls, tc.expectedCalls)
+			}
+			if tc.expectedResult == nil {
+				return
+			}
+
+			var result []refreshResult
+			err = json.Unmarshal(recorder.Body.Bytes(), &result)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result) != len(tc.expectedResult) {
+				t.Fatalf("result == %#v, want %#v", result, tc.expectedResult)
+			}
+			for i, r := range result {
+				expected := tc.expectedResult[i]
+				if r.Location != expected.Location || r.SKUs != expected.SKUs || r.Error != expected.Error || r.Refreshed.IsZero() {
+					t.Fatalf("result[%d] == %#v, want %#v", i, r, expected)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package vmcapabilities
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2019-07-01/compute"
+	"github.com/giantswarm/microerror"
+)
+
+var locationRegexp = regexp.MustCompile(`^[a-zA-Z0-9]+$`)
+
+// Snapshot persists the SKUs last fetched from the Azure API for every
+// location, so that they can be served while the Azure API is unreachable.
+type Snapshot interface {
+	// Load returns the SKUs saved for the specified location and the time
+	// they were fetched. The error matches IsSnapshotNotFound when none were
+	// saved.
+	Load(ctx context.Context, location string) (map[string]compute.ResourceSku, time.Time, error)
+	// Save replaces the SKUs saved for the specified location.
+	Save(ctx context.Context, location string, skus map[string]compute.ResourceSku, fetched time.Time) error
+}
+
+type FileSnapshotConfig struct {
+	// Dir is the directory the SKUs are saved in, one file per location, e.g.
+	// westeurope.json. It must be on a volume outliving the pod for the
+	// snapshot to be served after a restart.
+	Dir string
+}
+
+// FileSnapshot saves the SKUs of every location in a JSON file in the same
+// format as the catalog served by File. The modification time of the file is
+// the time the SKUs were fetched.
+type FileSnapshot struct {
+	dir string
+}
+
+func NewFileSnapshot(config FileSnapshotConfig) (*FileSnapshot, error) {
+	if config.Dir == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Dir must not be empty", config)
+	}
+
+	s := &FileSnapshot{
+		dir: config.Dir,
+	}
+
+	return s, nil
+}
+
+func (s *FileSnapshot) Load(_ context.Context, location string) (map[string]compute.ResourceSku, time.Time, error) {
+	path, err := s.path(location)
+	if err != nil {
+		return nil, time.Time{}, microerror.Mask(err)
+	}
+
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, time.Time{}, microerror.Maskf(snapshotNotFoundError, "no VM SKUs saved for location %s", location)
+	} else if err != nil {
+		return nil, time.Time{}, microerror.Mask(err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}, microerror.Mask(err)
+	}
+
+	var list []compute.ResourceSku
+	err = json.Unmarshal(data, &list)
+	if err != nil {
+		return nil, time.Time{}, microerror.Maskf(invalidConfigError, "unable to parse VM SKU snapshot %s: %s", path, err.Error())
+	}
+
+	skus := map[string]compute.ResourceSku{}
+	for _, sku := range list {
+		if sku.Name != nil {
+			skus[*sku.Name] = sku
+		}
+	}
+
+	return skus, info.ModTime(), nil
+}
+
+// Save writes the SKUs to a temporary file which then replaces the file of
+// the location, so that a snapshot is never read half written.
+func (s *FileSnapshot) Save(_ context.Context, location string, skus map[string]compute.ResourceSku, fetched time.Time) error {
+	path, err := s.path(location)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var names []string
+	for name := range skus {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	list := []compute.ResourceSku{}
+	for _, name := range names {
+		list = append(list, skus[name])
+	}
+
+	data, err := json.Marshal(list)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	f, err := ioutil.TempFile(s.dir, "."+location+"-*.json")
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(data)
+	if err != nil {
+		f.Close()
+		return microerror.Mask(err)
+	}
+	err = f.Close()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = os.Chtimes(f.Name(), fetched, fetched)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = os.Rename(f.Name(), path)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// path returns the file of the specified location. Locations come from the
+// objects being validated, so they are checked not to point elsewhere.
+func (s *FileSnapshot) path(location string) (string, error) {
+	if !locationRegexp.MatchString(location) {
+		return "", microerror.Maskf(invalidRequestError, "invalid location %q", location)
+	}
+
+	return filepath.Join(s.dir, location+".json"), nil
+}
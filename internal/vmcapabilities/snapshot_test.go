@@ -0,0 +1,62 @@
+package vmcapabilities
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func Test_FileSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vm-skus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	snapshot, err := NewFileSnapshot(FileSnapshotConfig{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	_, _, err = snapshot.Load(ctx, "westeurope")
+	if !IsSnapshotNotFound(err) {
+		t.Fatalf("error == %#v, want snapshot not found", err)
+	}
+
+	fetched := time.Date(2021, 10, 4, 12, 0, 0, 0, time.UTC)
+	err = snapshot.Save(ctx, "westeurope", skus("Standard_D4s_v3", "Standard_D8s_v3"), fetched)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = snapshot.Save(ctx, "germanywestcentral", skus("Standard_D4s_v3"), fetched)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, loadedFetched, err := snapshot.Load(ctx, "westeurope")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 2 || *loaded["Standard_D8s_v3"].Name != "Standard_D8s_v3" {
+		t.Fatalf("loaded == %#v, want Standard_D4s_v3 and Standard_D8s_v3", loaded)
+	}
+	if !loadedFetched.Equal(fetched) {
+		t.Fatalf("fetched == %s, want %s", loadedFetched, fetched)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("%d files, want one per location", len(files))
+	}
+
+	err = snapshot.Save(ctx, "../westeurope", skus("Standard_D4s_v3"), fetched)
+	if !IsInvalidRequest(err) {
+		t.Fatalf("error == %#v, want invalid request", err)
+	}
+}
@@ -3,13 +3,19 @@ package vmcapabilities
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2019-07-01/compute"
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/azure-admission-controller/pkg/generic"
+	"github.com/giantswarm/azure-admission-controller/pkg/metrics"
+	"github.com/giantswarm/azure-admission-controller/pkg/tracing"
 )
 
 const (
@@ -24,20 +30,57 @@ const (
 	capabilityCPUs   = "vCPUs"
 )
 
+const (
+	// refreshCheckInterval is how often Run checks for SKUs older than the TTL.
+	refreshCheckInterval = time.Minute
+
+	// fetchTimeout bounds fetching the SKUs of a location from the Azure API,
+	// which is not cancelled with the requests waiting for them.
+	fetchTimeout = 2 * time.Minute
+)
+
 type Config struct {
 	Azure  API
 	Logger micrologger.Logger
+	// Snapshot, when set, saves the SKUs fetched from the Azure API, and
+	// serves them when fetching the SKUs of a location which are not cached
+	// fails, e.g. when the Azure API is unreachable on startup.
+	Snapshot Snapshot
+	// TTL is how long the SKUs of a location are served before Run fetches
+	// them again. They are never refreshed when it is zero, unless they were
+	// loaded from the snapshot.
+	TTL time.Duration
 }
 
 type VMSKU struct {
-	azure     API
-	initMutex sync.Mutex
-	logger    micrologger.Logger
+	azure    API
+	logger   micrologger.Logger
+	snapshot Snapshot
+	ttl      time.Duration
+
+	// loadsMutex guards loads, the fetches of SKUs from the Azure API in
+	// progress by location.
+	loadsMutex sync.Mutex
+	loads      map[string]*load
+	// skusMutex guards skus, refreshed and stale. It is not held while SKUs
+	// are fetched, so the previous SKUs of a location are served while they
+	// are refreshed.
+	skusMutex sync.RWMutex
 	skus      map[string]cache
+	refreshed map[string]time.Time
+	stale     map[string]bool
 }
 
 type cache map[string]compute.ResourceSku
 
+// load is a fetch of the SKUs of a location from the Azure API. Concurrent
+// requests for the same location wait for it to be done instead of fetching
+// the SKUs again.
+type load struct {
+	done chan struct{}
+	err  error
+}
+
 func New(config Config) (*VMSKU, error) {
 	if config.Logger == nil {
 		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
@@ -45,10 +88,18 @@ func New(config Config) (*VMSKU, error) {
 	if config.Azure == nil {
 		return nil, microerror.Maskf(invalidConfigError, "%T.Azure must not be empty", config)
 	}
+	if config.TTL < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.TTL must not be negative", config)
+	}
 	return &VMSKU{
-		logger: config.Logger,
-		azure:  config.Azure,
-		skus:   make(map[string]cache),
+		logger:    config.Logger,
+		azure:     config.Azure,
+		snapshot:  config.Snapshot,
+		ttl:       config.TTL,
+		loads:     make(map[string]*load),
+		skus:      make(map[string]cache),
+		refreshed: make(map[string]time.Time),
+		stale:     make(map[string]bool),
 	}, nil
 }
 
@@ -106,16 +157,232 @@ func (v *VMSKU) SupportedAZs(ctx context.Context, location string, vmType string
 		return []string{}, nil
 	}
 
+	restricted := map[string]bool{}
+	for _, r := range restrictions(sku, compute.Zone, location) {
+		if r.RestrictionInfo != nil && r.RestrictionInfo.Zones != nil {
+			for _, zone := range *r.RestrictionInfo.Zones {
+				restricted[zone] = true
+			}
+		}
+	}
+
 	var azs []string
 	for _, l := range *sku.LocationInfo {
-		if l.Zones != nil {
-			azs = append(azs, *l.Zones...)
+		if l.Zones == nil {
+			continue
+		}
+		for _, zone := range *l.Zones {
+			if !restricted[zone] {
+				azs = append(azs, zone)
+			}
 		}
 	}
 
 	return azs, nil
 }
 
+// CheckAvailable returns an error matched by IsSkuRestrictedError when the
+// specified VM size is restricted in the specified location for the
+// subscription, e.g. because it is NotAvailableForSubscription, so VMs of
+// that size can't be created there.
+func (v *VMSKU) CheckAvailable(ctx context.Context, location string, vmType string) error {
+	sku, err := v.getSKU(ctx, location, vmType)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	for _, r := range restrictions(sku, compute.Location, location) {
+		return microerror.Maskf(skuRestrictedError, "VM size %#q can't be used in location %#q because %s (%s)", vmType, location, restrictionReason(r), r.ReasonCode)
+	}
+
+	return nil
+}
+
+// CheckZoneAvailable returns an error matched by IsSkuRestrictedError when the
+// specified zone is restricted for the specified VM size in the specified
+// location for the subscription. Such zones are not returned by SupportedAZs.
+func (v *VMSKU) CheckZoneAvailable(ctx context.Context, location string, vmType string, zone string) error {
+	sku, err := v.getSKU(ctx, location, vmType)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	for _, r := range restrictions(sku, compute.Zone, location) {
+		if r.RestrictionInfo == nil || r.RestrictionInfo.Zones == nil {
+			continue
+		}
+		for _, z := range *r.RestrictionInfo.Zones {
+			if z == zone {
+				return microerror.Maskf(skuRestrictedError, "VM size %#q can't be used in zone %#q of location %#q because %s (%s)", vmType, zone, location, restrictionReason(r), r.ReasonCode)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Warm fills the cache for the specified location, so that the first request
+// for it is not slowed down by the Azure API. It does nothing when the cache
+// is already filled.
+func (v *VMSKU) Warm(ctx context.Context, location string) error {
+	if location == "" {
+		return microerror.Maskf(invalidRequestError, "location can't be empty")
+	}
+
+	if _, ok := v.cached(location); ok {
+		return nil
+	}
+
+	err := v.initCache(ctx, location)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// Refresh fetches the SKUs of the specified location from the Azure API and
+// replaces the cached ones. The cached SKUs are served until it is done, and
+// kept when it fails. When the SKUs are already being fetched, it waits for
+// that fetch instead.
+func (v *VMSKU) Refresh(ctx context.Context, location string) error {
+	if location == "" {
+		return microerror.Maskf(invalidRequestError, "location can't be empty")
+	}
+
+	err := v.initCache(ctx, location)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// Refreshed returns the time the SKUs of the specified location were fetched
+// from the Azure API, and false when they are not cached.
+func (v *VMSKU) Refreshed(location string) (time.Time, bool) {
+	v.skusMutex.RLock()
+	defer v.skusMutex.RUnlock()
+
+	refreshed, ok := v.refreshed[location]
+	return refreshed, ok
+}
+
+// Stale returns whether the SKUs of the specified location were loaded from
+// the snapshot because fetching them from the Azure API failed.
+func (v *VMSKU) Stale(location string) bool {
+	v.skusMutex.RLock()
+	defer v.skusMutex.RUnlock()
+
+	return v.stale[location]
+}
+
+// Locations returns the locations SKUs are cached for.
+func (v *VMSKU) Locations() []string {
+	v.skusMutex.RLock()
+	defer v.skusMutex.RUnlock()
+
+	var locations []string
+	for location := range v.skus {
+		locations = append(locations, location)
+	}
+	sort.Strings(locations)
+
+	return locations
+}
+
+// Run refreshes the SKUs of every cached location once they are older than
+// the TTL, or when they were loaded from the snapshot, until the specified
+// context is done. The SKUs of a location are only replaced when they were
+// fetched, so the last ones are served while the Azure API fails, and
+// fetching them is retried every minute.
+func (v *VMSKU) Run(ctx context.Context) {
+	interval := refreshCheckInterval
+	if v.ttl > 0 && v.ttl < interval {
+		interval = v.ttl
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			v.refreshExpired(ctx, time.Now())
+		}
+	}
+}
+
+func (v *VMSKU) refreshExpired(ctx context.Context, now time.Time) {
+	for _, location := range v.Locations() {
+		refreshed, _ := v.Refreshed(location)
+		expired := v.ttl > 0 && now.Sub(refreshed) >= v.ttl
+		if !expired && !v.Stale(location) {
+			continue
+		}
+
+		err := v.Refresh(ctx, location)
+		if err != nil {
+			v.logger.LogCtx(ctx, "level", "warning", "message", fmt.Sprintf("unable to refresh VM SKU cache for location %s, serving the SKUs fetched at %s", location, refreshed.UTC().Format(time.RFC3339)), "stack", microerror.JSON(err))
+		}
+	}
+}
+
+func (v *VMSKU) cached(location string) (cache, bool) {
+	v.skusMutex.RLock()
+	defer v.skusMutex.RUnlock()
+
+	skus, ok := v.skus[location]
+	return skus, ok
+}
+
+// restrictions returns the restrictions of the specified type of the
+// specified SKU which apply to the specified location.
+// restrictionReason explains the reason code of the restriction.
+func restrictionReason(r compute.ResourceSkuRestrictions) string {
+	switch r.ReasonCode {
+	case compute.NotAvailableForSubscription:
+		return "it is not available for the subscription"
+	case compute.QuotaID:
+		return "the subscription's offer does not allow it"
+	}
+
+	return string(r.ReasonCode)
+}
+
+func restrictions(sku compute.ResourceSku, restrictionType compute.ResourceSkuRestrictionsType, location string) []compute.ResourceSkuRestrictions {
+	if sku.Restrictions == nil {
+		return nil
+	}
+
+	var result []compute.ResourceSkuRestrictions
+	for _, r := range *sku.Restrictions {
+		if r.Type != restrictionType {
+			continue
+		}
+
+		// The restricted locations are in the values, and for location
+		// restrictions also in the restriction info.
+		var locations []string
+		if r.Values != nil {
+			locations = append(locations, *r.Values...)
+		}
+		if r.RestrictionInfo != nil && r.RestrictionInfo.Locations != nil {
+			locations = append(locations, *r.RestrictionInfo.Locations...)
+		}
+		for _, l := range locations {
+			if strings.EqualFold(l, location) {
+				result = append(result, r)
+				break
+			}
+		}
+	}
+
+	return result
+}
+
 func (v *VMSKU) getCapability(ctx context.Context, location string, vmType string, name string) (*string, error) {
 	if name == "" {
 		return nil, microerror.Maskf(invalidRequestError, "name can't be empty")
@@ -143,13 +410,15 @@ func (v *VMSKU) getSKU(ctx context.Context, location string, vmType string) (com
 		return compute.ResourceSku{}, microerror.Maskf(invalidRequestError, "vmType can't be empty")
 	}
 
-	if _, ok := v.skus[location]; !ok {
+	skus, ok := v.cached(location)
+	if !ok {
 		err := v.initCache(ctx, location)
 		if err != nil {
 			return compute.ResourceSku{}, microerror.Mask(err)
 		}
+		skus, _ = v.cached(location)
 	}
-	vmsku, found := v.skus[location][vmType]
+	vmsku, found := skus[vmType]
 	if !found {
 		return compute.ResourceSku{}, microerror.Maskf(skuNotFoundError, vmType)
 	}
@@ -157,19 +426,111 @@ func (v *VMSKU) getSKU(ctx context.Context, location string, vmType string) (com
 	return vmsku, nil
 }
 
+// initCache fetches the SKUs of the specified location from the Azure API and
+// caches them. When they are already being fetched, it waits for that fetch
+// and returns its result.
 func (v *VMSKU) initCache(ctx context.Context, location string) error {
-	v.initMutex.Lock()
-	defer v.initMutex.Unlock()
+	v.loadsMutex.Lock()
+	l, ok := v.loads[location]
+	if !ok {
+		l = &load{done: make(chan struct{})}
+		v.loads[location] = l
+		// The SKUs are fetched for every request waiting for them, so the
+		// fetch is not cancelled with the request which started it.
+		go v.load(generic.Detach(ctx), location, l)
+	}
+	v.loadsMutex.Unlock()
+
+	select {
+	case <-l.done:
+		return microerror.Mask(l.err)
+	case <-ctx.Done():
+		return microerror.Mask(ctx.Err())
+	}
+}
+
+// load fetches the SKUs of the specified location, bounded by fetchTimeout,
+// and then wakes up the requests waiting for them, also when fetching them
+// panics.
+func (v *VMSKU) load(ctx context.Context, location string, l *load) {
+	defer func() {
+		if r := recover(); r != nil {
+			l.err = microerror.Maskf(executionFailedError, "fetching VM SKUs for location %s panicked: %v", location, r)
+			v.logger.LogCtx(ctx, "level", "error", "message", fmt.Sprintf("fetching VM SKUs for location %s panicked", location), "stack", microerror.JSON(l.err))
+		}
+
+		v.loadsMutex.Lock()
+		delete(v.loads, location)
+		v.loadsMutex.Unlock()
+		close(l.done)
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+
+	l.err = v.fetch(ctx, location)
+}
+
+func (v *VMSKU) fetch(ctx context.Context, location string) error {
+	ctx, span := tracing.Start(ctx, "vmcapabilities.initCache", tracing.String("azure.location", location))
+	defer span.End()
+
 	filter := fmt.Sprintf("location eq '%s'", location)
 	v.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("Initializing cache for location %s with filter: %s", location, filter))
 	skus, err := v.azure.List(ctx, filter)
 	if err != nil {
+		span.RecordError(err)
+		metrics.ObserveSKUCacheRefresh(location, metrics.RefreshFailure, 0, time.Now())
+
+		if _, ok := v.cached(location); !ok && v.snapshot != nil {
+			snapshotErr := v.loadSnapshot(ctx, location)
+			if snapshotErr == nil {
+				fetched, _ := v.Refreshed(location)
+				v.logger.LogCtx(ctx, "level", "warning", "message", fmt.Sprintf("unable to fetch VM SKUs for location %s, serving the stale SKUs fetched at %s from the snapshot", location, fetched.UTC().Format(time.RFC3339)), "stack", microerror.JSON(err))
+				return nil
+			} else if !IsSnapshotNotFound(snapshotErr) {
+				v.logger.LogCtx(ctx, "level", "warning", "message", fmt.Sprintf("unable to load VM SKU snapshot for location %s", location), "stack", microerror.JSON(snapshotErr))
+			}
+		}
+
 		return microerror.Mask(err)
 	}
+	span.SetAttributes(tracing.Int("azure.skus", len(skus)))
 
+	refreshed := time.Now()
+	v.skusMutex.Lock()
 	v.skus[location] = skus
+	v.refreshed[location] = refreshed
+	delete(v.stale, location)
+	v.skusMutex.Unlock()
+	metrics.ObserveSKUCacheRefresh(location, metrics.RefreshSuccess, len(skus), refreshed)
 
 	v.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("Initialized cache. Number of SKUs in cache for location %s: '%d'", location, len(skus)))
 
+	if v.snapshot != nil {
+		err = v.snapshot.Save(ctx, location, skus, refreshed)
+		if err != nil {
+			v.logger.LogCtx(ctx, "level", "warning", "message", fmt.Sprintf("unable to save VM SKU snapshot for location %s", location), "stack", microerror.JSON(err))
+		}
+	}
+
+	return nil
+}
+
+// loadSnapshot caches the SKUs of the specified location saved in the
+// snapshot, marked as stale.
+func (v *VMSKU) loadSnapshot(ctx context.Context, location string) error {
+	skus, fetched, err := v.snapshot.Load(ctx, location)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	v.skusMutex.Lock()
+	v.skus[location] = skus
+	v.refreshed[location] = fetched
+	v.stale[location] = true
+	v.skusMutex.Unlock()
+	metrics.ObserveSKUCacheSnapshot(location, len(skus), fetched)
+
 	return nil
 }
@@ -0,0 +1,541 @@
+package vmcapabilities
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2019-07-01/compute"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/giantswarm/micrologger"
+)
+
+// fakeAPI returns the SKUs of every location, or fails with err when it is
+// set, and counts the calls.
+type fakeAPI struct {
+	mutex sync.Mutex
+	skus  map[string]map[string]compute.ResourceSku
+	err   error
+	calls int
+}
+
+func (a *fakeAPI) List(_ context.Context, filter string) (map[string]compute.ResourceSku, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.calls++
+	if a.err != nil {
+		return nil, a.err
+	}
+	for location, skus := range a.skus {
+		if filter == "location eq '"+location+"'" {
+			return skus, nil
+		}
+	}
+
+	return map[string]compute.ResourceSku{}, nil
+}
+
+func newTestVMSKU(t *testing.T, api API, ttl time.Duration) *VMSKU {
+	t.Helper()
+	return newTestVMSKUWithSnapshot(t, api, nil, ttl)
+}
+
+func newTestVMSKUWithSnapshot(t *testing.T, api API, snapshot Snapshot, ttl time.Duration) *VMSKU {
+	t.Helper()
+
+	logger, err := micrologger.New(micrologger.Config{IOWriter: ioutil.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := New(Config{Azure: api, Logger: logger, Snapshot: snapshot, TTL: ttl})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return v
+}
+
+func skus(names ...string) map[string]compute.ResourceSku {
+	result := map[string]compute.ResourceSku{}
+	for _, name := range names {
+		result[name] = compute.ResourceSku{Name: to.StringPtr(name)}
+	}
+	return result
+}
+
+func Test_VMSKU_refreshExpired(t *testing.T) {
+	testCases := []struct {
+		name string
+		// age is how long ago the SKUs of westeurope were fetched when
+		// refreshExpired is called.
+		age           time.Duration
+		err           error
+		expectedCalls int
+		expectedSKUs  []string
+	}{
+		{
+			name:          "case 0: SKUs younger than the TTL are kept",
+			age:           time.Minute,
+			expectedCalls: 1,
+			expectedSKUs:  []string{"Standard_D4s_v3"},
+		},
+		{
+			name:          "case 1: SKUs older than the TTL are refreshed",
+			age:           2 * time.Hour,
+			expectedCalls: 2,
+			expectedSKUs:  []string{"Standard_D4s_v3", "Standard_D8s_v3"},
+		},
+		{
+			name:          "case 2: SKUs are kept when refreshing them fails",
+			age:           2 * time.Hour,
+			err:           errors.New("service unavailable"),
+			expectedCalls: 2,
+			expectedSKUs:  []string{"Standard_D4s_v3"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api := &fakeAPI{skus: map[string]map[string]compute.ResourceSku{"westeurope": skus("Standard_D4s_v3")}}
+			v := newTestVMSKU(t, api, time.Hour)
+
+			err := v.Warm(context.Background(), "westeurope")
+			if err != nil {
+				t.Fatal(err)
+			}
+			warmed, _ := v.Refreshed("westeurope")
+
+			api.mutex.Lock()
+			api.skus["westeurope"] = skus("Standard_D4s_v3", "Standard_D8s_v3")
+			api.err = tc.err
+			api.mutex.Unlock()
+
+			v.refreshExpired(context.Background(), warmed.Add(tc.age))
+
+			if api.calls != tc.expectedCalls {
+				t.Fatalf("%d calls to the Azure API, want %d", api.calls, tc.expectedCalls)
+			}
+			cached, _ := v.cached("westeurope")
+			if len(cached) != len(tc.expectedSKUs) {
+				t.Fatalf("%d cached SKUs, want %d", len(cached), len(tc.expectedSKUs))
+			}
+			for _, name := range tc.expectedSKUs {
+				if _, ok := cached[name]; !ok {
+					t.Fatalf("SKU %s is not cached", name)
+				}
+			}
+			refreshed, _ := v.Refreshed("westeurope")
+			if (tc.expectedCalls > 1 && tc.err == nil) != refreshed.After(warmed) {
+				t.Fatalf("refreshed == %s, warmed == %s", refreshed, warmed)
+			}
+		})
+	}
+}
+
+func Test_VMSKU_Snapshot(t *testing.T) {
+	testCases := []struct {
+		name string
+		// saved are the SKUs of westeurope in the snapshot.
+		saved         []string
+		err           error
+		expectedErr   bool
+		expectedStale bool
+		expectedSKUs  []string
+	}{
+		{
+			name:         "case 0: fetched SKUs are served and saved",
+			saved:        []string{"Standard_D4s_v3"},
+			expectedSKUs: []string{"Standard_D4s_v3", "Standard_D8s_v3"},
+		},
+		{
+			name:          "case 1: saved SKUs are served when the Azure API fails",
+			saved:         []string{"Standard_D4s_v3"},
+			err:           errors.New("service unavailable"),
+			expectedStale: true,
+			expectedSKUs:  []string{"Standard_D4s_v3"},
+		},
+		{
+			name:        "case 2: nothing is served when the Azure API fails and nothing was saved",
+			err:         errors.New("service unavailable"),
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "vm-skus")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			snapshot, err := NewFileSnapshot(FileSnapshotConfig{Dir: dir})
+			if err != nil {
+				t.Fatal(err)
+			}
+			fetched := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+			if tc.saved != nil {
+				err = snapshot.Save(context.Background(), "westeurope", skus(tc.saved...), fetched)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			api := &fakeAPI{
+				skus: map[string]map[string]compute.ResourceSku{"westeurope": skus("Standard_D4s_v3", "Standard_D8s_v3")},
+				err:  tc.err,
+			}
+			v := newTestVMSKUWithSnapshot(t, api, snapshot, 0)
+
+			err = v.Warm(context.Background(), "westeurope")
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("error == %#v, want error %t", err, tc.expectedErr)
+			}
+			if v.Stale("westeurope") != tc.expectedStale {
+				t.Fatalf("stale == %t, want %t", v.Stale("westeurope"), tc.expectedStale)
+			}
+			cached, _ := v.cached("westeurope")
+			if len(cached) != len(tc.expectedSKUs) {
+				t.Fatalf("%d cached SKUs, want %d", len(cached), len(tc.expectedSKUs))
+			}
+			if tc.expectedStale {
+				refreshed, _ := v.Refreshed("westeurope")
+				if !refreshed.Equal(fetched) {
+					t.Fatalf("refreshed == %s, want %s", refreshed, fetched)
+				}
+			}
+
+			if tc.err == nil {
+				saved, _, err := snapshot.Load(context.Background(), "westeurope")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(saved) != len(tc.expectedSKUs) {
+					t.Fatalf("%d saved SKUs, want %d", len(saved), len(tc.expectedSKUs))
+				}
+				return
+			}
+			if !tc.expectedStale {
+				return
+			}
+
+			// Stale SKUs are refreshed even without a TTL, once the Azure API
+			// is back.
+			api.mutex.Lock()
+			api.err = nil
+			api.mutex.Unlock()
+			v.refreshExpired(context.Background(), time.Now())
+
+			if v.Stale("westeurope") {
+				t.Fatal("SKUs are still stale")
+			}
+			cached, _ = v.cached("westeurope")
+			if len(cached) != 2 {
+				t.Fatalf("%d cached SKUs, want 2", len(cached))
+			}
+		})
+	}
+}
+
+func Test_VMSKU_Run(t *testing.T) {
+	api := &fakeAPI{skus: map[string]map[string]compute.ResourceSku{"westeurope": skus("Standard_D4s_v3")}}
+	v := newTestVMSKU(t, api, 10*time.Millisecond)
+
+	err := v.Warm(context.Background(), "westeurope")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		v.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		api.mutex.Lock()
+		calls := api.calls
+		api.mutex.Unlock()
+		if calls > 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("SKUs were not refreshed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	<-done
+}
+
+func Test_VMSKU_SupportedAZs(t *testing.T) {
+	testCases := []struct {
+		name         string
+		restrictions []compute.ResourceSkuRestrictions
+		expectedAZs  []string
+	}{
+		{
+			name:        "case 0: no restrictions",
+			expectedAZs: []string{"1", "2", "3"},
+		},
+		{
+			name: "case 1: zone restricted in the location",
+			restrictions: []compute.ResourceSkuRestrictions{
+				{
+					Type:   compute.Zone,
+					Values: &[]string{"westeurope"},
+					RestrictionInfo: &compute.ResourceSkuRestrictionInfo{
+						Locations: &[]string{"westeurope"},
+						Zones:     &[]string{"2"},
+					},
+					ReasonCode: compute.NotAvailableForSubscription,
+				},
+			},
+			expectedAZs: []string{"1", "3"},
+		},
+		{
+			name: "case 2: zone restricted in another location",
+			restrictions: []compute.ResourceSkuRestrictions{
+				{
+					Type:   compute.Zone,
+					Values: &[]string{"germanywestcentral"},
+					RestrictionInfo: &compute.ResourceSkuRestrictionInfo{
+						Locations: &[]string{"germanywestcentral"},
+						Zones:     &[]string{"2"},
+					},
+					ReasonCode: compute.NotAvailableForSubscription,
+				},
+			},
+			expectedAZs: []string{"1", "2", "3"},
+		},
+		{
+			name: "case 3: location restriction does not remove zones",
+			restrictions: []compute.ResourceSkuRestrictions{
+				{
+					Type:   compute.Location,
+					Values: &[]string{"westeurope"},
+					RestrictionInfo: &compute.ResourceSkuRestrictionInfo{
+						Locations: &[]string{"westeurope"},
+						Zones:     &[]string{"1"},
+					},
+					ReasonCode: compute.NotAvailableForSubscription,
+				},
+			},
+			expectedAZs: []string{"1", "2", "3"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sku := compute.ResourceSku{
+				Name: to.StringPtr("Standard_D4s_v3"),
+				LocationInfo: &[]compute.ResourceSkuLocationInfo{
+					{
+						Location: to.StringPtr("westeurope"),
+						Zones:    &[]string{"1", "2", "3"},
+					},
+				},
+			}
+			if tc.restrictions != nil {
+				sku.Restrictions = &tc.restrictions
+			}
+			api := &fakeAPI{skus: map[string]map[string]compute.ResourceSku{"westeurope": {"Standard_D4s_v3": sku}}}
+			v := newTestVMSKU(t, api, 0)
+
+			azs, err := v.SupportedAZs(context.Background(), "westeurope", "Standard_D4s_v3")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(azs, tc.expectedAZs) {
+				t.Fatalf("expected %v, got %v", tc.expectedAZs, azs)
+			}
+		})
+	}
+}
+
+func Test_VMSKU_CheckZoneAvailable(t *testing.T) {
+	testCases := []struct {
+		name         string
+		vmType       string
+		zone         string
+		errorMatcher func(error) bool
+	}{
+		{
+			name: "case 0: zone not restricted",
+			zone: "1",
+		},
+		{
+			name:         "case 1: zone restricted for the subscription",
+			zone:         "2",
+			errorMatcher: IsSkuRestrictedError,
+		},
+		{
+			name:         "case 2: unknown VM size",
+			vmType:       "Standard_D8s_v3",
+			zone:         "1",
+			errorMatcher: IsSkuNotFoundError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sku := compute.ResourceSku{
+				Name: to.StringPtr("Standard_D4s_v3"),
+				LocationInfo: &[]compute.ResourceSkuLocationInfo{
+					{
+						Location: to.StringPtr("westeurope"),
+						Zones:    &[]string{"1", "2", "3"},
+					},
+				},
+				Restrictions: &[]compute.ResourceSkuRestrictions{
+					{
+						Type:   compute.Zone,
+						Values: &[]string{"westeurope"},
+						RestrictionInfo: &compute.ResourceSkuRestrictionInfo{
+							Locations: &[]string{"westeurope"},
+							Zones:     &[]string{"2"},
+						},
+						ReasonCode: compute.NotAvailableForSubscription,
+					},
+				},
+			}
+			api := &fakeAPI{skus: map[string]map[string]compute.ResourceSku{"westeurope": {"Standard_D4s_v3": sku}}}
+			v := newTestVMSKU(t, api, 0)
+
+			vmType := tc.vmType
+			if vmType == "" {
+				vmType = "Standard_D4s_v3"
+			}
+			err := v.CheckZoneAvailable(context.Background(), "westeurope", vmType, tc.zone)
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// fall through
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("expected %#v got %#v", nil, err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("expected %#v got %#v", "error", nil)
+			case !tc.errorMatcher(err):
+				t.Fatalf("unexpected error: %#v", err)
+			}
+		})
+	}
+}
+
+// blockingAPI signals every call on called, and answers it once release is
+// closed, or panics when panics is set.
+type blockingAPI struct {
+	called  chan struct{}
+	release chan struct{}
+	panics  bool
+}
+
+func (a *blockingAPI) List(ctx context.Context, _ string) (map[string]compute.ResourceSku, error) {
+	a.called <- struct{}{}
+	select {
+	case <-a.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if a.panics {
+		panic("unexpected response")
+	}
+
+	return skus("Standard_D4s_v3"), nil
+}
+
+// waitingContext closes waiting when Done is called, i.e. when a request
+// waits for a fetch.
+type waitingContext struct {
+	context.Context
+	once    sync.Once
+	waiting chan struct{}
+}
+
+func (c *waitingContext) Done() <-chan struct{} {
+	c.once.Do(func() { close(c.waiting) })
+	return c.Context.Done()
+}
+
+func Test_VMSKU_initCache(t *testing.T) {
+	testCases := []struct {
+		name         string
+		panics       bool
+		errorMatcher func(err error) bool
+	}{
+		{
+			name: "case 0: waiters get the SKUs when the first request is cancelled",
+		},
+		{
+			name:         "case 1: waiters get an error when fetching the SKUs panics",
+			panics:       true,
+			errorMatcher: IsExecutionFailed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api := &blockingAPI{called: make(chan struct{}, 2), release: make(chan struct{}), panics: tc.panics}
+			v := newTestVMSKU(t, api, 0)
+
+			// The first request starts the fetch and is cancelled while it
+			// is in progress, like a timed out readiness probe.
+			leaderCtx, cancel := context.WithCancel(context.Background())
+			leaderErr := make(chan error)
+			go func() {
+				leaderErr <- v.Warm(leaderCtx, "westeurope")
+			}()
+			<-api.called
+
+			waiterCtx := &waitingContext{Context: context.Background(), waiting: make(chan struct{})}
+			waiterErr := make(chan error)
+			go func() {
+				waiterErr <- v.Refresh(waiterCtx, "westeurope")
+			}()
+			<-waiterCtx.waiting
+
+			cancel()
+			if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+				t.Fatalf("expected %#v got %#v", context.Canceled, err)
+			}
+
+			close(api.release)
+			var err error
+			select {
+			case err = <-waiterErr:
+			case <-time.After(5 * time.Second):
+				t.Fatal("waiter did not return")
+			}
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// fall through
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("expected %#v got %#v", nil, err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("expected %#v got %#v", "error", nil)
+			case !tc.errorMatcher(err):
+				t.Fatalf("unexpected error: %#v", err)
+			}
+
+			select {
+			case <-api.called:
+				t.Fatal("SKUs were fetched twice")
+			default:
+			}
+			if _, ok := v.Refreshed("westeurope"); ok == tc.panics {
+				t.Fatalf("expected SKUs cached %t", !tc.panics)
+			}
+		})
+	}
+}
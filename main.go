@@ -3,12 +3,14 @@ package main
 import (
 	"context"
 	"crypto/tls"
-	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2019-07-01/compute"
 	"github.com/Azure/go-autorest/autorest/azure/auth"
@@ -20,6 +22,7 @@ import (
 	"github.com/giantswarm/k8sclient/v5/pkg/k8sclient"
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"k8s.io/client-go/rest"
 	restclient "k8s.io/client-go/rest"
 	capz "sigs.k8s.io/cluster-api-provider-azure/api/v1alpha3"
@@ -28,16 +31,25 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/yaml"
 
 	"github.com/giantswarm/azure-admission-controller/internal/vmcapabilities"
 	"github.com/giantswarm/azure-admission-controller/pkg/app"
+	"github.com/giantswarm/azure-admission-controller/pkg/audit"
 	"github.com/giantswarm/azure-admission-controller/pkg/config"
+	"github.com/giantswarm/azure-admission-controller/pkg/enforcement"
+	"github.com/giantswarm/azure-admission-controller/pkg/offline"
 	"github.com/giantswarm/azure-admission-controller/pkg/project"
+	"github.com/giantswarm/azure-admission-controller/pkg/readiness"
+	"github.com/giantswarm/azure-admission-controller/pkg/recorder"
+	"github.com/giantswarm/azure-admission-controller/pkg/tracing"
 )
 
 func main() {
 	err := mainError()
-	if err != nil {
+	if IsFailed(err) {
+		os.Exit(1)
+	} else if err != nil {
 		panic(fmt.Sprintf("%#v\n", err))
 	}
 }
@@ -48,6 +60,19 @@ func mainError() error {
 		return microerror.Mask(err)
 	}
 
+	switch cfg.Command {
+	case config.CommandWebhookConfig:
+		return printWebhookConfigurations(cfg)
+	case config.CommandValidate:
+		return validateManifests(cfg)
+	case config.CommandMutate:
+		return mutateManifests(cfg)
+	case config.CommandReplay:
+		return replay(cfg)
+	case config.CommandDumpSKUCatalog:
+		return dumpSKUCatalog(cfg)
+	}
+
 	var newLogger micrologger.Logger
 	{
 		newLogger, err = micrologger.New(micrologger.Config{})
@@ -106,6 +131,14 @@ func mainError() error {
 			return microerror.Mask(err)
 		}
 
+		// Informers are created lazily on the first read. Release CRs are read
+		// for every request, so their informer is created upfront for the
+		// readiness check to wait for it.
+		_, err = ctrlCache.GetInformer(context.Background(), &releasev1alpha1.Release{})
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
 		go func() {
 			// XXX: This orphaned throw-away stop channel is very ugly, but
 			// will go away once `controller-runtime` library is updated. In
@@ -118,83 +151,590 @@ func mainError() error {
 			}
 		}()
 
-		ok := ctrlCache.WaitForCacheSync(make(<-chan struct{}))
-		if !ok {
-			return microerror.Mask(errors.New("couldn't wait for cache sync"))
-		}
+		// We don't wait for the cache to sync here, /readyz fails until it
+		// did, so the API server won't send us requests before.
 	}
 
-	var resourceSkusClient compute.ResourceSkusClient
-	{
-		// Azure sdk does not fail initializing the client if the environment variables are empty.
-		// We need to ensure ENV variables are set.
-		envVarNames := []string{
-			auth.ClientID,
-			auth.ClientSecret,
-			auth.SubscriptionID,
-			auth.TenantID,
-		}
-		for _, envVarName := range envVarNames {
-			if v := os.Getenv(envVarName); v == "" {
-				return microerror.Mask(fmt.Errorf("empty value or missing required env variable %q", envVarName))
-			}
+	var skuAPI vmcapabilities.API
+	if cfg.VMSKUBackend == config.VMSKUBackendFile {
+		skuAPI, err = vmcapabilities.NewFileAPI(cfg.SKUCatalog)
+		if err != nil {
+			return microerror.Mask(err)
 		}
-		settings, err := auth.GetSettingsFromEnvironment()
+	} else {
+		skuAPI, err = newAzureSKUAPI()
 		if err != nil {
 			return microerror.Mask(err)
 		}
-		authorizer, err := settings.GetAuthorizer()
+	}
+
+	var vmSKUSnapshot vmcapabilities.Snapshot
+	if cfg.VMSKUSnapshotDir != "" {
+		vmSKUSnapshot, err = vmcapabilities.NewFileSnapshot(vmcapabilities.FileSnapshotConfig{
+			Dir: cfg.VMSKUSnapshotDir,
+		})
 		if err != nil {
 			return microerror.Mask(err)
 		}
-		resourceSkusClient = compute.NewResourceSkusClient(settings.GetSubscriptionID())
-		resourceSkusClient.Client.Authorizer = authorizer
 	}
 
 	var vmcaps *vmcapabilities.VMSKU
 	{
 		vmcaps, err = vmcapabilities.New(vmcapabilities.Config{
+			Logger:   newLogger,
+			Azure:    skuAPI,
+			Snapshot: vmSKUSnapshot,
+			TTL:      cfg.VMSKUCacheTTL,
+		})
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		// Fill the cache in the background, /readyz retries until it is filled.
+		go func() {
+			err := vmcaps.Warm(context.Background(), cfg.Location)
+			if err != nil {
+				newLogger.LogCtx(context.Background(), "level", "warning", "message", fmt.Sprintf("unable to warm VM SKU cache for location %s", cfg.Location), "stack", microerror.JSON(err))
+			}
+		}()
+
+		// Refresh the cached SKUs once they are older than the TTL, or when
+		// they were loaded from the snapshot.
+		go vmcaps.Run(context.Background())
+	}
+
+	var auditSink *audit.Sink
+	{
+		writer, err := newFileWriter(cfg.AuditLogFile)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		auditSink, err = audit.NewSink(audit.SinkConfig{
+			Writer: writer,
+		})
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var enforcementStore *enforcement.Store
+	{
+		c := enforcement.StoreConfig{
+			Logger: newLogger,
+			Path:   cfg.EnforcementConfigFile,
+		}
+		enforcementStore, err = enforcement.NewStore(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		// Pick up changes of the mounted ConfigMap without a restart.
+		go enforcementStore.Run(context.Background())
+	}
+
+	var candidateEnforcementStore *enforcement.Store
+	if cfg.CandidateEnforcementConfigFile != "" {
+		c := enforcement.StoreConfig{
 			Logger: newLogger,
-			Azure:  vmcapabilities.NewAzureAPI(vmcapabilities.AzureConfig{ResourceSkuClient: &resourceSkusClient}),
+			Path:   cfg.CandidateEnforcementConfigFile,
+		}
+		candidateEnforcementStore, err = enforcement.NewStore(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		go candidateEnforcementStore.Run(context.Background())
+	}
+
+	var tracer *tracing.Tracer
+	var ctrlReader client.Reader = ctrlCache
+	if cfg.Tracing.Exporter != "" {
+		exporter, err := newTracingExporter(cfg.Tracing)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		tracer, err = tracing.New(tracing.Config{
+			Exporter: exporter,
+			Logger:   newLogger,
 		})
 		if err != nil {
 			return microerror.Mask(err)
 		}
+
+		// Record the Kubernetes API calls of traced requests.
+		ctrlClient = tracing.NewClient(ctrlClient)
+		ctrlReader = tracing.NewReader(ctrlCache)
+
+		go tracer.Run(context.Background())
+	}
+
+	var cm *certman.CertMan
+	{
+		cm, err = certman.New(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		err = cm.Watch()
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var readinessHandler *readiness.Handler
+	{
+		c := readiness.HandlerConfig{
+			Checks: []readiness.Check{
+				readiness.CacheSynced(ctrlCache),
+				readiness.CacheWarm("vm-sku-cache", vmcaps, cfg.Location),
+				readiness.CertificateValid(cm.GetCertificate),
+			},
+			Logger: newLogger,
+		}
+		readinessHandler, err = readiness.NewHandler(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var vmSKURefreshHandler *vmcapabilities.RefreshHandler
+	{
+		c := vmcapabilities.RefreshHandlerConfig{
+			Logger: newLogger,
+			VMSKU:  vmcaps,
+		}
+		vmSKURefreshHandler, err = vmcapabilities.NewRefreshHandler(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
 	}
 
 	// Here we register our endpoints.
 	handler := http.NewServeMux()
 	handler.HandleFunc("/healthz", healthCheck)
+	handler.Handle("/readyz", readinessHandler)
+	handler.Handle("/metrics", promhttp.Handler())
+
+	// The internal endpoints are not authenticated, so they are not served on
+	// the webhook listener.
+	internalHandler := http.NewServeMux()
+	internalHandler.Handle("/vm-skus/refresh", vmSKURefreshHandler)
 
 	// Register all webhook handlers
-	err = app.RegisterWebhookHandlers(handler, cfg, newLogger, ctrlClient, ctrlCache, vmcaps)
+	webhooks, err := app.RegisterWebhookHandlers(handler, cfg, newLogger, auditSink, enforcementStore, candidateEnforcementStore, ctrlClient, ctrlReader, vmcaps)
 	if err != nil {
 		return microerror.Mask(err)
 	}
 
+	var rootHandler http.Handler = handler
+	if cfg.RecordFile != "" {
+		writer, err := newFileWriter(cfg.RecordFile)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		r, err := recorder.New(recorder.Config{
+			Logger: newLogger,
+			Writer: writer,
+		})
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		rootHandler = r.Middleware(handler)
+	}
+	if tracer != nil {
+		rootHandler = tracer.Middleware(rootHandler)
+	}
+
 	newLogger.LogCtx(context.Background(), "level", "debug", "message", fmt.Sprintf("Listening on port %s", cfg.Address))
-	serve(cfg, handler)
+	serve(cfg, cm, rootHandler, internalHandler)
+
+	// Wait for the candidate handlers compared in the background before the
+	// spans are flushed.
+	webhooks.Wait()
+
+	if tracer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		err = tracer.Flush(ctx)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
 
 	return nil
 }
 
-func healthCheck(writer http.ResponseWriter, request *http.Request) {
-	writer.WriteHeader(http.StatusOK)
-	_, err := writer.Write([]byte("ok"))
+// newTracingExporter returns the exporter of the spans configured with the
+// tracing flags.
+func newTracingExporter(cfg config.Tracing) (tracing.Exporter, error) {
+	if cfg.Exporter == config.TracingExporterStdout {
+		return tracing.NewStdoutExporter(os.Stdout), nil
+	}
+
+	exporter, err := tracing.NewOTLPExporter(tracing.OTLPExporterConfig{
+		Endpoint:       cfg.OTLPEndpoint,
+		HTTPClient:     &http.Client{Timeout: 10 * time.Second},
+		ServiceName:    project.Name(),
+		ServiceVersion: project.Version(),
+	})
 	if err != nil {
-		panic(microerror.JSON(err))
+		return nil, microerror.Mask(err)
 	}
+
+	return exporter, nil
 }
 
-func serve(config config.Config, handler http.Handler) {
-	cm, err := certman.New(config.CertFile, config.KeyFile)
+// newAzureSKUAPI returns the API serving the VM SKUs of the Azure API, with
+// the credentials of the AZURE_* environment variables.
+func newAzureSKUAPI() (vmcapabilities.API, error) {
+	// Azure sdk does not fail initializing the client if the environment variables are empty.
+	// We need to ensure ENV variables are set.
+	envVarNames := []string{
+		auth.ClientID,
+		auth.ClientSecret,
+		auth.SubscriptionID,
+		auth.TenantID,
+	}
+	for _, envVarName := range envVarNames {
+		if v := os.Getenv(envVarName); v == "" {
+			return nil, microerror.Mask(fmt.Errorf("empty value or missing required env variable %q", envVarName))
+		}
+	}
+	settings, err := auth.GetSettingsFromEnvironment()
 	if err != nil {
-		panic(microerror.JSON(err))
+		return nil, microerror.Mask(err)
+	}
+	authorizer, err := settings.GetAuthorizer()
+	if err != nil {
+		return nil, microerror.Mask(err)
 	}
-	if err := cm.Watch(); err != nil {
+	resourceSkusClient := compute.NewResourceSkusClient(settings.GetSubscriptionID())
+	resourceSkusClient.Client.Authorizer = authorizer
+
+	return vmcapabilities.NewAzureAPI(vmcapabilities.AzureConfig{ResourceSkuClient: &resourceSkusClient}), nil
+}
+
+// dumpSKUCatalog prints the VM SKUs of the Azure API for the specified
+// locations as a catalog, which the file backend and the --sku-catalog flags
+// read, e.g. to run the webhooks without access to the Azure API.
+func dumpSKUCatalog(cfg config.Config) error {
+	skuAPI, err := newAzureSKUAPI()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	catalog, err := vmcapabilities.DumpCatalog(context.Background(), skuAPI, cfg.DumpSKUCatalog.Locations)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	data, err := vmcapabilities.MarshalCatalog(catalog, cfg.DumpSKUCatalog.Format)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if cfg.DumpSKUCatalog.Output == "-" {
+		_, err = os.Stdout.Write(data)
+	} else {
+		err = ioutil.WriteFile(cfg.DumpSKUCatalog.Output, data, 0644)
+	}
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// printWebhookConfigurations prints the webhook configurations for the
+// registered webhook handlers as YAML. Logs go to stderr, so the output can be
+// piped to kubectl or into the chart.
+func printWebhookConfigurations(cfg config.Config) error {
+	logger, err := micrologger.New(micrologger.Config{IOWriter: os.Stderr})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	mutating, validating, err := app.WebhookConfigurations(logger, cfg.WebhookConfig.Name, cfg.WebhookConfig.Namespace)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	for _, o := range []interface{}{mutating, validating} {
+		b, err := yaml.Marshal(o)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		fmt.Printf("---\n%s", b)
+	}
+
+	return nil
+}
+
+// validateManifests runs the webhook handlers for the manifests of the
+// specified files and prints the violations. It fails when there are any, so
+// it can be used in CI.
+func validateManifests(cfg config.Config) error {
+	logger, err := micrologger.New(micrologger.Config{IOWriter: os.Stderr})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var fixtures []offline.Manifest
+	if cfg.Validate.Fixtures != "" {
+		fixtures, err = offline.Load(cfg.Validate.Fixtures)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	manifests, err := offline.Load(cfg.Validate.Paths...)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var skuAPI vmcapabilities.API
+	if cfg.Validate.SKUCatalog != "" {
+		skuAPI, err = vmcapabilities.NewFileAPI(cfg.Validate.SKUCatalog)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	c := app.ValidateManifestsConfig{
+		BaseDomain: cfg.BaseDomain,
+		Location:   cfg.Location,
+		Logger:     logger,
+		SKUAPI:     skuAPI,
+		Fixtures:   fixtures,
+		Manifests:  manifests,
+	}
+	results, err := app.ValidateManifests(context.Background(), c)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var violations int
+	for _, r := range results {
+		for _, w := range r.Warnings {
+			fmt.Printf("%s: warning: %s\n", r.Manifest, w)
+		}
+		for _, v := range r.Violations {
+			location := fmt.Sprintf("%s:%d", r.Manifest.File, v.Line)
+			if v.Field != "" {
+				fmt.Printf("%s: %s %s: %s: %s\n", location, r.Manifest.Kind, r.Manifest.Name, v.Field, v.Message)
+			} else {
+				fmt.Printf("%s: %s %s: %s\n", location, r.Manifest.Kind, r.Manifest.Name, v.Message)
+			}
+		}
+		violations += len(r.Violations)
+	}
+
+	fmt.Fprintf(os.Stderr, "%d manifests validated, %d violations\n", len(results), violations)
+	if violations > 0 {
+		return microerror.Maskf(failedError, "%d violations", violations)
+	}
+
+	return nil
+}
+
+// mutateManifests runs the mutating webhook handlers for the manifests of the
+// specified files and prints the mutated manifests, or the changes with
+// --diff. It fails when a handler denies a manifest.
+func mutateManifests(cfg config.Config) error {
+	logger, err := micrologger.New(micrologger.Config{IOWriter: os.Stderr})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var fixtures []offline.Manifest
+	if cfg.Mutate.Fixtures != "" {
+		fixtures, err = offline.Load(cfg.Mutate.Fixtures)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var old []offline.Manifest
+	if cfg.Mutate.Old != "" {
+		old, err = offline.Load(cfg.Mutate.Old)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	manifests, err := offline.Load(cfg.Mutate.Paths...)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var skuAPI vmcapabilities.API
+	if cfg.Mutate.SKUCatalog != "" {
+		skuAPI, err = vmcapabilities.NewFileAPI(cfg.Mutate.SKUCatalog)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	c := app.MutateManifestsConfig{
+		BaseDomain: cfg.BaseDomain,
+		Location:   cfg.Location,
+		Logger:     logger,
+		SKUAPI:     skuAPI,
+		Fixtures:   fixtures,
+		Manifests:  manifests,
+		Old:        old,
+	}
+	results, err := app.MutateManifests(context.Background(), c)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var violations int
+	for _, r := range results {
+		for _, w := range r.Warnings {
+			fmt.Fprintf(os.Stderr, "%s: warning: %s\n", r.Manifest, w)
+		}
+		for _, v := range r.Violations {
+			fmt.Fprintf(os.Stderr, "%s:%d: %s %s: %s\n", r.Manifest.File, v.Line, r.Manifest.Kind, r.Manifest.Name, v.Message)
+		}
+		violations += len(r.Violations)
+
+		if cfg.Mutate.Diff {
+			if len(r.Patch) == 0 {
+				continue
+			}
+
+			diff, err := app.MutationDiff(r)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+			fmt.Print(diff)
+			continue
+		}
+
+		// Manifests which were not mutated are printed too, so the output
+		// can be applied as a whole.
+		object := r.Mutated
+		if object == nil {
+			object = r.Manifest.Object
+		}
+		b, err := yaml.JSONToYAML(object)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		fmt.Printf("---\n%s", b)
+	}
+
+	if violations > 0 {
+		fmt.Fprintf(os.Stderr, "%d manifests mutated, %d violations\n", len(results), violations)
+		return microerror.Maskf(failedError, "%d violations", violations)
+	}
+
+	return nil
+}
+
+// replay replays the recorded admission traffic of the specified files
+// against the webhook handlers and prints the responses which changed. It
+// fails when there are any.
+func replay(cfg config.Config) error {
+	logger, err := micrologger.New(micrologger.Config{IOWriter: os.Stderr})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var fixtures []offline.Manifest
+	if cfg.Replay.Fixtures != "" {
+		fixtures, err = offline.Load(cfg.Replay.Fixtures)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var records []recorder.Record
+	for _, path := range cfg.Replay.Paths {
+		r, err := recorder.Load(path)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		records = append(records, r...)
+	}
+
+	var skuAPI vmcapabilities.API
+	if cfg.Replay.SKUCatalog != "" {
+		skuAPI, err = vmcapabilities.NewFileAPI(cfg.Replay.SKUCatalog)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	enforcementStore, err := enforcement.NewStore(enforcement.StoreConfig{
+		Logger: logger,
+		Path:   cfg.EnforcementConfigFile,
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	c := app.ReplayConfig{
+		BaseDomain:  cfg.BaseDomain,
+		Enforcement: enforcementStore,
+		Location:    cfg.Location,
+		Logger:      logger,
+		SKUAPI:      skuAPI,
+		Fixtures:    fixtures,
+		Records:     records,
+	}
+	differences, err := app.Replay(context.Background(), c)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	for _, d := range differences {
+		fmt.Printf("%s %s %s: %s changed\n  recorded: %s\n  replayed: %s\n", d.Record.Time.Format(time.RFC3339), d.Record.Path, d.Object, d.Field, d.Recorded, d.Replayed)
+	}
+
+	fmt.Fprintf(os.Stderr, "%d requests replayed, %d differences\n", len(records), len(differences))
+	if len(differences) > 0 {
+		return microerror.Maskf(failedError, "%d differences", len(differences))
+	}
+
+	return nil
+}
+
+// newFileWriter returns the writer for the audit log or the recorded traffic.
+// The file is never closed, it is written to for the whole lifetime of the
+// process. "-" is stderr, so that the entries don't mix with the logs, which
+// are written to stdout.
+func newFileWriter(path string) (io.Writer, error) {
+	switch path {
+	case "":
+		return ioutil.Discard, nil
+	case "-":
+		return os.Stderr, nil
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return f, nil
+}
+
+func healthCheck(writer http.ResponseWriter, request *http.Request) {
+	writer.WriteHeader(http.StatusOK)
+	_, err := writer.Write([]byte("ok"))
+	if err != nil {
 		panic(microerror.JSON(err))
 	}
+}
 
+func serve(config config.Config, cm *certman.CertMan, handler http.Handler, internalHandler http.Handler) {
 	server := &http.Server{
 		Addr:    config.Address,
 		Handler: handler,
@@ -204,20 +744,50 @@ func serve(config config.Config, handler http.Handler) {
 		},
 	}
 
+	var internalServer *http.Server
+	if config.InternalAddress != "" {
+		internalServer = &http.Server{
+			Addr:    config.InternalAddress,
+			Handler: internalHandler,
+		}
+
+		go func() {
+			err := internalServer.ListenAndServe()
+			if err != nil {
+				if err != http.ErrServerClosed {
+					panic(microerror.JSON(err))
+				}
+			}
+		}()
+	}
+
+	// ListenAndServeTLS returns as soon as the shutdown begins, so serve waits
+	// for the requests in flight, and their spans, to end.
+	shutdown := make(chan struct{})
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, syscall.SIGTERM)
 	go func() {
+		defer close(shutdown)
 		<-sig
+		if internalServer != nil {
+			err := internalServer.Shutdown(context.Background())
+			if err != nil {
+				panic(microerror.JSON(err))
+			}
+		}
+
 		err := server.Shutdown(context.Background())
 		if err != nil {
 			panic(microerror.JSON(err))
 		}
 	}()
 
-	err = server.ListenAndServeTLS("", "")
+	err := server.ListenAndServeTLS("", "")
 	if err != nil {
 		if err != http.ErrServerClosed {
 			panic(microerror.JSON(err))
 		}
 	}
+
+	<-shutdown
 }
@@ -0,0 +1,23 @@
+package app
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+var unavailableError = &microerror.Error{
+	Kind: "unavailableError",
+}
+
+// IsUnavailable asserts unavailableError.
+func IsUnavailable(err error) bool {
+	return microerror.Cause(err) == unavailableError
+}
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
@@ -10,14 +10,19 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/giantswarm/azure-admission-controller/internal/vmcapabilities"
+	"github.com/giantswarm/azure-admission-controller/pkg/audit"
 	"github.com/giantswarm/azure-admission-controller/pkg/azurecluster"
 	"github.com/giantswarm/azure-admission-controller/pkg/azuremachine"
 	"github.com/giantswarm/azure-admission-controller/pkg/azuremachinepool"
 	"github.com/giantswarm/azure-admission-controller/pkg/azureupdate"
 	"github.com/giantswarm/azure-admission-controller/pkg/cluster"
 	"github.com/giantswarm/azure-admission-controller/pkg/config"
+	"github.com/giantswarm/azure-admission-controller/pkg/enforcement"
 	"github.com/giantswarm/azure-admission-controller/pkg/machinepool"
+	"github.com/giantswarm/azure-admission-controller/pkg/metrics"
 	"github.com/giantswarm/azure-admission-controller/pkg/mutator"
+	"github.com/giantswarm/azure-admission-controller/pkg/recovery"
+	"github.com/giantswarm/azure-admission-controller/pkg/requestcache"
 	"github.com/giantswarm/azure-admission-controller/pkg/spark"
 	"github.com/giantswarm/azure-admission-controller/pkg/validator"
 )
@@ -38,78 +43,180 @@ import (
 //
 // - A webhook handler implementation that implements mutator.WebhookUpdateHandler will be
 // registered to handle HTTP requests at path `/mutate/<resource name>/update`.
-func RegisterWebhookHandlers(httpRequestHandler HttpRequestHandler, cfg config.Config, newLogger micrologger.Logger, ctrlClient client.Client, ctrlReader client.Reader, vmcaps *vmcapabilities.VMSKU) error {
+//
+// Handlers having a candidate, see candidateHandlers, are registered in shadow mode with it,
+// e.g. with validator.NewShadowCreateHandler, for the operations both of them implement. The
+// candidates are evaluated with the configuration of the specified candidateEnforcementStore
+// when it is not nil.
+//
+// Webhook handlers only read from the Kubernetes API with the specified ctrlReader, which should
+// be backed by the controller-runtime cache. The specified ctrlClient is used to record events.
+//
+// Every registered handler is wrapped with recovery.Middleware, so a panic in a webhook handler
+// denies the request with an internal error instead of dropping the connection, and the denial
+// is recorded in the specified audit log.
+//
+// The returned Webhooks must be waited for once the server stopped handling requests.
+func RegisterWebhookHandlers(httpRequestHandler HttpRequestHandler, cfg config.Config, newLogger micrologger.Logger, auditSink *audit.Sink, enforcementStore *enforcement.Store, candidateEnforcementStore *enforcement.Store, ctrlClient client.Client, ctrlReader client.Reader, vmcaps *vmcapabilities.VMSKU) (*Webhooks, error) {
 	var err error
 
+	// Every object is read at most once per admission request, the HTTP
+	// handler factories attach a request cache to the context of every request.
+	ctrlReader = requestcache.NewReader(ctrlReader)
+
 	var validatorHttpHandlerFactory *validator.HttpHandlerFactory
 	{
 		c := validator.HttpHandlerFactoryConfig{
-			CtrlClient: ctrlClient,
-			CtrlReader: ctrlReader,
-			Logger:     newLogger,
+			AuditSink:   auditSink,
+			CtrlClient:  ctrlClient,
+			CtrlReader:  ctrlReader,
+			Enforcement: enforcementStore,
+			Logger:      newLogger,
+
+			CandidateEnforcement: candidateEnforcementStore,
 		}
 		validatorHttpHandlerFactory, err = validator.NewHttpHandlerFactory(c)
 		if err != nil {
-			return microerror.Mask(err)
+			return nil, microerror.Mask(err)
 		}
 	}
 
 	var mutatorHttpHandlerFactory *mutator.HttpHandlerFactory
 	{
 		c := mutator.HttpHandlerFactoryConfig{
-			CtrlClient: ctrlClient,
-			CtrlReader: ctrlReader,
-			Logger:     newLogger,
+			AuditSink:   auditSink,
+			CtrlReader:  ctrlReader,
+			Enforcement: enforcementStore,
+			Logger:      newLogger,
+
+			CandidateEnforcement: candidateEnforcementStore,
 		}
 		mutatorHttpHandlerFactory, err = mutator.NewHttpHandlerFactory(c)
 		if err != nil {
-			return microerror.Mask(err)
+			return nil, microerror.Mask(err)
 		}
 	}
 
-	handlers, err := getAllHandlers(cfg, newLogger, ctrlClient, ctrlReader, vmcaps)
+	handlers, err := getAllHandlers(cfg, newLogger, ctrlReader, vmcaps)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	alternatives, err := getCandidateHandlers(cfg, newLogger, ctrlReader, vmcaps)
 	if err != nil {
-		return microerror.Mask(err)
+		return nil, microerror.Mask(err)
 	}
+	candidates := candidateHandlers(handlers, alternatives, candidateEnforcementStore != nil)
 
 	for _, h := range handlers {
+		candidate := candidates[h.Resource()]
+
 		// Check if the handler is implementing validator.WebhookCreateHandler, and if it does,
 		// register a handler function for validating create requests.
 		if webhookHandler, ok := h.(validator.WebhookCreateHandler); ok {
-			pattern := fmt.Sprintf("/validate/%s/create", webhookHandler.Resource())
+			pattern := webhookPath(metrics.WebhookValidate, webhookHandler.Resource(), metrics.OperationCreate)
 			httpHandlerFunc := validatorHttpHandlerFactory.NewCreateHandler(webhookHandler)
-			httpRequestHandler.Handle(pattern, httpHandlerFunc)
+			if candidateHandler, ok := candidate.(validator.WebhookCreateHandler); ok {
+				httpHandlerFunc = validatorHttpHandlerFactory.NewShadowCreateHandler(webhookHandler, candidateHandler)
+			}
+			httpRequestHandler.Handle(pattern, recovery.Middleware(newLogger, auditSink, webhookHandler.Resource(), metrics.OperationCreate, metrics.WebhookValidate, httpHandlerFunc))
 		}
 
 		// Check if the handler is implementing validator.WebhookUpdateHandler, and if it does,
 		// register a handler function for validating update requests.
 		if webhookHandler, ok := h.(validator.WebhookUpdateHandler); ok {
-			pattern := fmt.Sprintf("/validate/%s/update", webhookHandler.Resource())
+			pattern := webhookPath(metrics.WebhookValidate, webhookHandler.Resource(), metrics.OperationUpdate)
 			httpHandlerFunc := validatorHttpHandlerFactory.NewUpdateHandler(webhookHandler)
-			httpRequestHandler.Handle(pattern, httpHandlerFunc)
+			if candidateHandler, ok := candidate.(validator.WebhookUpdateHandler); ok {
+				httpHandlerFunc = validatorHttpHandlerFactory.NewShadowUpdateHandler(webhookHandler, candidateHandler)
+			}
+			httpRequestHandler.Handle(pattern, recovery.Middleware(newLogger, auditSink, webhookHandler.Resource(), metrics.OperationUpdate, metrics.WebhookValidate, httpHandlerFunc))
 		}
 
 		// Check if the handler is implementing mutator.WebhookCreateHandler, and if it does,
 		// register a handler function for validating create requests.
 		if webhookHandler, ok := h.(mutator.WebhookCreateHandler); ok {
-			pattern := fmt.Sprintf("/mutate/%s/create", webhookHandler.Resource())
+			pattern := webhookPath(metrics.WebhookMutate, webhookHandler.Resource(), metrics.OperationCreate)
 			httpHandlerFunc := mutatorHttpHandlerFactory.NewCreateHandler(webhookHandler)
-			httpRequestHandler.Handle(pattern, httpHandlerFunc)
+			if candidateHandler, ok := candidate.(mutator.WebhookCreateHandler); ok {
+				httpHandlerFunc = mutatorHttpHandlerFactory.NewShadowCreateHandler(webhookHandler, candidateHandler)
+			}
+			httpRequestHandler.Handle(pattern, recovery.Middleware(newLogger, auditSink, webhookHandler.Resource(), metrics.OperationCreate, metrics.WebhookMutate, httpHandlerFunc))
 		}
 
 		// Check if the handler is implementing mutator.WebhookUpdateHandler, and if it does,
 		// register a handler function for validating update requests.
 		if webhookHandler, ok := h.(mutator.WebhookUpdateHandler); ok {
-			pattern := fmt.Sprintf("/mutate/%s/update", webhookHandler.Resource())
+			pattern := webhookPath(metrics.WebhookMutate, webhookHandler.Resource(), metrics.OperationUpdate)
 			httpHandlerFunc := mutatorHttpHandlerFactory.NewUpdateHandler(webhookHandler)
-			httpRequestHandler.Handle(pattern, httpHandlerFunc)
+			if candidateHandler, ok := candidate.(mutator.WebhookUpdateHandler); ok {
+				httpHandlerFunc = mutatorHttpHandlerFactory.NewShadowUpdateHandler(webhookHandler, candidateHandler)
+			}
+			httpRequestHandler.Handle(pattern, recovery.Middleware(newLogger, auditSink, webhookHandler.Resource(), metrics.OperationUpdate, metrics.WebhookMutate, httpHandlerFunc))
+		}
+	}
+
+	webhooks := &Webhooks{
+		validatorHttpHandlerFactory: validatorHttpHandlerFactory,
+		mutatorHttpHandlerFactory:   mutatorHttpHandlerFactory,
+	}
+
+	return webhooks, nil
+}
+
+// Webhooks are the webhook handlers registered with RegisterWebhookHandlers.
+type Webhooks struct {
+	validatorHttpHandlerFactory *validator.HttpHandlerFactory
+	mutatorHttpHandlerFactory   *mutator.HttpHandlerFactory
+}
+
+// Wait waits for the comparisons of candidate handlers running in the background, so that
+// their logs and metrics are not lost on shutdown. Call it once the server stopped handling
+// requests.
+func (w *Webhooks) Wait() {
+	w.validatorHttpHandlerFactory.Wait()
+	w.mutatorHttpHandlerFactory.Wait()
+}
+
+// webhookPath returns the path the webhook of the specified kind, i.e.
+// "validate" or "mutate", is registered at for the specified resource and
+// operation.
+func webhookPath(webhook, resource, operation string) string {
+	return fmt.Sprintf("/%s/%s/%s", webhook, resource, operation)
+}
+
+// getCandidateHandlers returns alternative implementations of the handlers of getAllHandlers,
+// e.g. with new or changed checks, which are evaluated in shadow mode next to the handler of the
+// same resource, see candidateHandlers. Their decisions and patches are only compared with the
+// ones of the live handlers, see validator.NewShadowCreateHandler. Add them here like in
+// getAllHandlers, and once a candidate proved itself, it replaces the live handler.
+func getCandidateHandlers(cfg config.Config, newLogger micrologger.Logger, ctrlReader client.Reader, vmcaps *vmcapabilities.VMSKU) ([]ResourceHandler, error) {
+	var candidates []ResourceHandler
+
+	return candidates, nil
+}
+
+// candidateHandlers returns the candidates of the specified handlers by resource. A handler's
+// candidate is its alternative implementation of getCandidateHandlers. With a candidate
+// enforcement configuration, every other handler is its own candidate, evaluated with that
+// configuration instead of the live one, e.g. with a check switched from audit to enforce mode.
+// That configuration is read for every request, so that changes apply to handlers and checks
+// without a restart.
+func candidateHandlers(handlers []ResourceHandler, alternatives []ResourceHandler, candidateEnforcement bool) map[string]ResourceHandler {
+	candidates := map[string]ResourceHandler{}
+	if candidateEnforcement {
+		for _, h := range handlers {
+			candidates[h.Resource()] = h
 		}
 	}
+	for _, a := range alternatives {
+		candidates[a.Resource()] = a
+	}
 
-	return nil
+	return candidates
 }
 
-func getAllHandlers(cfg config.Config, newLogger micrologger.Logger, ctrlClient client.Client, ctrlReader client.Reader, vmcaps *vmcapabilities.VMSKU) ([]ResourceHandler, error) {
+func getAllHandlers(cfg config.Config, newLogger micrologger.Logger, ctrlReader client.Reader, vmcaps *vmcapabilities.VMSKU) ([]ResourceHandler, error) {
 	scheme := runtime.NewScheme()
 	codecs := serializer.NewCodecFactory(scheme)
 	universalDeserializer := codecs.UniversalDeserializer()
@@ -117,7 +224,7 @@ func getAllHandlers(cfg config.Config, newLogger micrologger.Logger, ctrlClient
 
 	{
 		c := azureupdate.AzureConfigWebhookHandlerConfig{
-			CtrlClient: ctrlClient,
+			CtrlReader: ctrlReader,
 			Decoder:    universalDeserializer,
 			Logger:     newLogger,
 		}
@@ -132,7 +239,6 @@ func getAllHandlers(cfg config.Config, newLogger micrologger.Logger, ctrlClient
 		c := azurecluster.WebhookHandlerConfig{
 			BaseDomain: cfg.BaseDomain,
 			CtrlReader: ctrlReader,
-			CtrlClient: ctrlClient,
 			Decoder:    universalDeserializer,
 			Location:   cfg.Location,
 			Logger:     newLogger,
@@ -146,7 +252,7 @@ func getAllHandlers(cfg config.Config, newLogger micrologger.Logger, ctrlClient
 
 	{
 		c := azureupdate.AzureClusterConfigWebhookHandlerConfig{
-			CtrlClient: ctrlClient,
+			CtrlReader: ctrlReader,
 			Decoder:    universalDeserializer,
 			Logger:     newLogger,
 		}
@@ -159,7 +265,7 @@ func getAllHandlers(cfg config.Config, newLogger micrologger.Logger, ctrlClient
 
 	{
 		c := azuremachine.WebhookHandlerConfig{
-			CtrlClient: ctrlClient,
+			CtrlReader: ctrlReader,
 			Decoder:    universalDeserializer,
 			Location:   cfg.Location,
 			Logger:     newLogger,
@@ -174,7 +280,7 @@ func getAllHandlers(cfg config.Config, newLogger micrologger.Logger, ctrlClient
 
 	{
 		c := azuremachinepool.WebhookHandlerConfig{
-			CtrlClient: ctrlClient,
+			CtrlReader: ctrlReader,
 			Decoder:    universalDeserializer,
 			Location:   cfg.Location,
 			Logger:     newLogger,
@@ -190,7 +296,6 @@ func getAllHandlers(cfg config.Config, newLogger micrologger.Logger, ctrlClient
 	{
 		c := cluster.WebhookHandlerConfig{
 			BaseDomain: cfg.BaseDomain,
-			CtrlClient: ctrlClient,
 			CtrlReader: ctrlReader,
 			Decoder:    universalDeserializer,
 			Logger:     newLogger,
@@ -204,7 +309,7 @@ func getAllHandlers(cfg config.Config, newLogger micrologger.Logger, ctrlClient
 
 	{
 		c := machinepool.WebhookHandlerConfig{
-			CtrlClient: ctrlClient,
+			CtrlReader: ctrlReader,
 			Decoder:    universalDeserializer,
 			Logger:     newLogger,
 			VMcaps:     vmcaps,
@@ -218,7 +323,7 @@ func getAllHandlers(cfg config.Config, newLogger micrologger.Logger, ctrlClient
 
 	{
 		c := spark.WebhookHandlerConfig{
-			CtrlClient: ctrlClient,
+			CtrlReader: ctrlReader,
 			Decoder:    universalDeserializer,
 			Logger:     newLogger,
 		}
@@ -1,14 +1,24 @@
 package app
 
 import (
+	"bytes"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
+	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/giantswarm/azure-admission-controller/internal/vmcapabilities"
+	"github.com/giantswarm/azure-admission-controller/pkg/audit"
 	"github.com/giantswarm/azure-admission-controller/pkg/config"
+	"github.com/giantswarm/azure-admission-controller/pkg/enforcement"
+	"github.com/giantswarm/azure-admission-controller/pkg/metrics"
+	"github.com/giantswarm/azure-admission-controller/pkg/offline"
 	"github.com/giantswarm/azure-admission-controller/pkg/unittest"
 )
 
@@ -39,12 +49,229 @@ func Test_RegisterWebhookHandlers(t *testing.T) {
 		t.Fatal(microerror.JSON(err))
 	}
 
+	auditSink, err := audit.NewSink(audit.SinkConfig{
+		Writer: ioutil.Discard,
+	})
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+
+	enforcementStore, err := enforcement.NewStore(enforcement.StoreConfig{
+		Logger: logger,
+	})
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+
 	// Real *http.ServeMux, not that we gonna run it here.
 	handler := http.NewServeMux()
 
 	// Run webhook handlers registration.
-	err = RegisterWebhookHandlers(handler, cfg, logger, ctrlClient, ctrlClient, vmcaps)
+	_, err = RegisterWebhookHandlers(handler, cfg, logger, auditSink, enforcementStore, nil, ctrlClient, ctrlClient, vmcaps)
 	if err != nil {
 		t.Fatalf("Error while registering webhook handlers %#v", err)
 	}
 }
+
+// Test_RegisterWebhookHandlers_candidateEnforcement registers the handlers with
+// a candidate enforcement configuration, which is reloaded, and checks that
+// the handlers are evaluated with it in shadow mode.
+func Test_RegisterWebhookHandlers_candidateEnforcement(t *testing.T) {
+	logger, err := micrologger.New(micrologger.Config{IOWriter: ioutil.Discard})
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+
+	fixtures, err := offline.Load(filepath.Join("testdata", "validate", "fixtures"))
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+	manifests, err := offline.Load(filepath.Join("testdata", "validate", "manifests", "cluster.yaml"))
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+	ctrlClient, vmcaps, err := newOfflineClients(logger, nil, fixtures)
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+
+	auditSink, err := audit.NewSink(audit.SinkConfig{Writer: ioutil.Discard})
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+	enforcementStore, err := enforcement.NewStore(enforcement.StoreConfig{Logger: logger})
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+
+	// The candidate doesn't validate clusters.
+	candidateEnforcementConfigFile := filepath.Join(t.TempDir(), "candidate-enforcement.yaml")
+	err = ioutil.WriteFile(candidateEnforcementConfigFile, []byte("handlers:\n  cluster:\n    enabled: false\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	candidateEnforcementStore, err := enforcement.NewStore(enforcement.StoreConfig{
+		Logger: logger,
+		Path:   candidateEnforcementConfigFile,
+	})
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+
+	handler := http.NewServeMux()
+	cfg := config.Config{BaseDomain: replayBaseDomain, Location: replayLocation}
+	webhooks, err := RegisterWebhookHandlers(handler, cfg, logger, auditSink, enforcementStore, candidateEnforcementStore, ctrlClient, ctrlClient, vmcaps)
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+
+	// The live handler denies the cluster, its control plane endpoint doesn't
+	// match the base domain.
+	validate := func(result string) {
+		before := shadowEvaluations(t, "cluster", metrics.OperationCreate, metrics.WebhookValidate, result)
+
+		request := httptest.NewRequest(http.MethodPost, "/validate/cluster/create", bytes.NewReader(admissionReviewBody(t, manifests[0])))
+		request.Header.Set("Content-Type", "application/json")
+		handler.ServeHTTP(httptest.NewRecorder(), request)
+		webhooks.Wait()
+
+		after := shadowEvaluations(t, "cluster", metrics.OperationCreate, metrics.WebhookValidate, result)
+		if after != before+1 {
+			t.Fatalf("expected one shadow evaluation with result %#q, got %v", result, after-before)
+		}
+	}
+
+	validate(metrics.ShadowDecisionDivergence)
+
+	// The candidate validates clusters once the configuration is reloaded.
+	err = ioutil.WriteFile(candidateEnforcementConfigFile, []byte("handlers:\n  cluster:\n    enabled: true\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = candidateEnforcementStore.Reload()
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+
+	validate(metrics.ShadowMatch)
+}
+
+// alternativeHandler is an alternative implementation of the handler of a
+// resource.
+type alternativeHandler struct {
+	ResourceHandler
+}
+
+func Test_candidateHandlers(t *testing.T) {
+	var err error
+	var logger micrologger.Logger
+	{
+		logger, err = micrologger.New(micrologger.Config{IOWriter: ioutil.Discard})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := config.Config{
+		BaseDomain: "k8s.test.westeurope.azure.gigantic.io",
+		Location:   "westeurope",
+	}
+
+	ctrlClient := unittest.FakeK8sClient().CtrlClient()
+	vmcaps, err := vmcapabilities.New(vmcapabilities.Config{
+		Azure:  unittest.NewEmptyResourceSkuStubAPI(),
+		Logger: logger,
+	})
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+
+	handlers, err := getAllHandlers(cfg, logger, ctrlClient, vmcaps)
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+	live := map[string]ResourceHandler{}
+	for _, h := range handlers {
+		live[h.Resource()] = h
+	}
+	alternative := &alternativeHandler{ResourceHandler: live["azuremachinepool"]}
+
+	testCases := []struct {
+		name                 string
+		alternatives         []ResourceHandler
+		candidateEnforcement bool
+		// expectedAlternatives are the resources whose candidate is the
+		// alternative implementation, all others are their own candidate.
+		expectedAlternatives []string
+		// expectedCandidates is the number of expected candidates.
+		expectedCandidates int
+	}{
+		{
+			name: "case 0: no alternative implementations and no candidate enforcement configuration",
+		},
+		{
+			name:                 "case 1: alternative implementation",
+			alternatives:         []ResourceHandler{alternative},
+			expectedAlternatives: []string{"azuremachinepool"},
+			expectedCandidates:   1,
+		},
+		{
+			name:                 "case 2: candidate enforcement configuration",
+			candidateEnforcement: true,
+			expectedCandidates:   len(handlers),
+		},
+		{
+			name:                 "case 3: alternative implementation and candidate enforcement configuration",
+			alternatives:         []ResourceHandler{alternative},
+			candidateEnforcement: true,
+			expectedAlternatives: []string{"azuremachinepool"},
+			expectedCandidates:   len(handlers),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			candidates := candidateHandlers(handlers, tc.alternatives, tc.candidateEnforcement)
+			if len(candidates) != tc.expectedCandidates {
+				t.Fatalf("expected %d candidates, got %d", tc.expectedCandidates, len(candidates))
+			}
+
+			var alternatives []string
+			for resource, c := range candidates {
+				if c == live[resource] {
+					// The live handler is reused as its own candidate.
+					continue
+				}
+				alternatives = append(alternatives, resource)
+			}
+			if !reflect.DeepEqual(alternatives, tc.expectedAlternatives) {
+				t.Fatalf("expected alternative implementations for %v, got %v", tc.expectedAlternatives, alternatives)
+			}
+		})
+	}
+}
+
+func shadowEvaluations(t *testing.T, resource, operation, webhook, result string) float64 {
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, family := range families {
+		if family.GetName() != "azure_admission_controller_webhook_shadow_evaluations_total" {
+			continue
+		}
+
+		for _, m := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["resource"] == resource && labels["operation"] == operation && labels["webhook"] == webhook && labels["result"] == result {
+				return m.GetCounter().GetValue()
+			}
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,204 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/pmezard/go-difflib/difflib"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/azure-admission-controller/internal/vmcapabilities"
+	"github.com/giantswarm/azure-admission-controller/pkg/generic"
+	"github.com/giantswarm/azure-admission-controller/pkg/mutator"
+	"github.com/giantswarm/azure-admission-controller/pkg/offline"
+)
+
+type MutateManifestsConfig struct {
+	// BaseDomain and Location are the ones of the installation the manifests
+	// are meant for.
+	BaseDomain string
+	Location   string
+	Logger     micrologger.Logger
+	// SKUAPI serves the VM SKUs, e.g. vmcapabilities.NewFileAPI. SKU lookups
+	// fail when it is nil.
+	SKUAPI vmcapabilities.API
+
+	// Fixtures are the objects the handlers look up, e.g. Release,
+	// Organization and Cluster objects.
+	Fixtures []offline.Manifest
+	// Manifests are the objects to mutate.
+	Manifests []offline.Manifest
+	// Old are the current versions of the objects to mutate. Manifests with
+	// an old version are mutated like an update, the others like a create.
+	Old []offline.Manifest
+}
+
+// MutatedManifest is the result of mutating a manifest.
+type MutatedManifest struct {
+	Manifest offline.Manifest
+	// Handled is false when no mutating webhook handler is registered for
+	// the kind and operation of the manifest.
+	Handled bool
+	// Skipped is true when the object is not reconciled by a legacy release,
+	// so the webhooks don't mutate it.
+	Skipped bool
+	// Update is true when the manifest was mutated like an update.
+	Update bool
+	// Original is the object as JSON before it was mutated, serialized like
+	// the API server does, and Mutated the object after it was mutated. Both
+	// are empty when the manifest was not handled or skipped.
+	Original []byte
+	Mutated  []byte
+	Patch    []mutator.PatchOperation
+	// Violations are the errors the mutating webhook denied the request with.
+	Violations []ManifestViolation
+	Warnings   []string
+}
+
+// MutateManifests runs the mutating webhook handlers for the specified
+// manifests like the API server would when they are created or updated, and
+// returns the mutated objects. The handlers read the fixtures and the
+// manifests from a fake client instead of the management cluster.
+func MutateManifests(ctx context.Context, config MutateManifestsConfig) ([]MutatedManifest, error) {
+	if config.BaseDomain == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.BaseDomain must not be empty", config)
+	}
+	if config.Location == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Location must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	// The old versions are served, like the API server would serve the
+	// stored objects during an update.
+	ctrlClient, handlers, err := newOfflineHandlers(config.BaseDomain, config.Location, config.Logger, config.SKUAPI, config.Fixtures, config.Manifests, config.Old)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	old := map[string]offline.Manifest{}
+	for _, m := range config.Old {
+		old[manifestKey(m)] = m
+	}
+
+	var results []MutatedManifest
+	for _, m := range config.Manifests {
+		var oldManifest *offline.Manifest
+		if o, ok := old[manifestKey(m)]; ok {
+			oldManifest = &o
+		}
+
+		result, err := mutateManifest(ctx, config.Logger, ctrlClient, handlers, m, oldManifest)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func mutateManifest(ctx context.Context, logger micrologger.Logger, ctrlClient client.Client, handlers []ResourceHandler, m offline.Manifest, old *offline.Manifest) (MutatedManifest, error) {
+	result := MutatedManifest{
+		Manifest: m,
+		Update:   old != nil,
+	}
+
+	handler := handlerForKind(handlers, m.GroupVersionKind().Group, m.GroupVersionKind().Version, m.Kind)
+	decoder, ok := handler.(generic.Decoder)
+	if !ok {
+		return result, nil
+	}
+	createHandler, isCreateHandler := handler.(mutator.WebhookCreateHandler)
+	updateHandler, isUpdateHandler := handler.(mutator.WebhookUpdateHandler)
+	if (old == nil && !isCreateHandler) || (old != nil && !isUpdateHandler) {
+		return result, nil
+	}
+	result.Handled = true
+
+	// Nothing is persisted, handlers must not have side effects.
+	ctx = generic.WithWarnings(ctx)
+	ctx = generic.WithDryRun(ctx, true)
+
+	object, err := decoder.Decode(runtime.RawExtension{Raw: m.Object})
+	if err != nil {
+		result.Violations = newManifestViolations(m, err)
+		return result, nil
+	}
+
+	reconciled, err := isReconciled(ctx, logger, ctrlClient, object)
+	if err != nil {
+		return MutatedManifest{}, microerror.Mask(err)
+	}
+	if !reconciled {
+		result.Skipped = true
+		return result, nil
+	}
+
+	var patch []mutator.PatchOperation
+	if old == nil {
+		patch, err = createHandler.OnCreateMutate(ctx, object)
+	} else {
+		var oldObject interface{}
+		oldObject, err = decoder.Decode(runtime.RawExtension{Raw: old.Object})
+		if err != nil {
+			return MutatedManifest{}, microerror.Mask(err)
+		}
+		patch, err = updateHandler.OnUpdateMutate(ctx, oldObject, object)
+	}
+	result.Warnings = generic.Warnings(ctx)
+	if err != nil {
+		result.Violations = newManifestViolations(m, err)
+		return result, nil
+	}
+
+	result.Original, err = json.Marshal(object)
+	if err != nil {
+		return MutatedManifest{}, microerror.Mask(err)
+	}
+	_, result.Mutated, err = patchObject(decoder, object, patch)
+	if err != nil {
+		return MutatedManifest{}, microerror.Mask(err)
+	}
+	result.Patch = patch
+
+	return result, nil
+}
+
+// manifestKey returns the identity of the object of the specified manifest.
+func manifestKey(m offline.Manifest) string {
+	return m.GroupVersionKind().String() + "/" + m.Namespace + "/" + m.Name
+}
+
+// MutationDiff returns a unified diff of the specified mutated manifest, from
+// the object as the API server serializes it to the mutated object, as YAML.
+func MutationDiff(m MutatedManifest) (string, error) {
+	original, err := yaml.JSONToYAML(m.Original)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+	mutated, err := yaml.JSONToYAML(m.Mutated)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	name := fmt.Sprintf("%s (%s %s/%s)", m.Manifest.File, m.Manifest.Kind, m.Manifest.Namespace, m.Manifest.Name)
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(original)),
+		B:        difflib.SplitLines(string(mutated)),
+		FromFile: "a/" + name,
+		ToFile:   "b/" + name,
+		Context:  3,
+	})
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return diff, nil
+}
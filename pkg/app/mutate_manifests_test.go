@@ -0,0 +1,111 @@
+package app
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/azure-admission-controller/pkg/offline"
+)
+
+func Test_MutateManifests(t *testing.T) {
+	logger, err := micrologger.New(micrologger.Config{IOWriter: ioutil.Discard})
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+
+	fixtures, err := offline.Load(filepath.Join("testdata", "validate", "fixtures"))
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+	manifests, err := offline.Load(filepath.Join("testdata", "validate", "manifests"))
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+	old, err := offline.Load(filepath.Join("testdata", "mutate", "old"))
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+
+	results, err := MutateManifests(context.Background(), MutateManifestsConfig{
+		BaseDomain: "k8s.test.westeurope.azure.gigantic.io",
+		Location:   "westeurope",
+		Logger:     logger,
+		Fixtures:   fixtures,
+		Manifests:  manifests,
+		Old:        old,
+	})
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+
+	type result struct {
+		name    string
+		handled bool
+		skipped bool
+		update  bool
+		paths   []string
+	}
+	var actual []result
+	for _, r := range results {
+		var paths []string
+		for _, p := range r.Patch {
+			paths = append(paths, p.Operation+" "+p.Path)
+		}
+		actual = append(actual, result{
+			name:    r.Manifest.Name,
+			handled: r.Handled,
+			skipped: r.Skipped,
+			update:  r.Update,
+			paths:   paths,
+		})
+	}
+
+	expected := []result{
+		// Updated, there is an old version.
+		{
+			name:    "ab123",
+			handled: true,
+			update:  true,
+		},
+		// Created, the control plane endpoint and cluster network are
+		// defaulted.
+		{
+			name:    "cd456",
+			handled: true,
+			paths: []string{
+				"add /spec/clusterNetwork",
+				"add /spec/controlPlaneEndpoint/host",
+				"add /spec/controlPlaneEndpoint/port",
+			},
+		},
+		// Release 20.0.0 is not in the fixtures.
+		{
+			name:    "ef789",
+			handled: true,
+			skipped: true,
+		},
+		// ConfigMaps are not handled.
+		{
+			name: "values",
+		},
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("results == %#v, want %#v", actual, expected)
+	}
+
+	diff, err := MutationDiff(results[1])
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+	if !strings.Contains(diff, "+    host: api.cd456.k8s.test.westeurope.azure.gigantic.io\n") {
+		t.Fatalf("diff does not contain the defaulted control plane endpoint host:\n%s", diff)
+	}
+}
@@ -0,0 +1,231 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	admissionv1 "k8s.io/api/admission/v1"
+
+	"github.com/giantswarm/azure-admission-controller/internal/admissionreview"
+	"github.com/giantswarm/azure-admission-controller/internal/vmcapabilities"
+	"github.com/giantswarm/azure-admission-controller/pkg/audit"
+	appconfig "github.com/giantswarm/azure-admission-controller/pkg/config"
+	"github.com/giantswarm/azure-admission-controller/pkg/enforcement"
+	"github.com/giantswarm/azure-admission-controller/pkg/offline"
+	"github.com/giantswarm/azure-admission-controller/pkg/recorder"
+)
+
+const (
+	// ReplayFieldResponse is the field of a ReplayDifference when the request
+	// could not be answered like it was recorded, e.g. because the webhook is
+	// not registered anymore.
+	ReplayFieldResponse = "response"
+	ReplayFieldAllowed  = "allowed"
+	ReplayFieldStatus   = "status"
+	ReplayFieldPatch    = "patch"
+	ReplayFieldWarnings = "warnings"
+)
+
+type ReplayConfig struct {
+	// BaseDomain and Location are the ones of the installation the traffic
+	// was recorded in.
+	BaseDomain  string
+	Location    string
+	Enforcement *enforcement.Store
+	Logger      micrologger.Logger
+	// SKUAPI serves the VM SKUs, e.g. vmcapabilities.NewFileAPI. SKU lookups
+	// fail when it is nil.
+	SKUAPI vmcapabilities.API
+
+	// Fixtures are the objects the handlers look up, e.g. Release,
+	// Organization and Cluster objects, as they were when the traffic was
+	// recorded.
+	Fixtures []offline.Manifest
+	Records  []recorder.Record
+}
+
+// ReplayDifference is a difference between the recorded response to a request
+// and the response of the replay.
+type ReplayDifference struct {
+	Record recorder.Record
+	// Object is the kind and the name of the object of the request, e.g.
+	// "AzureMachinePool org-acme/a1b2c".
+	Object string
+	// Field is the field of the response which changed, e.g.
+	// ReplayFieldAllowed.
+	Field    string
+	Recorded string
+	Replayed string
+}
+
+// Replay sends the requests of the specified records to the webhook handlers
+// of this build and returns the differences between the recorded responses
+// and the new ones. The handlers read the fixtures from a fake client instead
+// of the management cluster.
+func Replay(ctx context.Context, config ReplayConfig) ([]ReplayDifference, error) {
+	if config.BaseDomain == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.BaseDomain must not be empty", config)
+	}
+	if config.Enforcement == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Enforcement must not be empty", config)
+	}
+	if config.Location == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Location must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	ctrlClient, vmcaps, err := newOfflineClients(config.Logger, config.SKUAPI, config.Fixtures)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	auditSink, err := audit.NewSink(audit.SinkConfig{
+		Writer: ioutil.Discard,
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	cfg := appconfig.Config{
+		BaseDomain: config.BaseDomain,
+		Location:   config.Location,
+	}
+	handler := http.NewServeMux()
+	webhooks, err := RegisterWebhookHandlers(handler, cfg, config.Logger, auditSink, config.Enforcement, nil, ctrlClient, ctrlClient, vmcaps)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	defer webhooks.Wait()
+
+	var differences []ReplayDifference
+	for _, r := range config.Records {
+		d, err := replayRecord(ctx, handler, r)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		differences = append(differences, d...)
+	}
+
+	return differences, nil
+}
+
+func replayRecord(ctx context.Context, handler http.Handler, r recorder.Record) ([]ReplayDifference, error) {
+	admissionRequest, _, err := admissionreview.Decode(r.Request)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	name := admissionRequest.Name
+	if admissionRequest.Namespace != "" {
+		name = admissionRequest.Namespace + "/" + name
+	}
+	newDifference := func(field, recorded, replayed string) ReplayDifference {
+		return ReplayDifference{
+			Record:   r,
+			Object:   fmt.Sprintf("%s %s", admissionRequest.Kind.Kind, name),
+			Field:    field,
+			Recorded: recorded,
+			Replayed: replayed,
+		}
+	}
+
+	request := httptest.NewRequest(http.MethodPost, r.Path, bytes.NewReader(r.Request)).WithContext(ctx)
+	request.Header.Set("Content-Type", "application/json")
+	writer := httptest.NewRecorder()
+	handler.ServeHTTP(writer, request)
+
+	recorded, recordedWarnings, recordedErr := admissionreview.DecodeResponse(r.Response)
+	replayed, replayedWarnings, replayedErr := admissionreview.DecodeResponse(writer.Body.Bytes())
+	if recordedErr != nil || replayedErr != nil {
+		if recordedErr != nil && replayedErr != nil {
+			return nil, nil
+		}
+
+		recordedResponse, replayedResponse := "AdmissionReview", "AdmissionReview"
+		if recordedErr != nil {
+			recordedResponse = "no AdmissionReview"
+		}
+		if replayedErr != nil {
+			replayedResponse = fmt.Sprintf("HTTP %d without AdmissionReview", writer.Code)
+			if writer.Code == http.StatusNotFound {
+				replayedResponse = "webhook not registered"
+			}
+		}
+
+		return []ReplayDifference{
+			newDifference(ReplayFieldResponse, recordedResponse, replayedResponse),
+		}, nil
+	}
+
+	var differences []ReplayDifference
+	if recorded.Allowed != replayed.Allowed {
+		differences = append(differences, newDifference(ReplayFieldAllowed, fmt.Sprint(recorded.Allowed), fmt.Sprint(replayed.Allowed)))
+	}
+	if s, rs := describeStatus(recorded), describeStatus(replayed); s != rs {
+		differences = append(differences, newDifference(ReplayFieldStatus, s, rs))
+	}
+	equal, err := equalPatches(recorded.Patch, replayed.Patch)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	if !equal {
+		differences = append(differences, newDifference(ReplayFieldPatch, string(recorded.Patch), string(replayed.Patch)))
+	}
+	if !reflect.DeepEqual(recordedWarnings, replayedWarnings) {
+		differences = append(differences, newDifference(ReplayFieldWarnings, strings.Join(recordedWarnings, "; "), strings.Join(replayedWarnings, "; ")))
+	}
+
+	return differences, nil
+}
+
+func describeStatus(response *admissionv1.AdmissionResponse) string {
+	if response.Result == nil {
+		return ""
+	}
+
+	return fmt.Sprintf("%d %s: %s", response.Result.Code, response.Result.Reason, response.Result.Message)
+}
+
+// equalPatches returns whether the specified JSON patches are equal, ignoring
+// their formatting.
+func equalPatches(a, b []byte) (bool, error) {
+	decode := func(patch []byte) (interface{}, error) {
+		if len(patch) == 0 {
+			return nil, nil
+		}
+
+		var decoded interface{}
+		err := json.Unmarshal(patch, &decoded)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		// An empty patch is serialized as null or as an empty array.
+		if p, ok := decoded.([]interface{}); ok && len(p) == 0 {
+			return nil, nil
+		}
+
+		return decoded, nil
+	}
+
+	decodedA, err := decode(a)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+	decodedB, err := decode(b)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	return reflect.DeepEqual(decodedA, decodedB), nil
+}
@@ -0,0 +1,198 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/giantswarm/azure-admission-controller/pkg/audit"
+	"github.com/giantswarm/azure-admission-controller/pkg/config"
+	"github.com/giantswarm/azure-admission-controller/pkg/enforcement"
+	"github.com/giantswarm/azure-admission-controller/pkg/offline"
+	"github.com/giantswarm/azure-admission-controller/pkg/recorder"
+)
+
+const (
+	replayBaseDomain = "k8s.test.westeurope.azure.gigantic.io"
+	replayLocation   = "westeurope"
+)
+
+// Test_Replay records the traffic of this build and replays it, which must
+// not find differences, then replays it with a tampered recording.
+func Test_Replay(t *testing.T) {
+	logger, err := micrologger.New(micrologger.Config{IOWriter: ioutil.Discard})
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+
+	fixtures, err := offline.Load(filepath.Join("testdata", "validate", "fixtures"))
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+	manifests, err := offline.Load(filepath.Join("testdata", "validate", "manifests", "cluster.yaml"))
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+
+	enforcementStore, err := enforcement.NewStore(enforcement.StoreConfig{Logger: logger})
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+
+	records := recordTraffic(t, logger, enforcementStore, fixtures, []recordedRequest{
+		// Denied, the control plane endpoint doesn't match the base domain.
+		{path: "/validate/cluster/create", manifest: manifests[0]},
+		// Patched, the control plane endpoint is defaulted.
+		{path: "/mutate/cluster/create", manifest: manifests[1]},
+	})
+
+	replay := func(records []recorder.Record) []string {
+		differences, err := Replay(context.Background(), ReplayConfig{
+			BaseDomain:  replayBaseDomain,
+			Enforcement: enforcementStore,
+			Location:    replayLocation,
+			Logger:      logger,
+			Fixtures:    fixtures,
+			Records:     records,
+		})
+		if err != nil {
+			t.Fatal(microerror.JSON(err))
+		}
+
+		var fields []string
+		for _, d := range differences {
+			fields = append(fields, d.Record.Path+" "+d.Object+" "+d.Field)
+		}
+
+		return fields
+	}
+
+	differences := replay(records)
+	if len(differences) != 0 {
+		t.Fatalf("differences == %#v, want none", differences)
+	}
+
+	// The recorded request was allowed and the patch was different, e.g. by
+	// a previous build.
+	tampered := append([]recorder.Record(nil), records...)
+	tampered[0].Response = json.RawMessage(`{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview","response":{"uid":"abc","allowed":true}}`)
+	tampered[1].Response = json.RawMessage(`{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview","response":{"uid":"abc","allowed":true,"patch":"W10="}}`)
+	// The webhook is not registered anymore.
+	tampered = append(tampered, recorder.Record{
+		Path:     "/validate/g8scontrolplane/create",
+		Request:  records[0].Request,
+		Response: records[0].Response,
+	})
+
+	differences = replay(tampered)
+	expected := []string{
+		"/validate/cluster/create Cluster org-acme/ab123 allowed",
+		"/validate/cluster/create Cluster org-acme/ab123 status",
+		"/mutate/cluster/create Cluster org-acme/cd456 patch",
+		"/validate/g8scontrolplane/create Cluster org-acme/ab123 response",
+	}
+	if !reflect.DeepEqual(differences, expected) {
+		t.Fatalf("differences == %#v, want %#v", differences, expected)
+	}
+}
+
+type recordedRequest struct {
+	path     string
+	manifest offline.Manifest
+}
+
+// recordTraffic sends create requests for the specified manifests to the
+// webhook handlers of this build and returns the recorded traffic.
+func recordTraffic(t *testing.T, logger micrologger.Logger, enforcementStore *enforcement.Store, fixtures []offline.Manifest, requests []recordedRequest) []recorder.Record {
+	ctrlClient, vmcaps, err := newOfflineClients(logger, nil, fixtures)
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+
+	auditSink, err := audit.NewSink(audit.SinkConfig{Writer: ioutil.Discard})
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+
+	handler := http.NewServeMux()
+	cfg := config.Config{BaseDomain: replayBaseDomain, Location: replayLocation}
+	_, err = RegisterWebhookHandlers(handler, cfg, logger, auditSink, enforcementStore, nil, ctrlClient, ctrlClient, vmcaps)
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+
+	recordFile := filepath.Join(t.TempDir(), "records.jsonl")
+	var buffer bytes.Buffer
+	r, err := recorder.New(recorder.Config{Logger: logger, Writer: &buffer})
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+	recordingHandler := r.Middleware(handler)
+
+	for _, request := range requests {
+		body := admissionReviewBody(t, request.manifest)
+		httpRequest := httptest.NewRequest(http.MethodPost, request.path, bytes.NewReader(body))
+		httpRequest.Header.Set("Content-Type", "application/json")
+		recordingHandler.ServeHTTP(httptest.NewRecorder(), httpRequest)
+	}
+
+	err = ioutil.WriteFile(recordFile, buffer.Bytes(), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := recorder.Load(recordFile)
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+	if len(records) != len(requests) {
+		t.Fatalf("%d records, want %d", len(records), len(requests))
+	}
+
+	return records
+}
+
+// admissionReviewBody returns an AdmissionReview of a create request for the
+// specified manifest.
+func admissionReviewBody(t *testing.T, manifest offline.Manifest) []byte {
+	review := admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "admission.k8s.io/v1",
+			Kind:       "AdmissionReview",
+		},
+		Request: &admissionv1.AdmissionRequest{
+			UID: "abc",
+			Kind: metav1.GroupVersionKind{
+				Group:   manifest.GroupVersionKind().Group,
+				Version: manifest.GroupVersionKind().Version,
+				Kind:    manifest.Kind,
+			},
+			Name:      manifest.Name,
+			Namespace: manifest.Namespace,
+			Operation: admissionv1.Create,
+			UserInfo: authenticationv1.UserInfo{
+				Username: "jane",
+				Groups:   []string{"customer:admins"},
+			},
+			Object: runtime.RawExtension{Raw: manifest.Object},
+		},
+	}
+	body, err := json.Marshal(review)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return body
+}
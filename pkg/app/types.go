@@ -2,10 +2,15 @@ package app
 
 import (
 	"net/http"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 type ResourceHandler interface {
 	Resource() string
+	// GroupVersionResource returns the resource the webhooks of the handler
+	// are registered for in the webhook configurations.
+	GroupVersionResource() schema.GroupVersionResource
 }
 
 type HttpRequestHandler interface {
@@ -0,0 +1,332 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha3"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/azure-admission-controller/internal/vmcapabilities"
+	appconfig "github.com/giantswarm/azure-admission-controller/pkg/config"
+	"github.com/giantswarm/azure-admission-controller/pkg/filter"
+	"github.com/giantswarm/azure-admission-controller/pkg/generic"
+	"github.com/giantswarm/azure-admission-controller/pkg/mutator"
+	"github.com/giantswarm/azure-admission-controller/pkg/offline"
+	"github.com/giantswarm/azure-admission-controller/pkg/validator"
+)
+
+type ValidateManifestsConfig struct {
+	// BaseDomain and Location are the ones of the installation the manifests
+	// are meant for.
+	BaseDomain string
+	Location   string
+	Logger     micrologger.Logger
+	// SKUAPI serves the VM SKUs, e.g. vmcapabilities.NewFileAPI. SKU lookups
+	// fail when it is nil.
+	SKUAPI vmcapabilities.API
+
+	// Fixtures are the objects the handlers look up, e.g. Release,
+	// Organization and Cluster objects.
+	Fixtures []offline.Manifest
+	// Manifests are the objects to validate. They are served to the handlers
+	// too, so that e.g. a MachinePool finds the Cluster it belongs to.
+	Manifests []offline.Manifest
+}
+
+// ManifestResult is the result of validating a manifest.
+type ManifestResult struct {
+	Manifest offline.Manifest
+	// Handled is false when no webhook handler is registered for the kind of
+	// the manifest.
+	Handled bool
+	// Skipped is true when the object is not reconciled by a legacy release,
+	// so the webhooks don't validate it.
+	Skipped    bool
+	Violations []ManifestViolation
+	Warnings   []string
+}
+
+// ManifestViolation is a violation found in a manifest.
+type ManifestViolation struct {
+	// Field is the path of the field the violation is about, empty when it is
+	// not about a single field.
+	Field string
+	// Line is the line of the field in the file of the manifest.
+	Line    int
+	Message string
+}
+
+// ValidateManifests runs the webhook handlers for the specified manifests like
+// the API server would when they are created: the mutating webhook first and
+// the validating webhook on the mutated object. The handlers read the
+// fixtures and the manifests from a fake client instead of the management
+// cluster.
+func ValidateManifests(ctx context.Context, config ValidateManifestsConfig) ([]ManifestResult, error) {
+	if config.BaseDomain == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.BaseDomain must not be empty", config)
+	}
+	if config.Location == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Location must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	ctrlClient, handlers, err := newOfflineHandlers(config.BaseDomain, config.Location, config.Logger, config.SKUAPI, config.Fixtures, config.Manifests)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var results []ManifestResult
+	for _, m := range config.Manifests {
+		result, err := validateManifest(ctx, config.Logger, ctrlClient, handlers, m)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func validateManifest(ctx context.Context, logger micrologger.Logger, ctrlClient client.Client, handlers []ResourceHandler, m offline.Manifest) (ManifestResult, error) {
+	handler := handlerForKind(handlers, m.GroupVersionKind().Group, m.GroupVersionKind().Version, m.Kind)
+	if handler == nil {
+		return ManifestResult{Manifest: m}, nil
+	}
+
+	// Nothing is persisted, handlers must not have side effects.
+	ctx = generic.WithWarnings(ctx)
+	ctx = generic.WithDryRun(ctx, true)
+
+	skipped, err := runHandler(ctx, logger, ctrlClient, handler, m)
+	if err != nil && !isViolation(err) {
+		return ManifestResult{}, microerror.Mask(err)
+	}
+
+	result := ManifestResult{
+		Manifest: m,
+		Handled:  true,
+		Skipped:  skipped,
+		Warnings: generic.Warnings(ctx),
+	}
+	if err != nil {
+		result.Violations = newManifestViolations(m, err)
+	}
+
+	return result, nil
+}
+
+// runHandler runs the mutating and the validating webhooks of the specified
+// handler for the creation of the object of the specified manifest. It
+// reports whether the object was skipped because it is not reconciled by a
+// legacy release. Violations are returned as a violationError.
+func runHandler(ctx context.Context, logger micrologger.Logger, ctrlClient client.Client, handler ResourceHandler, m offline.Manifest) (bool, error) {
+	decoder, ok := handler.(generic.Decoder)
+	if !ok {
+		return false, nil
+	}
+
+	object, err := decoder.Decode(runtime.RawExtension{Raw: m.Object})
+	if err != nil {
+		return false, &violationError{err: err}
+	}
+
+	reconciled, err := isReconciled(ctx, logger, ctrlClient, object)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+	if !reconciled {
+		return true, nil
+	}
+
+	if mutatingHandler, ok := handler.(mutator.WebhookCreateHandler); ok {
+		patch, err := mutatingHandler.OnCreateMutate(ctx, object)
+		if err != nil {
+			return false, &violationError{err: err}
+		}
+
+		object, _, err = patchObject(decoder, object, patch)
+		if err != nil {
+			return false, microerror.Mask(err)
+		}
+	}
+
+	if validatingHandler, ok := handler.(validator.WebhookCreateHandler); ok {
+		err = validatingHandler.OnCreateValidate(ctx, object)
+		if err != nil {
+			return false, &violationError{err: err}
+		}
+	}
+
+	return false, nil
+}
+
+// newOfflineHandlers returns the webhook handlers of the installation with the
+// specified base domain and location, reading the objects of the specified
+// manifests from a fake client instead of the management cluster, see
+// newOfflineClients.
+func newOfflineHandlers(baseDomain, location string, logger micrologger.Logger, skuAPI vmcapabilities.API, manifests ...[]offline.Manifest) (client.Client, []ResourceHandler, error) {
+	ctrlClient, vmcaps, err := newOfflineClients(logger, skuAPI, manifests...)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	cfg := appconfig.Config{
+		BaseDomain: baseDomain,
+		Location:   location,
+	}
+	handlers, err := getAllHandlers(cfg, logger, ctrlClient, vmcaps)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	return ctrlClient, handlers, nil
+}
+
+// newOfflineClients returns a fake client serving the objects of the
+// specified manifests, and the VM SKUs served by the specified API. Later
+// manifests replace earlier ones with the same identity. VM SKUs can't be
+// looked up when the API is nil.
+func newOfflineClients(logger micrologger.Logger, skuAPI vmcapabilities.API, manifests ...[]offline.Manifest) (client.Client, *vmcapabilities.VMSKU, error) {
+	if skuAPI == nil {
+		skuAPI = offlineSKUAPI{}
+	}
+
+	var objects []offline.Manifest
+	for _, m := range manifests {
+		objects = append(objects, m...)
+	}
+
+	ctrlClient, err := offline.NewClient(objects)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	vmcaps, err := vmcapabilities.New(vmcapabilities.Config{
+		Azure:  skuAPI,
+		Logger: logger,
+	})
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	return ctrlClient, vmcaps, nil
+}
+
+// isReconciled returns whether the specified object is reconciled by a legacy
+// release, the webhooks don't handle it otherwise.
+func isReconciled(ctx context.Context, logger micrologger.Logger, ctrlClient client.Client, object metav1.ObjectMetaAccessor) (bool, error) {
+	ownerClusterGetter := func(objectMeta metav1.ObjectMetaAccessor) (capi.Cluster, bool, error) {
+		ownerCluster, ok, err := generic.TryGetOwnerCluster(ctx, ctrlClient, object)
+		if err != nil {
+			return capi.Cluster{}, false, microerror.Mask(err)
+		}
+
+		return ownerCluster, ok, nil
+	}
+
+	reconciled, err := filter.IsObjectReconciledByLegacyRelease(ctx, logger, ctrlClient, object, ownerClusterGetter)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	return reconciled, nil
+}
+
+// patchObject applies the specified patch to the object and returns the
+// patched object, decoded and as JSON.
+func patchObject(decoder generic.Decoder, object metav1.ObjectMetaAccessor, patch []mutator.PatchOperation) (metav1.ObjectMetaAccessor, []byte, error) {
+	// The API server patches the object as it serializes it, with the fields
+	// which are not omitted when empty, not the manifest as written.
+	objectJSON, err := json.Marshal(object)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	objectJSON, err = applyPatch(objectJSON, patch)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	patched, err := decoder.Decode(runtime.RawExtension{Raw: objectJSON})
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	return patched, objectJSON, nil
+}
+
+// violationError wraps the errors the webhook handlers deny a request with,
+// to tell them apart from errors preventing the validation.
+type violationError struct {
+	err error
+}
+
+func (e *violationError) Error() string {
+	return e.err.Error()
+}
+
+func (e *violationError) Unwrap() error {
+	return e.err
+}
+
+func isViolation(err error) bool {
+	_, ok := err.(*violationError)
+	return ok
+}
+
+// handlerForKind returns the handler for the specified kind, nil when there
+// is none.
+func handlerForKind(handlers []ResourceHandler, group, version, kind string) ResourceHandler {
+	for _, h := range handlers {
+		gvr := h.GroupVersionResource()
+		if gvr.Group == group && gvr.Version == version && h.Resource() == strings.ToLower(kind) {
+			return h
+		}
+	}
+
+	return nil
+}
+
+func applyPatch(objectJSON []byte, patch []mutator.PatchOperation) ([]byte, error) {
+	if len(patch) == 0 {
+		return objectJSON, nil
+	}
+
+	patchJSON, err := json.Marshal(patch)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	decodedPatch, err := jsonpatch.DecodePatch(patchJSON)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	patched, err := decodedPatch.Apply(objectJSON)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return patched, nil
+}
+
+func newManifestViolations(m offline.Manifest, err error) []ManifestViolation {
+	var violations []ManifestViolation
+	for _, cause := range validator.StatusCauses(err) {
+		violations = append(violations, ManifestViolation{
+			Field:   cause.Field,
+			Line:    m.FieldLine(cause.Field),
+			Message: cause.Message,
+		})
+	}
+
+	return violations
+}
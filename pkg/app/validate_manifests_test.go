@@ -0,0 +1,99 @@
+package app
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/azure-admission-controller/pkg/offline"
+)
+
+func Test_ValidateManifests(t *testing.T) {
+	logger, err := micrologger.New(micrologger.Config{IOWriter: ioutil.Discard})
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+
+	fixtures, err := offline.Load(filepath.Join("testdata", "validate", "fixtures"))
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+	manifests, err := offline.Load(filepath.Join("testdata", "validate", "manifests"))
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+
+	results, err := ValidateManifests(context.Background(), ValidateManifestsConfig{
+		BaseDomain: "k8s.test.westeurope.azure.gigantic.io",
+		Location:   "westeurope",
+		Logger:     logger,
+		Fixtures:   fixtures,
+		Manifests:  manifests,
+	})
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+
+	type result struct {
+		name       string
+		handled    bool
+		skipped    bool
+		violations []ManifestViolation
+	}
+	var actual []result
+	for _, r := range results {
+		var violations []ManifestViolation
+		for _, v := range r.Violations {
+			// Only the beginning of the message is checked, the rest is
+			// up to the handler.
+			v.Message = strings.SplitN(v.Message, ":", 2)[0]
+			violations = append(violations, v)
+		}
+		actual = append(actual, result{
+			name:       r.Manifest.Name,
+			handled:    r.Handled,
+			skipped:    r.Skipped,
+			violations: violations,
+		})
+	}
+
+	expected := []result{
+		// The control plane endpoint doesn't match the base domain.
+		{
+			name:    "ab123",
+			handled: true,
+			violations: []ManifestViolation{
+				{
+					Field:   "spec.controlPlaneEndpoint",
+					Line:    13,
+					Message: "invalid control plane endpoint host error",
+				},
+			},
+		},
+		// The control plane endpoint is defaulted by the mutating webhook.
+		{
+			name:    "cd456",
+			handled: true,
+		},
+		// Release 20.0.0 is not in the fixtures.
+		{
+			name:    "ef789",
+			handled: true,
+			skipped: true,
+		},
+		// ConfigMaps are not handled.
+		{
+			name: "values",
+		},
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("results == %#v, want %#v", actual, expected)
+	}
+}
@@ -0,0 +1,179 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2019-07-01/compute"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/giantswarm/azure-admission-controller/internal/vmcapabilities"
+	"github.com/giantswarm/azure-admission-controller/pkg/config"
+	"github.com/giantswarm/azure-admission-controller/pkg/metrics"
+	"github.com/giantswarm/azure-admission-controller/pkg/mutator"
+	"github.com/giantswarm/azure-admission-controller/pkg/validator"
+)
+
+const (
+	webhookDomain = "giantswarm.io"
+)
+
+// WebhookConfigurations returns the MutatingWebhookConfiguration and the
+// ValidatingWebhookConfiguration matching the webhook handlers registered by
+// RegisterWebhookHandlers, for a deployment with the specified name in the
+// specified namespace.
+//
+// The handlers are only introspected, so they are created with a fake client
+// and without access to Azure.
+func WebhookConfigurations(logger micrologger.Logger, name, namespace string) (*admissionregistrationv1.MutatingWebhookConfiguration, *admissionregistrationv1.ValidatingWebhookConfiguration, error) {
+	handlers, err := getOfflineHandlers(logger)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	mutating, validating := newWebhookConfigurations(handlers, name, namespace)
+
+	return mutating, validating, nil
+}
+
+func getOfflineHandlers(logger micrologger.Logger) ([]ResourceHandler, error) {
+	ctrlClient := fake.NewFakeClientWithScheme(runtime.NewScheme())
+
+	vmcaps, err := vmcapabilities.New(vmcapabilities.Config{
+		Azure:  offlineSKUAPI{},
+		Logger: logger,
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	// The handlers only check these are set.
+	cfg := config.Config{
+		BaseDomain: "offline",
+		Location:   "offline",
+	}
+
+	handlers, err := getAllHandlers(cfg, logger, ctrlClient, vmcaps)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return handlers, nil
+}
+
+// newWebhookConfigurations returns the webhook configurations for the
+// specified handlers. Webhooks are registered for the operations the handlers
+// implement, at the paths RegisterWebhookHandlers registers them at.
+func newWebhookConfigurations(handlers []ResourceHandler, name, namespace string) (*admissionregistrationv1.MutatingWebhookConfiguration, *admissionregistrationv1.ValidatingWebhookConfiguration) {
+	objectMeta := metav1.ObjectMeta{
+		Name:      name,
+		Namespace: namespace,
+		Annotations: map[string]string{
+			"cert-manager.io/inject-ca-from": fmt.Sprintf("%s/%s-certificates", namespace, name),
+		},
+	}
+
+	mutating := &admissionregistrationv1.MutatingWebhookConfiguration{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: admissionregistrationv1.SchemeGroupVersion.String(),
+			Kind:       "MutatingWebhookConfiguration",
+		},
+		ObjectMeta: objectMeta,
+	}
+	validating := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: admissionregistrationv1.SchemeGroupVersion.String(),
+			Kind:       "ValidatingWebhookConfiguration",
+		},
+		ObjectMeta: *objectMeta.DeepCopy(),
+	}
+
+	for _, h := range handlers {
+		if _, ok := h.(mutator.WebhookCreateHandler); ok {
+			mutating.Webhooks = append(mutating.Webhooks, newMutatingWebhook(h, metrics.WebhookMutate, admissionregistrationv1.Create, name, namespace))
+		}
+		if _, ok := h.(mutator.WebhookUpdateHandler); ok {
+			mutating.Webhooks = append(mutating.Webhooks, newMutatingWebhook(h, metrics.WebhookMutate, admissionregistrationv1.Update, name, namespace))
+		}
+		if _, ok := h.(validator.WebhookCreateHandler); ok {
+			validating.Webhooks = append(validating.Webhooks, newValidatingWebhook(h, metrics.WebhookValidate, admissionregistrationv1.Create, name, namespace))
+		}
+		if _, ok := h.(validator.WebhookUpdateHandler); ok {
+			validating.Webhooks = append(validating.Webhooks, newValidatingWebhook(h, metrics.WebhookValidate, admissionregistrationv1.Update, name, namespace))
+		}
+	}
+
+	return mutating, validating
+}
+
+func newMutatingWebhook(h ResourceHandler, webhook string, operation admissionregistrationv1.OperationType, name, namespace string) admissionregistrationv1.MutatingWebhook {
+	w := newValidatingWebhook(h, webhook, operation, name, namespace)
+
+	return admissionregistrationv1.MutatingWebhook{
+		Name:                    w.Name,
+		ClientConfig:            w.ClientConfig,
+		Rules:                   w.Rules,
+		FailurePolicy:           w.FailurePolicy,
+		SideEffects:             w.SideEffects,
+		AdmissionReviewVersions: w.AdmissionReviewVersions,
+	}
+}
+
+func newValidatingWebhook(h ResourceHandler, webhook string, operation admissionregistrationv1.OperationType, name, namespace string) admissionregistrationv1.ValidatingWebhook {
+	gvr := h.GroupVersionResource()
+	op := operationLabel(operation)
+	path := webhookPath(webhook, h.Resource(), op)
+	failurePolicy := admissionregistrationv1.Fail
+	// Denials are recorded as events, which is skipped on dry run.
+	sideEffects := admissionregistrationv1.SideEffectClassNoneOnDryRun
+
+	return admissionregistrationv1.ValidatingWebhook{
+		Name: fmt.Sprintf("%s.%s.%s.%s.%s", webhook, gvr.Resource, op, name, webhookDomain),
+		ClientConfig: admissionregistrationv1.WebhookClientConfig{
+			Service: &admissionregistrationv1.ServiceReference{
+				Name:      name,
+				Namespace: namespace,
+				Path:      &path,
+			},
+			// Placeholder, cert-manager injects the CA bundle.
+			CABundle: []byte("\n"),
+		},
+		Rules: []admissionregistrationv1.RuleWithOperations{
+			{
+				Operations: []admissionregistrationv1.OperationType{operation},
+				Rule: admissionregistrationv1.Rule{
+					APIGroups:   []string{gvr.Group},
+					APIVersions: []string{gvr.Version},
+					Resources:   []string{gvr.Resource},
+				},
+			},
+		},
+		FailurePolicy:           &failurePolicy,
+		SideEffects:             &sideEffects,
+		AdmissionReviewVersions: []string{"v1", "v1beta1"},
+	}
+}
+
+func operationLabel(operation admissionregistrationv1.OperationType) string {
+	switch operation {
+	case admissionregistrationv1.Create:
+		return metrics.OperationCreate
+	case admissionregistrationv1.Update:
+		return metrics.OperationUpdate
+	}
+
+	return ""
+}
+
+// offlineSKUAPI is used when the handlers run without access to Azure, i.e.
+// when introspecting them or when validating manifests without a SKU catalog.
+type offlineSKUAPI struct{}
+
+func (offlineSKUAPI) List(_ context.Context, _ string) (map[string]compute.ResourceSku, error) {
+	return nil, microerror.Maskf(unavailableError, "the Azure API is not available offline, VM SKUs can only be looked up in a SKU catalog")
+}
@@ -0,0 +1,131 @@
+package app
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	chartName      = "azure-admission-controller"
+	chartNamespace = "giantswarm"
+)
+
+var (
+	includeNameRegexp      = regexp.MustCompile(`{{ include "resource\.default\.name" \. }}`)
+	includeNamespaceRegexp = regexp.MustCompile(`{{ include "resource\.default\.namespace" \. }}`)
+	otherTemplateRegexp    = regexp.MustCompile(`(?m)^.*{{.*}}.*$`)
+)
+
+// Test_WebhookConfigurations_Chart fails when the webhook configurations in
+// the chart don't match the registered webhook handlers. Regenerate them with
+// the webhook-config command.
+func Test_WebhookConfigurations_Chart(t *testing.T) {
+	logger, err := micrologger.New(micrologger.Config{IOWriter: ioutil.Discard})
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+
+	mutating, validating, err := WebhookConfigurations(logger, chartName, chartNamespace)
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+
+	chartMutating, chartValidating := loadChartWebhookConfigurations(t)
+
+	expectedMutating := mutatingWebhooksByName(mutating.Webhooks)
+	actualMutating := mutatingWebhooksByName(chartMutating.Webhooks)
+	compareWebhookNames(t, "MutatingWebhookConfiguration", keys(expectedMutating), keys(actualMutating))
+	for name, expected := range expectedMutating {
+		if !reflect.DeepEqual(actualMutating[name], expected) {
+			t.Errorf("chart mutating webhook %q == %#v, want %#v", name, actualMutating[name], expected)
+		}
+	}
+
+	expectedValidating := validatingWebhooksByName(validating.Webhooks)
+	actualValidating := validatingWebhooksByName(chartValidating.Webhooks)
+	compareWebhookNames(t, "ValidatingWebhookConfiguration", keys(expectedValidating), keys(actualValidating))
+	for name, expected := range expectedValidating {
+		if !reflect.DeepEqual(actualValidating[name], expected) {
+			t.Errorf("chart validating webhook %q == %#v, want %#v", name, actualValidating[name], expected)
+		}
+	}
+}
+
+// loadChartWebhookConfigurations parses the webhook configurations in the
+// chart, with the name and namespace includes replaced by their values and
+// any other template line dropped.
+func loadChartWebhookConfigurations(t *testing.T) (admissionregistrationv1.MutatingWebhookConfiguration, admissionregistrationv1.ValidatingWebhookConfiguration) {
+	data, err := ioutil.ReadFile(filepath.Join("..", "..", "helm", chartName, "templates", "webhook.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifest := includeNameRegexp.ReplaceAllString(string(data), chartName)
+	manifest = includeNamespaceRegexp.ReplaceAllString(manifest, chartNamespace)
+	manifest = otherTemplateRegexp.ReplaceAllString(manifest, "")
+
+	var mutating admissionregistrationv1.MutatingWebhookConfiguration
+	var validating admissionregistrationv1.ValidatingWebhookConfiguration
+	for _, document := range strings.Split(manifest, "\n---\n") {
+		var typeMeta struct {
+			Kind string `json:"kind"`
+		}
+		err = yaml.Unmarshal([]byte(document), &typeMeta)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		switch typeMeta.Kind {
+		case "MutatingWebhookConfiguration":
+			err = yaml.Unmarshal([]byte(document), &mutating)
+		case "ValidatingWebhookConfiguration":
+			err = yaml.Unmarshal([]byte(document), &validating)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return mutating, validating
+}
+
+func compareWebhookNames(t *testing.T, kind string, expected, actual []string) {
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("chart %s webhooks == %v, want %v", kind, actual, expected)
+	}
+}
+
+func mutatingWebhooksByName(webhooks []admissionregistrationv1.MutatingWebhook) map[string]admissionregistrationv1.MutatingWebhook {
+	m := map[string]admissionregistrationv1.MutatingWebhook{}
+	for _, w := range webhooks {
+		m[w.Name] = w
+	}
+	return m
+}
+
+func validatingWebhooksByName(webhooks []admissionregistrationv1.ValidatingWebhook) map[string]admissionregistrationv1.ValidatingWebhook {
+	m := map[string]admissionregistrationv1.ValidatingWebhook{}
+	for _, w := range webhooks {
+		m[w.Name] = w
+	}
+	return m
+}
+
+func keys(m interface{}) []string {
+	var result []string
+	for _, k := range reflect.ValueOf(m).MapKeys() {
+		result = append(result, k.String())
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,106 @@
+// Package audit writes a structured audit trail of the admission decisions,
+// as JSON lines, separate from the debug output of the logger.
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Entry is a single admission decision in the audit log.
+type Entry struct {
+	Time      time.Time                 `json:"time"`
+	UID       types.UID                 `json:"uid"`
+	UserInfo  authenticationv1.UserInfo `json:"userInfo"`
+	Kind      metav1.GroupVersionKind   `json:"kind"`
+	Namespace string                    `json:"namespace,omitempty"`
+	Name      string                    `json:"name,omitempty"`
+	// Resource is the resource of the webhook handler which handled the
+	// request, e.g. "azuremachinepool".
+	Resource  string `json:"resource"`
+	Webhook   string `json:"webhook"`
+	Operation string `json:"operation"`
+	DryRun    bool   `json:"dryRun,omitempty"`
+	// Decision is the outcome of the request as reported in the metrics, i.e.
+	// one of "allowed", "denied", "error" or "skipped".
+	Decision string              `json:"decision"`
+	Reason   metav1.StatusReason `json:"reason,omitempty"`
+	Message  string              `json:"message,omitempty"`
+	// Patches are the JSON patch operations returned by mutating webhooks.
+	Patches json.RawMessage `json:"patches,omitempty"`
+}
+
+// NewEntry returns the audit log entry for the specified admission request
+// and the response it was answered with.
+func NewEntry(resource, webhook, operation, decision string, request *admissionv1.AdmissionRequest, response *admissionv1.AdmissionResponse) Entry {
+	entry := Entry{
+		Time:      time.Now().UTC(),
+		UID:       request.UID,
+		UserInfo:  request.UserInfo,
+		Kind:      request.Kind,
+		Namespace: request.Namespace,
+		Name:      request.Name,
+		Resource:  resource,
+		Webhook:   webhook,
+		Operation: operation,
+		DryRun:    request.DryRun != nil && *request.DryRun,
+		Decision:  decision,
+	}
+
+	if response.Result != nil {
+		entry.Reason = response.Result.Reason
+		entry.Message = response.Result.Message
+	}
+
+	// Mutating webhooks always return the patch of allowed requests, but an
+	// empty one is serialized as null and not worth recording.
+	if len(response.Patch) > 0 && string(response.Patch) != "null" {
+		entry.Patches = json.RawMessage(response.Patch)
+	}
+
+	return entry
+}
+
+type SinkConfig struct {
+	Writer io.Writer
+}
+
+// Sink writes audit log entries to a writer, one JSON object per line. It is
+// safe for concurrent use.
+type Sink struct {
+	mutex   sync.Mutex
+	encoder *json.Encoder
+}
+
+func NewSink(config SinkConfig) (*Sink, error) {
+	if config.Writer == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Writer must not be empty", config)
+	}
+
+	s := &Sink{
+		encoder: json.NewEncoder(config.Writer),
+	}
+
+	return s, nil
+}
+
+// Record writes the specified entry to the audit log.
+func (s *Sink) Record(entry Entry) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	err := s.encoder.Encode(entry)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
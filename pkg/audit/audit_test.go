@@ -0,0 +1,148 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/giantswarm/microerror"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_NewSink(t *testing.T) {
+	testCases := []struct {
+		name         string
+		config       SinkConfig
+		errorMatcher func(err error) bool
+	}{
+		{
+			name:   "case 0: valid config",
+			config: SinkConfig{Writer: &bytes.Buffer{}},
+		},
+		{
+			name:         "case 1: missing writer",
+			config:       SinkConfig{},
+			errorMatcher: IsInvalidConfig,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewSink(tc.config)
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+		})
+	}
+}
+
+func Test_Record(t *testing.T) {
+	dryRun := true
+
+	testCases := []struct {
+		name            string
+		decision        string
+		request         *admissionv1.AdmissionRequest
+		response        *admissionv1.AdmissionResponse
+		expectedDryRun  bool
+		expectedReason  metav1.StatusReason
+		expectedMessage string
+		expectedPatches string
+	}{
+		{
+			name:     "case 0: allowed request with patches",
+			decision: "allowed",
+			request: &admissionv1.AdmissionRequest{
+				UID:       "abc",
+				Name:      "ab123",
+				Namespace: "org-giantswarm",
+				Operation: admissionv1.Create,
+				UserInfo:  authenticationv1.UserInfo{Username: "jane"},
+			},
+			response: &admissionv1.AdmissionResponse{
+				UID:     "abc",
+				Allowed: true,
+				Patch:   []byte(`[{"op":"add","path":"/spec/location","value":"westeurope"}]`),
+			},
+			expectedPatches: `[{"op":"add","path":"/spec/location","value":"westeurope"}]`,
+		},
+		{
+			name:     "case 1: denied dry run request",
+			decision: "denied",
+			request: &admissionv1.AdmissionRequest{
+				UID:       "def",
+				Name:      "ab123",
+				Namespace: "org-giantswarm",
+				Operation: admissionv1.Update,
+				DryRun:    &dryRun,
+				UserInfo:  authenticationv1.UserInfo{Username: "jane"},
+			},
+			response: &admissionv1.AdmissionResponse{
+				UID:     "def",
+				Allowed: false,
+				Result: &metav1.Status{
+					Reason:  metav1.StatusReasonForbidden,
+					Message: "location can't be changed",
+				},
+				Patch: []byte("null"),
+			},
+			expectedDryRun:  true,
+			expectedReason:  metav1.StatusReasonForbidden,
+			expectedMessage: "location can't be changed",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			sink, err := NewSink(SinkConfig{Writer: &buf})
+			if err != nil {
+				t.Fatal(microerror.JSON(err))
+			}
+
+			entry := NewEntry("azurecluster", "validating", string(tc.request.Operation), tc.decision, tc.request, tc.response)
+			err = sink.Record(entry)
+			if err != nil {
+				t.Fatal(microerror.JSON(err))
+			}
+
+			var recorded Entry
+			err = json.Unmarshal(buf.Bytes(), &recorded)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if recorded.UID != tc.request.UID {
+				t.Fatalf("uid == %q, want %q", recorded.UID, tc.request.UID)
+			}
+			if recorded.UserInfo.Username != tc.request.UserInfo.Username {
+				t.Fatalf("username == %q, want %q", recorded.UserInfo.Username, tc.request.UserInfo.Username)
+			}
+			if recorded.Decision != tc.decision {
+				t.Fatalf("decision == %q, want %q", recorded.Decision, tc.decision)
+			}
+			if recorded.DryRun != tc.expectedDryRun {
+				t.Fatalf("dry run == %t, want %t", recorded.DryRun, tc.expectedDryRun)
+			}
+			if recorded.Reason != tc.expectedReason {
+				t.Fatalf("reason == %q, want %q", recorded.Reason, tc.expectedReason)
+			}
+			if recorded.Message != tc.expectedMessage {
+				t.Fatalf("message == %q, want %q", recorded.Message, tc.expectedMessage)
+			}
+			if string(recorded.Patches) != tc.expectedPatches {
+				t.Fatalf("patches == %s, want %s", recorded.Patches, tc.expectedPatches)
+			}
+		})
+	}
+}
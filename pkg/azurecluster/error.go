@@ -2,6 +2,9 @@ package azurecluster
 
 import (
 	"github.com/giantswarm/microerror"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/azure-admission-controller/internal/errors"
 )
 
 var invalidConfigError = &microerror.Error{
@@ -66,3 +69,11 @@ var unexpectedLocationError = &microerror.Error{
 func IsUnexpectedLocationError(err error) bool {
 	return microerror.Cause(err) == unexpectedLocationError
 }
+
+func init() {
+	errors.RegisterStatusReason(metav1.StatusReasonBadRequest, parsingFailedError)
+	errors.RegisterStatusReason(metav1.StatusReasonForbidden,
+		controlPlaneEndpointWasChangedError,
+		locationWasChangedError,
+	)
+}
@@ -93,7 +93,6 @@ func TestAzureClusterUpdateMutate(t *testing.T) {
 			handler, err := NewWebhookHandler(WebhookHandlerConfig{
 				BaseDomain: "k8s.test.westeurope.azure.gigantic.io",
 				CtrlReader: ctrlClient,
-				CtrlClient: ctrlClient,
 				Decoder:    unittest.NewFakeDecoder(),
 				Location:   "westeurope",
 				Logger:     newLogger,
@@ -8,6 +8,7 @@ import (
 	"github.com/giantswarm/azure-admission-controller/internal/errors"
 	"github.com/giantswarm/azure-admission-controller/pkg/generic"
 	"github.com/giantswarm/azure-admission-controller/pkg/key"
+	"github.com/giantswarm/azure-admission-controller/pkg/validator"
 )
 
 func (h *WebhookHandler) OnCreateValidate(ctx context.Context, object interface{}) error {
@@ -16,28 +17,19 @@ func (h *WebhookHandler) OnCreateValidate(ctx context.Context, object interface{
 		return microerror.Mask(err)
 	}
 
-	err = azureClusterCR.ValidateCreate()
-	err = errors.IgnoreCAPIErrorForField("metadata.Name", err)
-	err = errors.IgnoreCAPIErrorForField("spec.networkSpec.subnets", err)
-	err = errors.IgnoreCAPIErrorForField("spec.SubscriptionID", err)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = generic.ValidateOrganizationLabelContainsExistingOrganization(ctx, h.ctrlClient, azureClusterCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = validateControlPlaneEndpoint(*azureClusterCR, h.baseDomain)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = validateLocation(*azureClusterCR, h.location)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	return nil
+	var errs validator.ErrorList
+	errs.Check(ctx, "upstreamWebhook", "", func() error {
+		err := azureClusterCR.ValidateCreate()
+		err = errors.IgnoreCAPIErrorForField("metadata.Name", err)
+		err = errors.IgnoreCAPIErrorForField("spec.networkSpec.subnets", err)
+		err = errors.IgnoreCAPIErrorForField("spec.SubscriptionID", err)
+		return err
+	})
+	errs.Check(ctx, "validateOrganizationLabelContainsExistingOrganization", "metadata.labels", func() error {
+		return generic.ValidateOrganizationLabelContainsExistingOrganization(ctx, h.ctrlReader, azureClusterCR)
+	})
+	errs.Check(ctx, "validateControlPlaneEndpoint", "spec.controlPlaneEndpoint", func() error { return validateControlPlaneEndpoint(*azureClusterCR, h.baseDomain) })
+	errs.Check(ctx, "validateLocation", "spec.location", func() error { return validateLocation(*azureClusterCR, h.location) })
+
+	return microerror.Mask(errs.ToAggregate())
 }
@@ -5,13 +5,13 @@ import (
 
 	"github.com/giantswarm/microerror"
 	capz "sigs.k8s.io/cluster-api-provider-azure/api/v1alpha3"
-	capiutil "sigs.k8s.io/cluster-api/util"
 
 	"github.com/giantswarm/azure-admission-controller/internal/errors"
 	"github.com/giantswarm/azure-admission-controller/internal/releaseversion"
 	"github.com/giantswarm/azure-admission-controller/internal/semverhelper"
 	"github.com/giantswarm/azure-admission-controller/pkg/generic"
 	"github.com/giantswarm/azure-admission-controller/pkg/key"
+	"github.com/giantswarm/azure-admission-controller/pkg/validator"
 )
 
 func (h *WebhookHandler) OnUpdateValidate(ctx context.Context, oldObject interface{}, object interface{}) error {
@@ -29,27 +29,21 @@ func (h *WebhookHandler) OnUpdateValidate(ctx context.Context, oldObject interfa
 		return microerror.Mask(err)
 	}
 
-	err = azureClusterNewCR.ValidateUpdate(azureClusterOldCR)
-	err = errors.IgnoreCAPIErrorForField("metadata.Name", err)
-	err = errors.IgnoreCAPIErrorForField("spec.networkSpec.subnets", err)
-	// TODO(axbarsan): Remove this once all the older clusters have it.
-	err = errors.IgnoreCAPIErrorForField("spec.networkSpec.apiServerLB", err)
-	err = errors.IgnoreCAPIErrorForField("spec.SubscriptionID", err)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = generic.ValidateOrganizationLabelUnchanged(azureClusterOldCR, azureClusterNewCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = validateControlPlaneEndpointUnchanged(*azureClusterOldCR, *azureClusterNewCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
+	var errs validator.ErrorList
+	errs.Check(ctx, "upstreamWebhook", "", func() error {
+		err := azureClusterNewCR.ValidateUpdate(azureClusterOldCR)
+		err = errors.IgnoreCAPIErrorForField("metadata.Name", err)
+		err = errors.IgnoreCAPIErrorForField("spec.networkSpec.subnets", err)
+		// TODO(axbarsan): Remove this once all the older clusters have it.
+		err = errors.IgnoreCAPIErrorForField("spec.networkSpec.apiServerLB", err)
+		err = errors.IgnoreCAPIErrorForField("spec.SubscriptionID", err)
+		return err
+	})
+	errs.Check(ctx, "validateOrganizationLabelUnchanged", "metadata.labels", func() error { return generic.ValidateOrganizationLabelUnchanged(azureClusterOldCR, azureClusterNewCR) })
+	errs.Check(ctx, "validateControlPlaneEndpointUnchanged", "spec.controlPlaneEndpoint", func() error { return validateControlPlaneEndpointUnchanged(*azureClusterOldCR, *azureClusterNewCR) })
+	errs.Check(ctx, "validateRelease", "metadata.labels", func() error { return h.validateRelease(ctx, azureClusterOldCR, azureClusterNewCR) })
 
-	return h.validateRelease(ctx, azureClusterOldCR, azureClusterNewCR)
+	return microerror.Mask(errs.ToAggregate())
 }
 
 func (h *WebhookHandler) validateRelease(ctx context.Context, azureClusterOldCR *capz.AzureCluster, azureClusterNewCR *capz.AzureCluster) error {
@@ -63,9 +57,11 @@ func (h *WebhookHandler) validateRelease(ctx context.Context, azureClusterOldCR
 	}
 
 	if !newClusterVersion.Equals(oldClusterVersion) {
-		cluster, err := capiutil.GetOwnerCluster(ctx, h.ctrlClient, azureClusterNewCR.ObjectMeta)
+		cluster, ok, err := generic.TryGetOwnerCluster(ctx, h.ctrlReader, azureClusterNewCR)
 		if err != nil {
 			return microerror.Mask(err)
+		} else if !ok {
+			return microerror.Maskf(errors.NotFoundError, "Cluster CR for AzureCluster %#q not found", azureClusterNewCR.Name)
 		}
 
 		clusterCRReleaseVersion, err := semverhelper.GetSemverFromLabels(cluster.Labels)
@@ -78,5 +74,5 @@ func (h *WebhookHandler) validateRelease(ctx context.Context, azureClusterOldCR
 		}
 	}
 
-	return releaseversion.Validate(ctx, h.ctrlClient, oldClusterVersion, newClusterVersion)
+	return releaseversion.Validate(ctx, h.ctrlReader, oldClusterVersion, newClusterVersion)
 }
@@ -5,6 +5,7 @@ import (
 	"github.com/giantswarm/micrologger"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	capz "sigs.k8s.io/cluster-api-provider-azure/api/v1alpha3"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -15,7 +16,6 @@ import (
 type WebhookHandler struct {
 	baseDomain string
 	ctrlReader client.Reader
-	ctrlClient client.Client
 	decoder    runtime.Decoder
 	location   string
 	logger     micrologger.Logger
@@ -24,7 +24,6 @@ type WebhookHandler struct {
 type WebhookHandlerConfig struct {
 	BaseDomain string
 	CtrlReader client.Reader
-	CtrlClient client.Client
 	Decoder    runtime.Decoder
 	Location   string
 	Logger     micrologger.Logger
@@ -37,9 +36,6 @@ func NewWebhookHandler(config WebhookHandlerConfig) (*WebhookHandler, error) {
 	if config.CtrlReader == nil {
 		return nil, microerror.Maskf(invalidConfigError, "%T.CtrlReader must not be empty", config)
 	}
-	if config.CtrlClient == nil {
-		return nil, microerror.Maskf(invalidConfigError, "%T.CtrlClient must not be empty", config)
-	}
 	if config.Decoder == nil {
 		return nil, microerror.Maskf(invalidConfigError, "%T.Decoder must not be empty", config)
 	}
@@ -53,7 +49,6 @@ func NewWebhookHandler(config WebhookHandlerConfig) (*WebhookHandler, error) {
 	v := &WebhookHandler{
 		baseDomain: config.BaseDomain,
 		ctrlReader: config.CtrlReader,
-		ctrlClient: config.CtrlClient,
 		decoder:    config.Decoder,
 		location:   config.Location,
 		logger:     config.Logger,
@@ -70,6 +65,12 @@ func (h *WebhookHandler) Resource() string {
 	return "azurecluster"
 }
 
+// GroupVersionResource returns the resource the webhooks of this handler are
+// registered for.
+func (h *WebhookHandler) GroupVersionResource() schema.GroupVersionResource {
+	return capz.GroupVersion.WithResource("azureclusters")
+}
+
 func (h *WebhookHandler) Decode(rawObject runtime.RawExtension) (metav1.ObjectMetaAccessor, error) {
 	azureClusterCR := &capz.AzureCluster{}
 	if _, _, err := validator.Deserializer.Decode(rawObject.Raw, nil, azureClusterCR); err != nil {
@@ -2,6 +2,9 @@ package azuremachine
 
 import (
 	"github.com/giantswarm/microerror"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/azure-admission-controller/internal/errors"
 )
 
 var invalidConfigError = &microerror.Error{
@@ -75,3 +78,11 @@ var sshFieldIsSetError = &microerror.Error{
 func IsSSHFieldIsSetError(err error) bool {
 	return microerror.Cause(err) == sshFieldIsSetError
 }
+
+func init() {
+	errors.RegisterStatusReason(metav1.StatusReasonBadRequest, parsingFailedError)
+	errors.RegisterStatusReason(metav1.StatusReasonForbidden,
+		failureDomainWasChangedError,
+		locationWasChangedError,
+	)
+}
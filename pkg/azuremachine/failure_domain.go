@@ -1,19 +1,27 @@
 package azuremachine
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
 	"github.com/giantswarm/microerror"
 	capz "sigs.k8s.io/cluster-api-provider-azure/api/v1alpha3"
+
+	"github.com/giantswarm/azure-admission-controller/internal/vmcapabilities"
 )
 
-func validateFailureDomain(azureMachine capz.AzureMachine, supportedAZs []string) error {
+func validateFailureDomain(ctx context.Context, vmcaps *vmcapabilities.VMSKU, azureMachine capz.AzureMachine) error {
 	// No failure domain specified.
 	if azureMachine.Spec.FailureDomain == nil || *azureMachine.Spec.FailureDomain == "" {
 		return nil
 	}
 
+	supportedAZs, err := vmcaps.SupportedAZs(ctx, azureMachine.Spec.Location, azureMachine.Spec.VMSize)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
 	for _, az := range supportedAZs {
 		if *azureMachine.Spec.FailureDomain == az {
 			// Failure Domain is valid.
@@ -21,6 +29,12 @@ func validateFailureDomain(azureMachine capz.AzureMachine, supportedAZs []string
 		}
 	}
 
+	// Zones restricted for the subscription are not supported, tell why.
+	err = vmcaps.CheckZoneAvailable(ctx, azureMachine.Spec.Location, azureMachine.Spec.VMSize, *azureMachine.Spec.FailureDomain)
+	if vmcapabilities.IsSkuRestrictedError(err) {
+		return microerror.Mask(err)
+	}
+
 	supportedAZsMsg := fmt.Sprintf("Location %#q supports Failure Domains %s for VM size %#q but got %#q", azureMachine.Spec.Location, strings.Join(supportedAZs, ", "), azureMachine.Spec.VMSize, *azureMachine.Spec.FailureDomain)
 	if len(supportedAZs) == 0 {
 		supportedAZsMsg = fmt.Sprintf("Location %#q does not support specifying a Failure Domain for VM size %#q", azureMachine.Spec.Location, azureMachine.Spec.VMSize)
@@ -100,7 +100,7 @@ func TestAzureMachineCreateMutate(t *testing.T) {
 			}
 
 			handler, err := NewWebhookHandler(WebhookHandlerConfig{
-				CtrlClient: ctrlClient,
+				CtrlReader: ctrlClient,
 				Decoder:    unittest.NewFakeDecoder(),
 				Location:   "westeurope",
 				Logger:     newLogger,
@@ -2,12 +2,16 @@ package azuremachine
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/giantswarm/microerror"
+	capz "sigs.k8s.io/cluster-api-provider-azure/api/v1alpha3"
 
 	"github.com/giantswarm/azure-admission-controller/internal/errors"
+	"github.com/giantswarm/azure-admission-controller/internal/vmcapabilities"
 	"github.com/giantswarm/azure-admission-controller/pkg/generic"
 	"github.com/giantswarm/azure-admission-controller/pkg/key"
+	"github.com/giantswarm/azure-admission-controller/pkg/validator"
 )
 
 func (h *WebhookHandler) OnCreateValidate(ctx context.Context, object interface{}) error {
@@ -16,35 +20,36 @@ func (h *WebhookHandler) OnCreateValidate(ctx context.Context, object interface{
 		return microerror.Mask(err)
 	}
 
-	err = cr.ValidateCreate()
-	err = errors.IgnoreCAPIErrorForField("sshPublicKey", err)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = generic.ValidateOrganizationLabelContainsExistingOrganization(ctx, h.ctrlClient, cr)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = checkSSHKeyIsEmpty(ctx, cr)
-	if err != nil {
-		return microerror.Mask(err)
+	var errs validator.ErrorList
+	errs.Check(ctx, "upstreamWebhook", "", func() error {
+		err := cr.ValidateCreate()
+		err = errors.IgnoreCAPIErrorForField("sshPublicKey", err)
+		return err
+	})
+	errs.Check(ctx, "validateOrganizationLabelContainsExistingOrganization", "metadata.labels", func() error {
+		return generic.ValidateOrganizationLabelContainsExistingOrganization(ctx, h.ctrlReader, cr)
+	})
+	errs.Check(ctx, "checkSSHKeyIsEmpty", "spec.sshPublicKey", func() error { return checkSSHKeyIsEmpty(ctx, cr) })
+	errs.Check(ctx, "validateLocation", "spec.location", func() error { return validateLocation(*cr, h.location) })
+	if errs.Check(ctx, "checkVMSizeAvailable", "spec.vmSize", func() error { return h.checkVMSizeAvailable(ctx, cr) }) {
+		// The failure domains of an unavailable VM size are not checked.
+		errs.Check(ctx, "validateFailureDomain", "spec.failureDomain", func() error { return validateFailureDomain(ctx, h.vmcaps, *cr) })
 	}
 
-	err = validateLocation(*cr, h.location)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	supportedAZs, err := h.vmcaps.SupportedAZs(ctx, cr.Spec.Location, cr.Spec.VMSize)
-	if err != nil {
-		return microerror.Mask(err)
-	}
+	return microerror.Mask(errs.ToAggregate())
+}
 
-	err = validateFailureDomain(*cr, supportedAZs)
-	if err != nil {
+// checkVMSizeAvailable checks that the VM size is not restricted for the
+// subscription. VM sizes restricted for the subscription are accepted by the
+// API server but fail when the VM is created. Other errors, e.g. when the
+// Azure API is not reachable or the VM size is unknown, are only logged, so
+// that they don't block creating AzureMachines.
+func (h *WebhookHandler) checkVMSizeAvailable(ctx context.Context, cr *capz.AzureMachine) error {
+	err := h.vmcaps.CheckAvailable(ctx, cr.Spec.Location, cr.Spec.VMSize)
+	if vmcapabilities.IsSkuRestrictedError(err) {
 		return microerror.Mask(err)
+	} else if err != nil {
+		h.logger.LogCtx(ctx, "level", "warning", "message", fmt.Sprintf("unable to check whether VM size %#q is available in location %#q", cr.Spec.VMSize, cr.Spec.Location), "stack", microerror.JSON(err))
 	}
 
 	return nil
@@ -14,13 +14,17 @@ import (
 
 	"github.com/giantswarm/azure-admission-controller/internal/vmcapabilities"
 	"github.com/giantswarm/azure-admission-controller/pkg/unittest"
+	"github.com/giantswarm/azure-admission-controller/pkg/validator"
 )
 
 func TestAzureMachineCreateValidate(t *testing.T) {
 	type testCase struct {
 		name         string
 		azureMachine *capz.AzureMachine
+		vmSize       string
 		errorMatcher func(err error) bool
+		// expectedField is the field of the violation, when it is set.
+		expectedField string
 	}
 
 	testCases := []testCase{
@@ -59,6 +63,39 @@ func TestAzureMachineCreateValidate(t *testing.T) {
 			azureMachine: azureMachineObject("", "westeurope", nil, nil),
 			errorMatcher: nil,
 		},
+		{
+			name:          "Case 7 - failure domain restricted for the subscription",
+			azureMachine:  azureMachineObject("", "westeurope", to.StringPtr("2"), nil),
+			vmSize:        "Standard_D8s_v3",
+			errorMatcher:  vmcapabilities.IsSkuRestrictedError,
+			expectedField: "spec.failureDomain",
+		},
+		{
+			name:         "Case 8 - failure domain not restricted for the subscription",
+			azureMachine: azureMachineObject("", "westeurope", to.StringPtr("1"), nil),
+			vmSize:       "Standard_D8s_v3",
+			errorMatcher: nil,
+		},
+		{
+			name:          "Case 9 - VM size not available for the subscription",
+			azureMachine:  azureMachineObject("", "westeurope", nil, nil),
+			vmSize:        "Standard_D16s_v3",
+			errorMatcher:  vmcapabilities.IsSkuRestrictedError,
+			expectedField: "spec.vmSize",
+		},
+		{
+			name:         "Case 10 - unknown VM size",
+			azureMachine: azureMachineObject("", "westeurope", nil, nil),
+			vmSize:       "Standard_D32s_v3",
+			errorMatcher: nil,
+		},
+		{
+			name:          "Case 11 - failure domain for unknown VM size",
+			azureMachine:  azureMachineObject("", "westeurope", to.StringPtr("1"), nil),
+			vmSize:        "Standard_D32s_v3",
+			errorMatcher:  IsLocationWithNoFailureDomainSupportError,
+			expectedField: "spec.failureDomain",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -119,6 +156,41 @@ func TestAzureMachineCreateValidate(t *testing.T) {
 						},
 					},
 				},
+				"Standard_D8s_v3": {
+					Name: to.StringPtr("Standard_D8s_v3"),
+					LocationInfo: &[]compute.ResourceSkuLocationInfo{
+						{
+							Zones: &[]string{
+								"1",
+								"2",
+							},
+						},
+					},
+					Restrictions: &[]compute.ResourceSkuRestrictions{
+						{
+							Type:   compute.Zone,
+							Values: &[]string{"westeurope"},
+							RestrictionInfo: &compute.ResourceSkuRestrictionInfo{
+								Locations: &[]string{"westeurope"},
+								Zones:     &[]string{"2"},
+							},
+							ReasonCode: compute.NotAvailableForSubscription,
+						},
+					},
+				},
+				"Standard_D16s_v3": {
+					Name: to.StringPtr("Standard_D16s_v3"),
+					Restrictions: &[]compute.ResourceSkuRestrictions{
+						{
+							Type:   compute.Location,
+							Values: &[]string{"westeurope"},
+							RestrictionInfo: &compute.ResourceSkuRestrictionInfo{
+								Locations: &[]string{"westeurope"},
+							},
+							ReasonCode: compute.NotAvailableForSubscription,
+						},
+					},
+				},
 			}
 			stubAPI := unittest.NewResourceSkuStubAPI(stubbedSKUs)
 			vmcaps, err := vmcapabilities.New(vmcapabilities.Config{
@@ -130,7 +202,7 @@ func TestAzureMachineCreateValidate(t *testing.T) {
 			}
 
 			handler, err := NewWebhookHandler(WebhookHandlerConfig{
-				CtrlClient: ctrlClient,
+				CtrlReader: ctrlClient,
 				Decoder:    unittest.NewFakeDecoder(),
 				Location:   "westeurope",
 				Logger:     newLogger,
@@ -140,6 +212,10 @@ func TestAzureMachineCreateValidate(t *testing.T) {
 				t.Fatal(err)
 			}
 
+			if tc.vmSize != "" {
+				tc.azureMachine.Spec.VMSize = tc.vmSize
+			}
+
 			// Run validating webhook handler on AzureMachine creation.
 			err = handler.OnCreateValidate(ctx, tc.azureMachine)
 
@@ -154,6 +230,13 @@ func TestAzureMachineCreateValidate(t *testing.T) {
 			case !tc.errorMatcher(err):
 				t.Fatalf("unexpected error: %#v", err)
 			}
+
+			if tc.expectedField != "" {
+				causes := validator.StatusCauses(err)
+				if len(causes) != 1 || causes[0].Field != tc.expectedField {
+					t.Fatalf("expected 1 cause for field %#q, got %#v", tc.expectedField, causes)
+				}
+			}
 		})
 	}
 }
@@ -4,12 +4,14 @@ import (
 	"context"
 
 	"github.com/giantswarm/microerror"
+	capz "sigs.k8s.io/cluster-api-provider-azure/api/v1alpha3"
 
 	"github.com/giantswarm/azure-admission-controller/internal/errors"
 	"github.com/giantswarm/azure-admission-controller/internal/releaseversion"
 	"github.com/giantswarm/azure-admission-controller/internal/semverhelper"
 	"github.com/giantswarm/azure-admission-controller/pkg/generic"
 	"github.com/giantswarm/azure-admission-controller/pkg/key"
+	"github.com/giantswarm/azure-admission-controller/pkg/validator"
 )
 
 func (h *WebhookHandler) OnUpdateValidate(ctx context.Context, oldObject interface{}, object interface{}) error {
@@ -27,32 +29,22 @@ func (h *WebhookHandler) OnUpdateValidate(ctx context.Context, oldObject interfa
 		return microerror.Mask(err)
 	}
 
-	err = azureMachineNewCR.ValidateUpdate(azureMachineOldCR)
-	err = errors.IgnoreCAPIErrorForField("sshPublicKey", err)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = checkSSHKeyIsEmpty(ctx, azureMachineNewCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
+	var errs validator.ErrorList
+	errs.Check(ctx, "upstreamWebhook", "", func() error {
+		err := azureMachineNewCR.ValidateUpdate(azureMachineOldCR)
+		err = errors.IgnoreCAPIErrorForField("sshPublicKey", err)
+		return err
+	})
+	errs.Check(ctx, "checkSSHKeyIsEmpty", "spec.sshPublicKey", func() error { return checkSSHKeyIsEmpty(ctx, azureMachineNewCR) })
+	errs.Check(ctx, "validateOrganizationLabelUnchanged", "metadata.labels", func() error { return generic.ValidateOrganizationLabelUnchanged(azureMachineOldCR, azureMachineNewCR) })
+	errs.Check(ctx, "validateLocationUnchanged", "spec.location", func() error { return validateLocationUnchanged(*azureMachineOldCR, *azureMachineNewCR) })
+	errs.Check(ctx, "validateFailureDomainUnchanged", "spec.failureDomain", func() error { return validateFailureDomainUnchanged(*azureMachineOldCR, *azureMachineNewCR) })
+	errs.Check(ctx, "validateRelease", "metadata.labels", func() error { return h.validateRelease(ctx, azureMachineOldCR, azureMachineNewCR) })
 
-	err = generic.ValidateOrganizationLabelUnchanged(azureMachineOldCR, azureMachineNewCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = validateLocationUnchanged(*azureMachineOldCR, *azureMachineNewCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = validateFailureDomainUnchanged(*azureMachineOldCR, *azureMachineNewCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
+	return microerror.Mask(errs.ToAggregate())
+}
 
+func (h *WebhookHandler) validateRelease(ctx context.Context, azureMachineOldCR *capz.AzureMachine, azureMachineNewCR *capz.AzureMachine) error {
 	oldClusterVersion, err := semverhelper.GetSemverFromLabels(azureMachineOldCR.Labels)
 	if err != nil {
 		return microerror.Maskf(errors.ParsingFailedError, "unable to parse version from AzureConfig (before edit)")
@@ -62,5 +54,5 @@ func (h *WebhookHandler) OnUpdateValidate(ctx context.Context, oldObject interfa
 		return microerror.Maskf(errors.ParsingFailedError, "unable to parse version from AzureConfig (after edit)")
 	}
 
-	return releaseversion.Validate(ctx, h.ctrlClient, oldClusterVersion, newClusterVersion)
+	return releaseversion.Validate(ctx, h.ctrlReader, oldClusterVersion, newClusterVersion)
 }
@@ -89,7 +89,7 @@ func TestAzureMachineUpdateValidate(t *testing.T) {
 			}
 
 			handler, err := NewWebhookHandler(WebhookHandlerConfig{
-				CtrlClient: ctrlClient,
+				CtrlReader: ctrlClient,
 				Decoder:    unittest.NewFakeDecoder(),
 				Location:   "westeurope",
 				Logger:     newLogger,
@@ -7,6 +7,7 @@ import (
 	"github.com/giantswarm/micrologger"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	capz "sigs.k8s.io/cluster-api-provider-azure/api/v1alpha3"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -18,7 +19,7 @@ import (
 )
 
 type WebhookHandler struct {
-	ctrlClient client.Client
+	ctrlReader client.Reader
 	decoder    runtime.Decoder
 	location   string
 	logger     micrologger.Logger
@@ -26,7 +27,7 @@ type WebhookHandler struct {
 }
 
 type WebhookHandlerConfig struct {
-	CtrlClient client.Client
+	CtrlReader client.Reader
 	Decoder    runtime.Decoder
 	Location   string
 	Logger     micrologger.Logger
@@ -34,8 +35,8 @@ type WebhookHandlerConfig struct {
 }
 
 func NewWebhookHandler(config WebhookHandlerConfig) (*WebhookHandler, error) {
-	if config.CtrlClient == nil {
-		return nil, microerror.Maskf(invalidConfigError, "%T.CtrlClient must not be empty", config)
+	if config.CtrlReader == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.CtrlReader must not be empty", config)
 	}
 	if config.Decoder == nil {
 		return nil, microerror.Maskf(invalidConfigError, "%T.Decoder must not be empty", config)
@@ -51,7 +52,7 @@ func NewWebhookHandler(config WebhookHandlerConfig) (*WebhookHandler, error) {
 	}
 
 	v := &WebhookHandler{
-		ctrlClient: config.CtrlClient,
+		ctrlReader: config.CtrlReader,
 		decoder:    config.Decoder,
 		location:   config.Location,
 		logger:     config.Logger,
@@ -69,6 +70,12 @@ func (h *WebhookHandler) Resource() string {
 	return "azuremachine"
 }
 
+// GroupVersionResource returns the resource the webhooks of this handler are
+// registered for.
+func (h *WebhookHandler) GroupVersionResource() schema.GroupVersionResource {
+	return capz.GroupVersion.WithResource("azuremachines")
+}
+
 func (h *WebhookHandler) Decode(rawObject runtime.RawExtension) (metav1.ObjectMetaAccessor, error) {
 	cr := &capz.AzureMachine{}
 	if _, _, err := validator.Deserializer.Decode(rawObject.Raw, nil, cr); err != nil {
@@ -0,0 +1,206 @@
+package azuremachinepool
+
+import (
+	"context"
+	"io/ioutil"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/apiextensions/v3/pkg/apis/release/v1alpha1"
+	securityv1alpha1 "github.com/giantswarm/apiextensions/v3/pkg/apis/security/v1alpha1"
+	"github.com/giantswarm/apiextensions/v3/pkg/label"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	capz "sigs.k8s.io/cluster-api-provider-azure/api/v1alpha3"
+	capzexp "sigs.k8s.io/cluster-api-provider-azure/exp/api/v1alpha3"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha3"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	builder "github.com/giantswarm/azure-admission-controller/internal/test/azuremachinepool"
+	"github.com/giantswarm/azure-admission-controller/pkg/filter"
+	"github.com/giantswarm/azure-admission-controller/pkg/generic"
+	"github.com/giantswarm/azure-admission-controller/pkg/mutator"
+	"github.com/giantswarm/azure-admission-controller/pkg/requestcache"
+	"github.com/giantswarm/azure-admission-controller/pkg/unittest"
+)
+
+// countingReader counts the reads reaching the Kubernetes API or the
+// controller-runtime cache, and delays them by the round trip time to the
+// API server.
+type countingReader struct {
+	delay  time.Duration
+	reader client.Reader
+	reads  int64
+}
+
+func (r *countingReader) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	atomic.AddInt64(&r.reads, 1)
+	time.Sleep(r.delay)
+	return r.reader.Get(ctx, key, obj)
+}
+
+func (r *countingReader) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	atomic.AddInt64(&r.reads, 1)
+	time.Sleep(r.delay)
+	return r.reader.List(ctx, list, opts...)
+}
+
+// BenchmarkAzureMachinePoolCreate does the Kubernetes reads of the mutating
+// and the validating webhook requests for an AzureMachinePool create, like the
+// HTTP handler factories and the webhook handler do, with and without a
+// request cache, and reading from the API server or from the informers of the
+// controller-runtime cache. The reads/op metric is the number of reads
+// reaching the reader.
+func BenchmarkAzureMachinePoolCreate(b *testing.B) {
+	testCases := []struct {
+		name         string
+		delay        time.Duration
+		requestCache bool
+	}{
+		{
+			name:  "case 0: API server without request cache",
+			delay: time.Millisecond,
+		},
+		{
+			name:         "case 1: API server with request cache",
+			delay:        time.Millisecond,
+			requestCache: true,
+		},
+		{
+			name: "case 2: informers without request cache",
+		},
+		{
+			name:         "case 3: informers with request cache",
+			requestCache: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		b.Run(tc.name, func(b *testing.B) {
+			logger, err := micrologger.New(micrologger.Config{IOWriter: ioutil.Discard})
+			if err != nil {
+				b.Fatal(microerror.JSON(err))
+			}
+
+			ctrlClient := unittest.FakeK8sClient().CtrlClient()
+			createBenchmarkObjects(b, ctrlClient)
+
+			reader := &countingReader{delay: tc.delay, reader: ctrlClient}
+			var ctrlReader client.Reader = reader
+			if tc.requestCache {
+				ctrlReader = requestcache.NewReader(reader)
+			}
+
+			// The mutating webhook copies the release and the azure-operator
+			// version from the Cluster and the AzureCluster. The validating
+			// webhook gets the mutated object.
+			mutated := builder.BuildAzureMachinePool()
+			nodePool := builder.BuildAzureMachinePool()
+			delete(nodePool.Labels, label.ReleaseVersion)
+			delete(nodePool.Labels, label.AzureOperatorVersion)
+
+			request := func(ctx context.Context, object *capzexp.AzureMachinePool, lookups ...func(ctx context.Context) error) {
+				if tc.requestCache {
+					ctx = requestcache.NewContext(ctx)
+				}
+
+				ownerClusterGetter := func(metav1.ObjectMetaAccessor) (capi.Cluster, bool, error) {
+					return generic.TryGetOwnerCluster(ctx, ctrlReader, object)
+				}
+				ok, err := filter.IsObjectReconciledByLegacyRelease(ctx, logger, ctrlReader, object, ownerClusterGetter)
+				if err != nil {
+					b.Fatal(microerror.JSON(err))
+				} else if !ok {
+					b.Fatal("object not reconciled by a legacy release")
+				}
+
+				for _, lookup := range lookups {
+					err = lookup(ctx)
+					if err != nil {
+						b.Fatal(microerror.JSON(err))
+					}
+				}
+			}
+
+			ctx := context.Background()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				request(ctx, nodePool,
+					func(ctx context.Context) error {
+						_, err := mutator.EnsureReleaseVersionLabel(ctx, ctrlReader, nodePool)
+						return err
+					},
+					func(ctx context.Context) error {
+						_, err := mutator.CopyAzureOperatorVersionLabelFromAzureClusterCR(ctx, ctrlReader, nodePool)
+						return err
+					},
+				)
+				request(ctx, mutated,
+					func(ctx context.Context) error {
+						return generic.ValidateOrganizationLabelMatchesCluster(ctx, ctrlReader, mutated)
+					},
+				)
+			}
+			b.StopTimer()
+
+			b.ReportMetric(float64(atomic.LoadInt64(&reader.reads))/float64(b.N), "reads/op")
+		})
+	}
+}
+
+func createBenchmarkObjects(b *testing.B, ctrlClient client.Client) {
+	objects := []runtime.Object{
+		&securityv1alpha1.Organization{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "giantswarm",
+			},
+		},
+		&v1alpha1.Release{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "v13.0.0",
+			},
+			Spec: v1alpha1.ReleaseSpec{
+				Components: []v1alpha1.ReleaseSpecComponent{
+					{
+						Name:    "azure-operator",
+						Version: "5.0.0",
+					},
+				},
+			},
+		},
+		&capi.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "ab123",
+				Namespace: "org-giantswarm",
+				Labels: map[string]string{
+					label.AzureOperatorVersion: "5.0.0",
+					label.Cluster:              "ab123",
+					label.Organization:         "giantswarm",
+					label.ReleaseVersion:       "13.0.0",
+				},
+			},
+		},
+		&capz.AzureCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "ab123",
+				Namespace: "org-giantswarm",
+				Labels: map[string]string{
+					label.AzureOperatorVersion: "5.0.0",
+					label.Cluster:              "ab123",
+					label.Organization:         "giantswarm",
+					label.ReleaseVersion:       "13.0.0",
+				},
+			},
+		},
+	}
+
+	for _, o := range objects {
+		err := ctrlClient.Create(context.Background(), o)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
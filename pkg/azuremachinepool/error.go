@@ -2,6 +2,9 @@ package azuremachinepool
 
 import (
 	"github.com/giantswarm/microerror"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/azure-admission-controller/internal/errors"
 )
 
 var invalidConfigError = &microerror.Error{
@@ -138,3 +141,14 @@ var invalidStorageAccountTypeError = &microerror.Error{
 func IsInvalidStorageAccountTypeError(err error) bool {
 	return microerror.Cause(err) == invalidStorageAccountTypeError
 }
+
+func init() {
+	errors.RegisterStatusReason(metav1.StatusReasonBadRequest, parsingFailedError)
+	errors.RegisterStatusReason(metav1.StatusReasonForbidden,
+		locationWasChangedError,
+		acceleratedNetworkingWasChangedError,
+		spotVMOptionsWasChangedError,
+		storageAccountWasChangedError,
+		switchToVmSizeThatDoesNotSupportAcceleratedNetworkingError,
+	)
+}
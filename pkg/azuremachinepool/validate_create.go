@@ -7,6 +7,7 @@ import (
 
 	"github.com/giantswarm/azure-admission-controller/pkg/generic"
 	"github.com/giantswarm/azure-admission-controller/pkg/key"
+	"github.com/giantswarm/azure-admission-controller/pkg/validator"
 )
 
 func (h *WebhookHandler) OnCreateValidate(ctx context.Context, object interface{}) error {
@@ -15,45 +16,18 @@ func (h *WebhookHandler) OnCreateValidate(ctx context.Context, object interface{
 		return microerror.Mask(err)
 	}
 
-	err = azureMPNewCR.ValidateCreate()
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = generic.ValidateOrganizationLabelMatchesCluster(ctx, h.ctrlClient, azureMPNewCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = checkInstanceTypeIsValid(ctx, h.vmcaps, azureMPNewCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = checkAcceleratedNetworking(ctx, h.vmcaps, azureMPNewCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = checkStorageAccountTypeIsValid(ctx, h.vmcaps, azureMPNewCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = checkSSHKeyIsEmpty(ctx, azureMPNewCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = checkDataDisks(ctx, azureMPNewCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = checkLocation(*azureMPNewCR, h.location)
-	if err != nil {
-		return microerror.Mask(err)
+	var errs validator.ErrorList
+	errs.Check(ctx, "upstreamWebhook", "", func() error { return azureMPNewCR.ValidateCreate() })
+	errs.Check(ctx, "validateOrganizationLabelMatchesCluster", "metadata.labels", func() error { return generic.ValidateOrganizationLabelMatchesCluster(ctx, h.ctrlReader, azureMPNewCR) })
+	if errs.Check(ctx, "checkVMSizeAvailable", "spec.template.vmSize", func() error { return checkVMSizeAvailable(ctx, h.vmcaps, nil, azureMPNewCR) }) &&
+		errs.Check(ctx, "checkInstanceTypeIsValid", "spec.template.vmSize", func() error { return checkInstanceTypeIsValid(ctx, h.vmcaps, nil, azureMPNewCR) }) {
+		// The capabilities of an unavailable or invalid VM size are not checked.
+		errs.Check(ctx, "checkAcceleratedNetworking", "spec.template.acceleratedNetworking", func() error { return checkAcceleratedNetworking(ctx, h.vmcaps, azureMPNewCR) })
+		errs.Check(ctx, "checkStorageAccountTypeIsValid", "spec.template.osDisk.managedDisk.storageAccountType", func() error { return checkStorageAccountTypeIsValid(ctx, h.vmcaps, azureMPNewCR) })
 	}
+	errs.Check(ctx, "checkSSHKeyIsEmpty", "spec.template.sshPublicKey", func() error { return checkSSHKeyIsEmpty(ctx, azureMPNewCR) })
+	errs.Check(ctx, "checkDataDisks", "spec.template.dataDisks", func() error { return checkDataDisks(ctx, azureMPNewCR) })
+	errs.Check(ctx, "checkLocation", "spec.location", func() error { return checkLocation(*azureMPNewCR, h.location) })
 
-	return nil
+	return microerror.Mask(errs.ToAggregate())
 }
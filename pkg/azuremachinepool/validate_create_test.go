@@ -20,6 +20,7 @@ import (
 	"github.com/giantswarm/azure-admission-controller/internal/vmcapabilities"
 	"github.com/giantswarm/azure-admission-controller/pkg/generic"
 	"github.com/giantswarm/azure-admission-controller/pkg/unittest"
+	"github.com/giantswarm/azure-admission-controller/pkg/validator"
 )
 
 func TestAzureMachinePoolCreateValidate(t *testing.T) {
@@ -34,6 +35,9 @@ func TestAzureMachinePoolCreateValidate(t *testing.T) {
 		name         string
 		nodePool     *capzexp.AzureMachinePool
 		errorMatcher func(err error) bool
+		// expectedCauses is the number of violations the request is denied
+		// with, unless it is zero.
+		expectedCauses int
 	}
 
 	var testCases []testCase
@@ -65,6 +69,8 @@ func TestAzureMachinePoolCreateValidate(t *testing.T) {
 			name:         fmt.Sprintf("case %d: instance type %s with accelerated networking enabled", len(testCases), instanceType),
 			nodePool:     builder.BuildAzureMachinePool(builder.VMSize(instanceType), builder.AcceleratedNetworking(to.BoolPtr(true))),
 			errorMatcher: vmcapabilities.IsSkuNotFoundError,
+			// The capabilities of the instance type are not checked.
+			expectedCauses: 1,
 		})
 	}
 
@@ -257,7 +263,7 @@ func TestAzureMachinePoolCreateValidate(t *testing.T) {
 			}
 
 			handler, err := NewWebhookHandler(WebhookHandlerConfig{
-				CtrlClient: ctrlClient,
+				CtrlReader: ctrlClient,
 				Decoder:    unittest.NewFakeDecoder(),
 				Location:   "westeurope",
 				Logger:     newLogger,
@@ -281,6 +287,10 @@ func TestAzureMachinePoolCreateValidate(t *testing.T) {
 			case !tc.errorMatcher(err):
 				t.Fatalf("unexpected error: %#v", err)
 			}
+
+			if tc.expectedCauses > 0 && len(validator.StatusCauses(err)) != tc.expectedCauses {
+				t.Fatalf("expected %d causes, got %#v", tc.expectedCauses, validator.StatusCauses(err))
+			}
 		})
 	}
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/giantswarm/azure-admission-controller/internal/vmcapabilities"
 	"github.com/giantswarm/azure-admission-controller/pkg/generic"
 	"github.com/giantswarm/azure-admission-controller/pkg/key"
+	"github.com/giantswarm/azure-admission-controller/pkg/validator"
 )
 
 func (h *WebhookHandler) OnUpdateValidate(ctx context.Context, oldObject interface{}, object interface{}) error {
@@ -34,57 +35,22 @@ func (h *WebhookHandler) OnUpdateValidate(ctx context.Context, oldObject interfa
 		return nil
 	}
 
-	err = azureMPNewCR.ValidateUpdate(azureMPOldCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = generic.ValidateOrganizationLabelUnchanged(azureMPOldCR, azureMPNewCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = checkInstanceTypeIsValid(ctx, h.vmcaps, azureMPNewCR)
-	if err != nil {
-		return microerror.Mask(err)
+	var errs validator.ErrorList
+	errs.Check(ctx, "upstreamWebhook", "", func() error { return azureMPNewCR.ValidateUpdate(azureMPOldCR) })
+	errs.Check(ctx, "validateOrganizationLabelUnchanged", "metadata.labels", func() error { return generic.ValidateOrganizationLabelUnchanged(azureMPOldCR, azureMPNewCR) })
+	if errs.Check(ctx, "checkVMSizeAvailable", "spec.template.vmSize", func() error { return checkVMSizeAvailable(ctx, h.vmcaps, azureMPOldCR, azureMPNewCR) }) &&
+		errs.Check(ctx, "checkInstanceTypeIsValid", "spec.template.vmSize", func() error { return checkInstanceTypeIsValid(ctx, h.vmcaps, azureMPOldCR, azureMPNewCR) }) {
+		// The capabilities of an unavailable or invalid VM size are not checked.
+		errs.Check(ctx, "checkAcceleratedNetworkingUpdateIsValid", "spec.template.acceleratedNetworking", func() error { return h.checkAcceleratedNetworkingUpdateIsValid(ctx, azureMPOldCR, azureMPNewCR) })
+		errs.Check(ctx, "checkInstanceTypeChangeIsValid", "spec.template.vmSize", func() error { return h.checkInstanceTypeChangeIsValid(ctx, azureMPOldCR, azureMPNewCR) })
 	}
+	errs.Check(ctx, "checkSpotVMOptionsUnchanged", "spec.template.spotVMOptions", func() error { return h.checkSpotVMOptionsUnchanged(ctx, azureMPOldCR, azureMPNewCR) })
+	errs.Check(ctx, "checkStorageAccountTypeUnchanged", "spec.template.osDisk.managedDisk.storageAccountType", func() error { return h.checkStorageAccountTypeUnchanged(ctx, azureMPOldCR, azureMPNewCR) })
+	errs.Check(ctx, "checkSSHKeyIsEmpty", "spec.template.sshPublicKey", func() error { return checkSSHKeyIsEmpty(ctx, azureMPNewCR) })
+	errs.Check(ctx, "checkDataDisks", "spec.template.dataDisks", func() error { return checkDataDisks(ctx, azureMPNewCR) })
+	errs.Check(ctx, "checkLocationUnchanged", "spec.location", func() error { return checkLocationUnchanged(*azureMPOldCR, *azureMPNewCR) })
 
-	err = h.checkAcceleratedNetworkingUpdateIsValid(ctx, azureMPOldCR, azureMPNewCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = h.checkInstanceTypeChangeIsValid(ctx, azureMPOldCR, azureMPNewCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = h.checkSpotVMOptionsUnchanged(ctx, azureMPOldCR, azureMPNewCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = h.checkStorageAccountTypeUnchanged(ctx, azureMPOldCR, azureMPNewCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = checkSSHKeyIsEmpty(ctx, azureMPNewCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = checkDataDisks(ctx, azureMPNewCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = checkLocationUnchanged(*azureMPOldCR, *azureMPNewCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	return nil
+	return microerror.Mask(errs.ToAggregate())
 }
 
 func (h *WebhookHandler) checkAcceleratedNetworkingUpdateIsValid(ctx context.Context, azureMPOldCR *capzexp.AzureMachinePool, azureMPNewCR *capzexp.AzureMachinePool) error {
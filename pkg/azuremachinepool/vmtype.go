@@ -7,14 +7,40 @@ import (
 	capzexp "sigs.k8s.io/cluster-api-provider-azure/exp/api/v1alpha3"
 
 	"github.com/giantswarm/azure-admission-controller/internal/vmcapabilities"
+	"github.com/giantswarm/azure-admission-controller/pkg/generic"
 )
 
 const (
 	minMemory = 16
 	minCPUs   = 4
+
+	// lowResourcesFactor defines how close to minMemory and minCPUs a VM size
+	// has to be to get a warning.
+	lowResourcesFactor = 1.25
 )
 
-func checkInstanceTypeIsValid(ctx context.Context, vmcaps *vmcapabilities.VMSKU, azureMachinePool *capzexp.AzureMachinePool) error {
+// checkVMSizeAvailable checks that the VM size is not restricted for the
+// subscription. VM sizes restricted for the subscription are accepted by the
+// API server but fail when the VMSS is created. The old node pool is nil on
+// create. Node pools keeping their VM size are not checked, so that they can
+// still be updated after it became restricted.
+func checkVMSizeAvailable(ctx context.Context, vmcaps *vmcapabilities.VMSKU, azureMPOldCR *capzexp.AzureMachinePool, azureMPNewCR *capzexp.AzureMachinePool) error {
+	if azureMPOldCR != nil && azureMPOldCR.Spec.Template.VMSize == azureMPNewCR.Spec.Template.VMSize {
+		return nil
+	}
+
+	err := vmcaps.CheckAvailable(ctx, azureMPNewCR.Spec.Location, azureMPNewCR.Spec.Template.VMSize)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// checkInstanceTypeIsValid checks that the VM size is big enough. The old node
+// pool is nil on create. It warns about VM sizes close to the minimum only on
+// create and when the VM size is changed, not on every update.
+func checkInstanceTypeIsValid(ctx context.Context, vmcaps *vmcapabilities.VMSKU, azureMPOldCR *capzexp.AzureMachinePool, azureMachinePool *capzexp.AzureMachinePool) error {
 	memory, err := vmcaps.Memory(ctx, azureMachinePool.Spec.Location, azureMachinePool.Spec.Template.VMSize)
 	if err != nil {
 		return microerror.Mask(err)
@@ -33,5 +59,10 @@ func checkInstanceTypeIsValid(ctx context.Context, vmcaps *vmcapabilities.VMSKU,
 		return microerror.Maskf(insufficientCPUError, "Number of cores has to be greater than %d", minCPUs)
 	}
 
+	vmSizeChanged := azureMPOldCR == nil || azureMPOldCR.Spec.Template.VMSize != azureMachinePool.Spec.Template.VMSize
+	if vmSizeChanged && (memory < minMemory*lowResourcesFactor || cpu < minCPUs*lowResourcesFactor) {
+		generic.AddWarning(ctx, "VM size %s with %d GBs of memory and %d cores is close to the minimum of %d GBs and %d cores, consider using a bigger VM size.", azureMachinePool.Spec.Template.VMSize, memory, cpu, minMemory, minCPUs)
+	}
+
 	return nil
 }
@@ -0,0 +1,197 @@
+package azuremachinepool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2019-07-01/compute"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/giantswarm/micrologger"
+	capzexp "sigs.k8s.io/cluster-api-provider-azure/exp/api/v1alpha3"
+
+	builder "github.com/giantswarm/azure-admission-controller/internal/test/azuremachinepool"
+	"github.com/giantswarm/azure-admission-controller/internal/vmcapabilities"
+	"github.com/giantswarm/azure-admission-controller/pkg/generic"
+	"github.com/giantswarm/azure-admission-controller/pkg/unittest"
+)
+
+func TestCheckVMSizeAvailable(t *testing.T) {
+	testCases := []struct {
+		name string
+		// oldVMSize is the VM size of the existing node pool, empty on create.
+		oldVMSize    string
+		vmSize       string
+		errorMatcher func(err error) bool
+	}{
+		{
+			name:   "case 0: create with available VM size",
+			vmSize: "Standard_D4s_v3",
+		},
+		{
+			name:         "case 1: create with VM size not available for the subscription",
+			vmSize:       "Standard_D16s_v3",
+			errorMatcher: vmcapabilities.IsSkuRestrictedError,
+		},
+		{
+			name:   "case 2: create with VM size restricted in another location",
+			vmSize: "Standard_D32s_v3",
+		},
+		{
+			name:      "case 3: update keeping a restricted VM size",
+			oldVMSize: "Standard_D16s_v3",
+			vmSize:    "Standard_D16s_v3",
+		},
+		{
+			name:         "case 4: update changing to a restricted VM size",
+			oldVMSize:    "Standard_D4s_v3",
+			vmSize:       "Standard_D16s_v3",
+			errorMatcher: vmcapabilities.IsSkuRestrictedError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			vmcaps := newTestVMCaps(t)
+
+			var oldAzureMachinePool *capzexp.AzureMachinePool
+			if tc.oldVMSize != "" {
+				oldAzureMachinePool = builder.BuildAzureMachinePool(builder.VMSize(tc.oldVMSize))
+			}
+			err := checkVMSizeAvailable(context.Background(), vmcaps, oldAzureMachinePool, builder.BuildAzureMachinePool(builder.VMSize(tc.vmSize)))
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// fall through
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("expected %#v got %#v", nil, err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("expected %#v got %#v", "error", nil)
+			case !tc.errorMatcher(err):
+				t.Fatalf("unexpected error: %#v", err)
+			}
+		})
+	}
+}
+
+func TestCheckInstanceTypeIsValid(t *testing.T) {
+	testCases := []struct {
+		name string
+		// oldVMSize is the VM size of the existing node pool, empty on create.
+		oldVMSize        string
+		vmSize           string
+		errorMatcher     func(err error) bool
+		expectedWarnings int
+	}{
+		{
+			name:             "case 0: VM size at the minimum",
+			vmSize:           "Standard_D4s_v3",
+			expectedWarnings: 1,
+		},
+		{
+			name:             "case 1: VM size well above the minimum",
+			vmSize:           "Standard_D8s_v3",
+			expectedWarnings: 0,
+		},
+		{
+			name:         "case 2: VM size below the minimum",
+			vmSize:       "Standard_D2s_v3",
+			errorMatcher: IsInsufficientMemoryError,
+		},
+		{
+			name:             "case 3: update keeping a VM size at the minimum",
+			oldVMSize:        "Standard_D4s_v3",
+			vmSize:           "Standard_D4s_v3",
+			expectedWarnings: 0,
+		},
+		{
+			name:             "case 4: update changing to a VM size at the minimum",
+			oldVMSize:        "Standard_D8s_v3",
+			vmSize:           "Standard_D4s_v3",
+			expectedWarnings: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			vmcaps := newTestVMCaps(t)
+
+			var oldAzureMachinePool *capzexp.AzureMachinePool
+			if tc.oldVMSize != "" {
+				oldAzureMachinePool = builder.BuildAzureMachinePool(builder.VMSize(tc.oldVMSize))
+			}
+			ctx := generic.WithWarnings(context.Background())
+			err := checkInstanceTypeIsValid(ctx, vmcaps, oldAzureMachinePool, builder.BuildAzureMachinePool(builder.VMSize(tc.vmSize)))
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// fall through
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("expected %#v got %#v", nil, err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("expected %#v got %#v", "error", nil)
+			case !tc.errorMatcher(err):
+				t.Fatalf("unexpected error: %#v", err)
+			}
+
+			warnings := generic.Warnings(ctx)
+			if len(warnings) != tc.expectedWarnings {
+				t.Fatalf("expected %d warnings, got %v", tc.expectedWarnings, warnings)
+			}
+		})
+	}
+}
+
+func newTestVMCaps(t *testing.T) *vmcapabilities.VMSKU {
+	t.Helper()
+
+	newSKU := func(name, cpus, memory string) compute.ResourceSku {
+		return compute.ResourceSku{
+			Name: to.StringPtr(name),
+			Capabilities: &[]compute.ResourceSkuCapabilities{
+				{
+					Name:  to.StringPtr("vCPUs"),
+					Value: to.StringPtr(cpus),
+				},
+				{
+					Name:  to.StringPtr("MemoryGB"),
+					Value: to.StringPtr(memory),
+				},
+			},
+		}
+	}
+
+	restrictedSKU := func(name, location string) compute.ResourceSku {
+		sku := newSKU(name, "16", "64")
+		sku.Restrictions = &[]compute.ResourceSkuRestrictions{
+			{
+				Type:   compute.Location,
+				Values: &[]string{location},
+				RestrictionInfo: &compute.ResourceSkuRestrictionInfo{
+					Locations: &[]string{location},
+				},
+				ReasonCode: compute.NotAvailableForSubscription,
+			},
+		}
+		return sku
+	}
+
+	logger, err := micrologger.New(micrologger.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stubAPI := unittest.NewResourceSkuStubAPI(map[string]compute.ResourceSku{
+		"Standard_D2s_v3":  newSKU("Standard_D2s_v3", "2", "8"),
+		"Standard_D4s_v3":  newSKU("Standard_D4s_v3", "4", "16"),
+		"Standard_D8s_v3":  newSKU("Standard_D8s_v3", "8", "32"),
+		"Standard_D16s_v3": restrictedSKU("Standard_D16s_v3", "westeurope"),
+		"Standard_D32s_v3": restrictedSKU("Standard_D32s_v3", "germanywestcentral"),
+	})
+	vmcaps, err := vmcapabilities.New(vmcapabilities.Config{
+		Azure:  stubAPI,
+		Logger: logger,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return vmcaps
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/giantswarm/micrologger"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	capzexp "sigs.k8s.io/cluster-api-provider-azure/exp/api/v1alpha3"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -13,7 +14,7 @@ import (
 )
 
 type WebhookHandler struct {
-	ctrlClient client.Client
+	ctrlReader client.Reader
 	decoder    runtime.Decoder
 	location   string
 	logger     micrologger.Logger
@@ -21,7 +22,7 @@ type WebhookHandler struct {
 }
 
 type WebhookHandlerConfig struct {
-	CtrlClient client.Client
+	CtrlReader client.Reader
 	Decoder    runtime.Decoder
 	Location   string
 	Logger     micrologger.Logger
@@ -29,8 +30,8 @@ type WebhookHandlerConfig struct {
 }
 
 func NewWebhookHandler(config WebhookHandlerConfig) (*WebhookHandler, error) {
-	if config.CtrlClient == nil {
-		return nil, microerror.Maskf(invalidConfigError, "%T.CtrlClient must not be empty", config)
+	if config.CtrlReader == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.CtrlReader must not be empty", config)
 	}
 	if config.Decoder == nil {
 		return nil, microerror.Maskf(invalidConfigError, "%T.Decoder must not be empty", config)
@@ -46,7 +47,7 @@ func NewWebhookHandler(config WebhookHandlerConfig) (*WebhookHandler, error) {
 	}
 
 	handler := &WebhookHandler{
-		ctrlClient: config.CtrlClient,
+		ctrlReader: config.CtrlReader,
 		decoder:    config.Decoder,
 		location:   config.Location,
 		logger:     config.Logger,
@@ -64,6 +65,12 @@ func (h *WebhookHandler) Resource() string {
 	return "azuremachinepool"
 }
 
+// GroupVersionResource returns the resource the webhooks of this handler are
+// registered for.
+func (h *WebhookHandler) GroupVersionResource() schema.GroupVersionResource {
+	return capzexp.GroupVersion.WithResource("azuremachinepools")
+}
+
 func (h *WebhookHandler) Decode(rawObject runtime.RawExtension) (metav1.ObjectMetaAccessor, error) {
 	cr := &capzexp.AzureMachinePool{}
 	if _, _, err := h.decoder.Decode(rawObject.Raw, nil, cr); err != nil {
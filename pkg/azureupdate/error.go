@@ -2,6 +2,9 @@ package azureupdate
 
 import (
 	"github.com/giantswarm/microerror"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/azure-admission-controller/internal/errors"
 )
 
 var availabilityZonesChangeError = &microerror.Error{
@@ -30,3 +33,10 @@ var masterCIDRChangeError = &microerror.Error{
 func IsMasterCIDRChange(err error) bool {
 	return microerror.Cause(err) == masterCIDRChangeError
 }
+
+func init() {
+	errors.RegisterStatusReason(metav1.StatusReasonForbidden,
+		availabilityZonesChangeError,
+		masterCIDRChangeError,
+	)
+}
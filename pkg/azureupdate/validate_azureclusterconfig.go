@@ -9,6 +9,7 @@ import (
 	"github.com/giantswarm/micrologger"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/giantswarm/azure-admission-controller/internal/errors"
@@ -17,20 +18,20 @@ import (
 )
 
 type AzureClusterConfigWebhookHandler struct {
-	ctrlClient client.Client
+	ctrlReader client.Reader
 	decoder    runtime.Decoder
 	logger     micrologger.Logger
 }
 
 type AzureClusterConfigWebhookHandlerConfig struct {
-	CtrlClient client.Client
+	CtrlReader client.Reader
 	Decoder    runtime.Decoder
 	Logger     micrologger.Logger
 }
 
 func NewAzureClusterConfigWebhookHandler(config AzureClusterConfigWebhookHandlerConfig) (*AzureClusterConfigWebhookHandler, error) {
-	if config.CtrlClient == nil {
-		return nil, microerror.Maskf(invalidConfigError, "%T.CtrlClient must not be empty", config)
+	if config.CtrlReader == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.CtrlReader must not be empty", config)
 	}
 	if config.Decoder == nil {
 		return nil, microerror.Maskf(invalidConfigError, "%T.Decoder must not be empty", config)
@@ -40,7 +41,7 @@ func NewAzureClusterConfigWebhookHandler(config AzureClusterConfigWebhookHandler
 	}
 
 	webhookHandler := &AzureClusterConfigWebhookHandler{
-		ctrlClient: config.CtrlClient,
+		ctrlReader: config.CtrlReader,
 		decoder:    config.Decoder,
 		logger:     config.Logger,
 	}
@@ -82,7 +83,7 @@ func (h *AzureClusterConfigWebhookHandler) OnUpdateValidate(ctx context.Context,
 	}
 
 	if !oldVersion.Equals(newVersion) {
-		return releaseversion.Validate(ctx, h.ctrlClient, oldVersion, newVersion)
+		return releaseversion.Validate(ctx, h.ctrlReader, oldVersion, newVersion)
 	}
 
 	return nil
@@ -96,6 +97,12 @@ func (h *AzureClusterConfigWebhookHandler) Resource() string {
 	return "azureclusterconfig"
 }
 
+// GroupVersionResource returns the resource the webhooks of this handler are
+// registered for.
+func (h *AzureClusterConfigWebhookHandler) GroupVersionResource() schema.GroupVersionResource {
+	return corev1alpha1.SchemeGroupVersion.WithResource("azureclusterconfigs")
+}
+
 func getSemver(version string) (semver.Version, error) {
 	return semver.ParseTolerant(version)
 }
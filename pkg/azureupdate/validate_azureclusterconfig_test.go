@@ -247,7 +247,7 @@ func TestAzureClusterConfigValidate(t *testing.T) {
 			ctrlClient := fake.NewFakeClientWithScheme(scheme)
 
 			handler, err := NewAzureClusterConfigWebhookHandler(AzureClusterConfigWebhookHandlerConfig{
-				CtrlClient: ctrlClient,
+				CtrlReader: ctrlClient,
 				Decoder:    unittest.NewFakeDecoder(),
 				Logger:     newLogger,
 			})
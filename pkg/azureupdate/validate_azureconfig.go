@@ -10,29 +10,31 @@ import (
 	"github.com/giantswarm/micrologger"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/giantswarm/azure-admission-controller/internal/errors"
 	"github.com/giantswarm/azure-admission-controller/internal/releaseversion"
 	"github.com/giantswarm/azure-admission-controller/internal/semverhelper"
 	"github.com/giantswarm/azure-admission-controller/pkg/key"
+	"github.com/giantswarm/azure-admission-controller/pkg/validator"
 )
 
 type AzureConfigWebhookHandler struct {
-	ctrlClient client.Client
+	ctrlReader client.Reader
 	decoder    runtime.Decoder
 	logger     micrologger.Logger
 }
 
 type AzureConfigWebhookHandlerConfig struct {
-	CtrlClient client.Client
+	CtrlReader client.Reader
 	Decoder    runtime.Decoder
 	Logger     micrologger.Logger
 }
 
 func NewAzureConfigWebhookHandler(config AzureConfigWebhookHandlerConfig) (*AzureConfigWebhookHandler, error) {
-	if config.CtrlClient == nil {
-		return nil, microerror.Maskf(invalidConfigError, "%T.CtrlClient must not be empty", config)
+	if config.CtrlReader == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.CtrlReader must not be empty", config)
 	}
 	if config.Decoder == nil {
 		return nil, microerror.Maskf(invalidConfigError, "%T.Decoder must not be empty", config)
@@ -42,7 +44,7 @@ func NewAzureConfigWebhookHandler(config AzureConfigWebhookHandlerConfig) (*Azur
 	}
 
 	webhookHandler := &AzureConfigWebhookHandler{
-		ctrlClient: config.CtrlClient,
+		ctrlReader: config.CtrlReader,
 		decoder:    config.Decoder,
 		logger:     config.Logger,
 	}
@@ -84,22 +86,16 @@ func (h *AzureConfigWebhookHandler) OnUpdateValidate(ctx context.Context, oldObj
 	}
 
 	if !oldVersion.Equals(newVersion) {
-		return releaseversion.Validate(ctx, h.ctrlClient, oldVersion, newVersion)
+		return releaseversion.Validate(ctx, h.ctrlReader, oldVersion, newVersion)
 	}
 
+	var errs validator.ErrorList
 	// Don't allow change of Master CIDR.
-	err = validateMasterCIDRUnchanged(azureConfigOldCR, azureConfigNewCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
+	errs.Check(ctx, "validateMasterCIDRUnchanged", "spec.azure.virtualNetwork.masterSubnetCIDR", func() error { return validateMasterCIDRUnchanged(azureConfigOldCR, azureConfigNewCR) })
 	// Don't allow change of Availability Zones.
-	err = validateAvailabilityZonesUnchanged(azureConfigOldCR, azureConfigNewCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
+	errs.Check(ctx, "validateAvailabilityZonesUnchanged", "spec.azure.availabilityZones", func() error { return validateAvailabilityZonesUnchanged(azureConfigOldCR, azureConfigNewCR) })
 
-	return nil
+	return microerror.Mask(errs.ToAggregate())
 }
 
 func (h *AzureConfigWebhookHandler) Log(keyVals ...interface{}) {
@@ -110,6 +106,12 @@ func (h *AzureConfigWebhookHandler) Resource() string {
 	return "azureconfig"
 }
 
+// GroupVersionResource returns the resource the webhooks of this handler are
+// registered for.
+func (h *AzureConfigWebhookHandler) GroupVersionResource() schema.GroupVersionResource {
+	return v1alpha1.SchemeGroupVersion.WithResource("azureconfigs")
+}
+
 func validateMasterCIDRUnchanged(old *v1alpha1.AzureConfig, new *v1alpha1.AzureConfig) error {
 	if old.Spec.Azure.VirtualNetwork.MasterSubnetCIDR != "" && old.Spec.Azure.VirtualNetwork.MasterSubnetCIDR != new.Spec.Azure.VirtualNetwork.MasterSubnetCIDR {
 		return microerror.Maskf(masterCIDRChangeError, "Spec.Azure.VirtualNetwork.MasterSubnetCIDR change disallowed")
@@ -123,7 +123,7 @@ func TestMasterCIDR(t *testing.T) {
 			}
 
 			handler, err := NewAzureConfigWebhookHandler(AzureConfigWebhookHandlerConfig{
-				CtrlClient: fakeCtrlClient,
+				CtrlReader: fakeCtrlClient,
 				Decoder:    unittest.NewFakeDecoder(),
 				Logger:     newLogger,
 			})
@@ -381,7 +381,7 @@ func TestAzureConfigValidate(t *testing.T) {
 			}
 
 			handler, err := NewAzureConfigWebhookHandler(AzureConfigWebhookHandlerConfig{
-				CtrlClient: fakeCtrlClient,
+				CtrlReader: fakeCtrlClient,
 				Decoder:    unittest.NewFakeDecoder(),
 				Logger:     newLogger,
 			})
@@ -2,6 +2,9 @@ package cluster
 
 import (
 	"github.com/giantswarm/microerror"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/azure-admission-controller/internal/errors"
 )
 
 var invalidConfigError = &microerror.Error{
@@ -102,3 +105,11 @@ var clusterNetworkWasChangedError = &microerror.Error{
 func IsClusterNetworkWasChangedError(err error) bool {
 	return microerror.Cause(err) == clusterNetworkWasChangedError
 }
+
+func init() {
+	errors.RegisterStatusReason(metav1.StatusReasonBadRequest, parsingFailedError)
+	errors.RegisterStatusReason(metav1.StatusReasonForbidden,
+		controlPlaneEndpointWasChangedError,
+		clusterNetworkWasChangedError,
+	)
+}
@@ -126,7 +126,6 @@ func TestClusterCreateMutate(t *testing.T) {
 
 			handler, err := NewWebhookHandler(WebhookHandlerConfig{
 				BaseDomain: "k8s.test.westeurope.azure.gigantic.io",
-				CtrlClient: ctrlClient,
 				CtrlReader: ctrlClient,
 				Decoder:    unittest.NewFakeDecoder(),
 				Logger:     newLogger,
@@ -92,7 +92,6 @@ func TestClusterUpdateMutate(t *testing.T) {
 
 			handler, err := NewWebhookHandler(WebhookHandlerConfig{
 				BaseDomain: "k8s.test.westeurope.azure.gigantic.io",
-				CtrlClient: ctrlClient,
 				CtrlReader: ctrlClient,
 				Decoder:    unittest.NewFakeDecoder(),
 				Logger:     newLogger,
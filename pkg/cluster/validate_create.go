@@ -8,6 +8,7 @@ import (
 	"github.com/giantswarm/azure-admission-controller/internal/scheduledupgrades"
 	"github.com/giantswarm/azure-admission-controller/pkg/generic"
 	"github.com/giantswarm/azure-admission-controller/pkg/key"
+	"github.com/giantswarm/azure-admission-controller/pkg/validator"
 )
 
 func (h *WebhookHandler) OnCreateValidate(ctx context.Context, object interface{}) error {
@@ -16,35 +17,17 @@ func (h *WebhookHandler) OnCreateValidate(ctx context.Context, object interface{
 		return microerror.Mask(err)
 	}
 
-	err = clusterCR.ValidateCreate()
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = generic.ValidateOrganizationLabelContainsExistingOrganization(ctx, h.ctrlClient, clusterCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = validateClusterNetwork(*clusterCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = validateControlPlaneEndpoint(*clusterCR, h.baseDomain)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = scheduledupgrades.ValidateClusterAnnotationUpgradeTime(nil, clusterCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = scheduledupgrades.ValidateClusterAnnotationUpgradeRelease(ctx, h.ctrlClient, clusterCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	return nil
+	var errs validator.ErrorList
+	errs.Check(ctx, "upstreamWebhook", "", func() error { return clusterCR.ValidateCreate() })
+	errs.Check(ctx, "validateOrganizationLabelContainsExistingOrganization", "metadata.labels", func() error {
+		return generic.ValidateOrganizationLabelContainsExistingOrganization(ctx, h.ctrlReader, clusterCR)
+	})
+	errs.Check(ctx, "validateClusterNetwork", "spec.clusterNetwork", func() error { return validateClusterNetwork(*clusterCR) })
+	errs.Check(ctx, "validateControlPlaneEndpoint", "spec.controlPlaneEndpoint", func() error { return validateControlPlaneEndpoint(*clusterCR, h.baseDomain) })
+	errs.Check(ctx, "validateClusterAnnotationUpgradeTime", "metadata.annotations", func() error { return scheduledupgrades.ValidateClusterAnnotationUpgradeTime(ctx, nil, clusterCR) })
+	errs.Check(ctx, "validateClusterAnnotationUpgradeRelease", "metadata.annotations", func() error {
+		return scheduledupgrades.ValidateClusterAnnotationUpgradeRelease(ctx, h.ctrlReader, clusterCR)
+	})
+
+	return microerror.Mask(errs.ToAggregate())
 }
@@ -16,6 +16,7 @@ import (
 	"github.com/giantswarm/azure-admission-controller/internal/semverhelper"
 	"github.com/giantswarm/azure-admission-controller/pkg/generic"
 	"github.com/giantswarm/azure-admission-controller/pkg/key"
+	"github.com/giantswarm/azure-admission-controller/pkg/validator"
 )
 
 func (h *WebhookHandler) OnUpdateValidate(ctx context.Context, oldObject interface{}, object interface{}) error {
@@ -33,42 +34,21 @@ func (h *WebhookHandler) OnUpdateValidate(ctx context.Context, oldObject interfa
 		return microerror.Mask(err)
 	}
 
-	err = clusterNewCR.ValidateUpdate(clusterOldCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = generic.ValidateOrganizationLabelUnchanged(clusterOldCR, clusterNewCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = validateClusterNetworkUnchanged(*clusterOldCR, *clusterNewCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = validateControlPlaneEndpointUnchanged(*clusterOldCR, *clusterNewCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = conditions.ValidateClusterConditions(clusterOldCR, clusterNewCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = scheduledupgrades.ValidateClusterAnnotationUpgradeTime(clusterOldCR, clusterNewCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = scheduledupgrades.ValidateClusterAnnotationUpgradeRelease(ctx, h.ctrlClient, clusterNewCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	return h.validateRelease(ctx, clusterOldCR, clusterNewCR)
+	var errs validator.ErrorList
+	errs.Check(ctx, "upstreamWebhook", "", func() error { return clusterNewCR.ValidateUpdate(clusterOldCR) })
+	errs.Check(ctx, "validateOrganizationLabelUnchanged", "metadata.labels", func() error { return generic.ValidateOrganizationLabelUnchanged(clusterOldCR, clusterNewCR) })
+	errs.Check(ctx, "validateClusterNetworkUnchanged", "spec.clusterNetwork", func() error { return validateClusterNetworkUnchanged(*clusterOldCR, *clusterNewCR) })
+	errs.Check(ctx, "validateControlPlaneEndpointUnchanged", "spec.controlPlaneEndpoint", func() error { return validateControlPlaneEndpointUnchanged(*clusterOldCR, *clusterNewCR) })
+	errs.Check(ctx, "validateClusterConditions", "status.conditions", func() error { return conditions.ValidateClusterConditions(clusterOldCR, clusterNewCR) })
+	errs.Check(ctx, "validateClusterAnnotationUpgradeTime", "metadata.annotations", func() error {
+		return scheduledupgrades.ValidateClusterAnnotationUpgradeTime(ctx, clusterOldCR, clusterNewCR)
+	})
+	errs.Check(ctx, "validateClusterAnnotationUpgradeRelease", "metadata.annotations", func() error {
+		return scheduledupgrades.ValidateClusterAnnotationUpgradeRelease(ctx, h.ctrlReader, clusterNewCR)
+	})
+	errs.Check(ctx, "validateRelease", "metadata.labels", func() error { return h.validateRelease(ctx, clusterOldCR, clusterNewCR) })
+
+	return microerror.Mask(errs.ToAggregate())
 }
 
 func validateClusterNetworkUnchanged(old capi.Cluster, new capi.Cluster) error {
@@ -127,5 +107,5 @@ func (h *WebhookHandler) validateRelease(ctx context.Context, clusterOldCR *capi
 		}
 	}
 
-	return releaseversion.Validate(ctx, h.ctrlClient, oldClusterVersion, newClusterVersion)
+	return releaseversion.Validate(ctx, h.ctrlReader, oldClusterVersion, newClusterVersion)
 }
@@ -5,6 +5,7 @@ import (
 	"github.com/giantswarm/micrologger"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	capi "sigs.k8s.io/cluster-api/api/v1alpha3"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -15,7 +16,6 @@ type WebhookHandler struct {
 	baseDomain string
 	decoder    runtime.Decoder
 	ctrlReader client.Reader
-	ctrlClient client.Client
 	logger     micrologger.Logger
 }
 
@@ -23,7 +23,6 @@ type WebhookHandlerConfig struct {
 	BaseDomain string
 	Decoder    runtime.Decoder
 	CtrlReader client.Reader
-	CtrlClient client.Client
 	Logger     micrologger.Logger
 }
 
@@ -37,9 +36,6 @@ func NewWebhookHandler(config WebhookHandlerConfig) (*WebhookHandler, error) {
 	if config.CtrlReader == nil {
 		return nil, microerror.Maskf(invalidConfigError, "%T.CtrlReader must not be empty", config)
 	}
-	if config.CtrlClient == nil {
-		return nil, microerror.Maskf(invalidConfigError, "%T.CtrlClient must not be empty", config)
-	}
 	if config.Logger == nil {
 		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
 	}
@@ -48,7 +44,6 @@ func NewWebhookHandler(config WebhookHandlerConfig) (*WebhookHandler, error) {
 		baseDomain: config.BaseDomain,
 		decoder:    config.Decoder,
 		ctrlReader: config.CtrlReader,
-		ctrlClient: config.CtrlClient,
 		logger:     config.Logger,
 	}
 
@@ -63,6 +58,12 @@ func (h *WebhookHandler) Resource() string {
 	return "cluster"
 }
 
+// GroupVersionResource returns the resource the webhooks of this handler are
+// registered for.
+func (h *WebhookHandler) GroupVersionResource() schema.GroupVersionResource {
+	return capi.GroupVersion.WithResource("clusters")
+}
+
 func (h *WebhookHandler) Decode(rawObject runtime.RawExtension) (metav1.ObjectMetaAccessor, error) {
 	cr := &capi.Cluster{}
 	if _, _, err := h.decoder.Decode(rawObject.Raw, nil, cr); err != nil {
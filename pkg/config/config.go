@@ -1,31 +1,229 @@
 package config
 
 import (
+	"errors"
+	"time"
+
 	"gopkg.in/alecthomas/kingpin.v2"
+
+	"github.com/giantswarm/azure-admission-controller/pkg/project"
+)
+
+const (
+	defaultAddress         = ":8080"
+	defaultInternalAddress = "localhost:8081"
+)
+
+const (
+	// CommandServe serves the admission webhooks. It is the default command.
+	CommandServe = "serve"
+	// CommandWebhookConfig prints the webhook configurations for the
+	// registered webhook handlers.
+	CommandWebhookConfig = "webhook-config"
+	// CommandValidate validates manifests offline, without a management
+	// cluster.
+	CommandValidate = "validate"
+	// CommandMutate prints manifests as mutated by the webhooks, without a
+	// management cluster.
+	CommandMutate = "mutate"
+	// CommandReplay replays recorded admission traffic against the webhook
+	// handlers and prints the responses which changed.
+	CommandReplay = "replay"
+	// CommandDumpSKUCatalog prints the VM SKUs of the Azure API as a catalog
+	// for the file backend.
+	CommandDumpSKUCatalog = "dump-sku-catalog"
 )
 
 const (
-	defaultAddress = ":8080"
+	// VMSKUBackendAzure fetches the VM SKUs from the Azure API.
+	VMSKUBackendAzure = "azure"
+	// VMSKUBackendFile reads the VM SKUs from a catalog file.
+	VMSKUBackendFile = "file"
+)
+
+const (
+	// TracingExporterOTLP sends spans to an OTLP/HTTP receiver.
+	TracingExporterOTLP = "otlp"
+	// TracingExporterStdout writes spans to stdout, for local debugging.
+	TracingExporterStdout = "stdout"
 )
 
 type Config struct {
+	// Command is the subcommand to run, e.g. CommandServe.
+	Command string
+
+	AuditLogFile      string
 	BaseDomain        string
 	CertFile          string
 	KeyFile           string
 	Address           string
 	AvailabilityZones string
 	Location          string
+
+	// InternalAddress is the address the internal endpoints, like the VM SKU
+	// cache refresh, are served on over plain HTTP. They are not
+	// authenticated, so it must not be reachable from outside of the pod.
+	// They are not served when it is empty.
+	InternalAddress string
+
+	// EnforcementConfigFile is the path of the enforcement configuration,
+	// see the enforcement package.
+	EnforcementConfigFile string
+	// CandidateEnforcementConfigFile is the path of an enforcement
+	// configuration every handler is also evaluated with in shadow mode, next
+	// to the live one. Only the handlers having an alternative implementation
+	// are evaluated in shadow mode when it is empty.
+	CandidateEnforcementConfigFile string
+	// RecordFile is the file the admission traffic is recorded to, see the
+	// recorder package. Nothing is recorded when it is empty.
+	RecordFile string
+	// Tracing is the configuration of the spans recorded for the admission
+	// requests, see the tracing package.
+	Tracing Tracing
+	// VMSKUCacheTTL is how long the VM SKUs of a location are cached before
+	// they are fetched from the Azure API again. They are never refreshed when
+	// it is zero.
+	VMSKUCacheTTL time.Duration
+	// VMSKUSnapshotDir is the directory the VM SKUs fetched from the Azure
+	// API are saved in, to be served when the Azure API is unreachable. They
+	// are not saved when it is empty.
+	VMSKUSnapshotDir string
+	// VMSKUBackend is where the VM SKUs come from, VMSKUBackendAzure or
+	// VMSKUBackendFile.
+	VMSKUBackend string
+	// SKUCatalog is the JSON or YAML catalog file of the VM SKUs read by
+	// VMSKUBackendFile.
+	SKUCatalog string
+
+	// WebhookConfig is the configuration of CommandWebhookConfig.
+	WebhookConfig WebhookConfig
+	// Validate is the configuration of CommandValidate.
+	Validate Validate
+	// Mutate is the configuration of CommandMutate.
+	Mutate Mutate
+	// Replay is the configuration of CommandReplay.
+	Replay Replay
+	// DumpSKUCatalog is the configuration of CommandDumpSKUCatalog.
+	DumpSKUCatalog DumpSKUCatalog
+}
+
+type Tracing struct {
+	// Exporter is TracingExporterOTLP or TracingExporterStdout. Requests are
+	// not traced when it is empty.
+	Exporter string
+	// OTLPEndpoint is the base URL of the OTLP/HTTP receiver, e.g.
+	// "http://otel-collector:4318".
+	OTLPEndpoint string
+}
+
+type WebhookConfig struct {
+	// Name is the name of the deployment, used for the webhook configurations
+	// and the service the webhooks call.
+	Name      string
+	Namespace string
+}
+
+type Validate struct {
+	// Paths are the YAML files and directories of the manifests to validate.
+	Paths []string
+	// Fixtures is the YAML file or directory of the objects the handlers
+	// look up, e.g. Release, Organization and Cluster objects.
+	Fixtures string
+	// SKUCatalog is the JSON or YAML file of the VM SKUs, e.g. the output of
+	// `az vm list-skus` or of CommandDumpSKUCatalog.
+	SKUCatalog string
+}
+
+type Mutate struct {
+	// Paths are the YAML files and directories of the manifests to mutate.
+	Paths []string
+	// Old is the YAML file or directory of the current versions of the
+	// manifests. Manifests with a current version are mutated like an update.
+	Old string
+	// Fixtures and SKUCatalog are like the ones of Validate.
+	Fixtures   string
+	SKUCatalog string
+	// Diff prints the changes instead of the mutated manifests.
+	Diff bool
+}
+
+type Replay struct {
+	// Paths are the files of the recorded admission traffic.
+	Paths []string
+	// Fixtures and SKUCatalog are like the ones of Validate.
+	Fixtures   string
+	SKUCatalog string
+}
+
+type DumpSKUCatalog struct {
+	// Locations are the Azure regions whose VM SKUs are dumped.
+	Locations []string
+	// Format is the format of the catalog, "json" or "yaml".
+	Format string
+	// Output is the file the catalog is written to, "-" for stdout.
+	Output string
 }
 
 func Parse() (Config, error) {
 	var result Config
 
-	kingpin.Flag("tls-cert-file", "File containing the certificate for HTTPS").Required().StringVar(&result.CertFile)
-	kingpin.Flag("tls-key-file", "File containing the private key for HTTPS").Required().StringVar(&result.KeyFile)
-	kingpin.Flag("address", "The address to listen on").Default(defaultAddress).StringVar(&result.Address)
-	kingpin.Flag("base-domain", "The base domain of the installation").Required().StringVar(&result.BaseDomain)
-	kingpin.Flag("location", "The azure region of the installation").Required().StringVar(&result.Location)
+	serve := kingpin.Command(CommandServe, "Serve the admission webhooks").Default()
+	serve.Flag("tls-cert-file", "File containing the certificate for HTTPS").Required().StringVar(&result.CertFile)
+	serve.Flag("tls-key-file", "File containing the private key for HTTPS").Required().StringVar(&result.KeyFile)
+	serve.Flag("address", "The address to listen on").Default(defaultAddress).StringVar(&result.Address)
+	serve.Flag("internal-address", "The address to serve the unauthenticated internal endpoints, like the VM SKU cache refresh, on over plain HTTP, not reachable from outside of the pod by default, disabled when empty").Default(defaultInternalAddress).StringVar(&result.InternalAddress)
+	serve.Flag("base-domain", "The base domain of the installation").Required().StringVar(&result.BaseDomain)
+	serve.Flag("location", "The azure region of the installation").Required().StringVar(&result.Location)
+	serve.Flag("audit-log-file", "File to write the audit log of admission decisions to, '-' for stderr, as the logs are written to stdout, disabled when empty").Default("").StringVar(&result.AuditLogFile)
+	serve.Flag("record-file", "File to record the admission requests and responses to, with user names and secrets redacted, for the replay command, disabled when empty").Default("").StringVar(&result.RecordFile)
+	serve.Flag("enforcement-config-file", "YAML file enabling and disabling webhook handlers and their checks, reloaded when it changes, everything enabled when empty").Default("").StringVar(&result.EnforcementConfigFile)
+	serve.Flag("candidate-enforcement-config-file", "YAML file in the format of --enforcement-config-file every webhook handler is also evaluated with in shadow mode, which is only logged and counted, reloaded when it changes, disabled when empty").Default("").StringVar(&result.CandidateEnforcementConfigFile)
+	serve.Flag("tracing-exporter", "Where to export the spans of the admission requests to, 'otlp' or 'stdout', disabled when empty").Default("").EnumVar(&result.Tracing.Exporter, "", TracingExporterOTLP, TracingExporterStdout)
+	serve.Flag("tracing-otlp-endpoint", "Base URL of the OTLP/HTTP receiver the spans are sent to with the 'otlp' exporter").Default("http://localhost:4318").Envar("OTEL_EXPORTER_OTLP_ENDPOINT").StringVar(&result.Tracing.OTLPEndpoint)
+	serve.Flag("vm-sku-cache-ttl", "How long the VM SKUs of a location are cached before they are fetched from the Azure API again, never when 0").Default("6h").DurationVar(&result.VMSKUCacheTTL)
+	serve.Flag("vm-sku-snapshot-dir", "Directory the VM SKUs are saved in, and loaded from when the Azure API is unreachable, disabled when empty").Default("").StringVar(&result.VMSKUSnapshotDir)
+	serve.Flag("vm-sku-backend", "Where the VM SKUs come from, 'azure' for the Azure API, which needs the AZURE_* environment variables, or 'file' for the catalog set with --sku-catalog").Default(VMSKUBackendAzure).EnumVar(&result.VMSKUBackend, VMSKUBackendAzure, VMSKUBackendFile)
+	serve.Flag("sku-catalog", "JSON or YAML file with the VM SKUs as printed by 'az vm list-skus' or the dump-sku-catalog command, read by the 'file' backend").Default("").StringVar(&result.SKUCatalog)
+	serve.Validate(func(*kingpin.CmdClause) error {
+		if result.VMSKUBackend == VMSKUBackendFile && result.SKUCatalog == "" {
+			return errors.New("--sku-catalog is required with --vm-sku-backend=file")
+		}
+		return nil
+	})
+
+	webhookConfig := kingpin.Command(CommandWebhookConfig, "Print the MutatingWebhookConfiguration and ValidatingWebhookConfiguration for the registered webhook handlers")
+	webhookConfig.Flag("name", "The name of the deployment").Default(project.Name()).StringVar(&result.WebhookConfig.Name)
+	webhookConfig.Flag("namespace", "The namespace of the deployment").Default("giantswarm").StringVar(&result.WebhookConfig.Namespace)
+
+	validate := kingpin.Command(CommandValidate, "Run the webhook handlers for manifests without a management cluster and print the violations")
+	validate.Flag("base-domain", "The base domain of the installation").Required().StringVar(&result.BaseDomain)
+	validate.Flag("location", "The azure region of the installation").Required().StringVar(&result.Location)
+	validate.Flag("fixtures", "YAML file or directory with the Release, Organization and Cluster objects the handlers look up").Default("").StringVar(&result.Validate.Fixtures)
+	validate.Flag("sku-catalog", "JSON or YAML file with the VM SKUs as printed by 'az vm list-skus' or the dump-sku-catalog command, VM sizes can't be validated when empty").Default("").StringVar(&result.Validate.SKUCatalog)
+	validate.Arg("path", "YAML files or directories of the manifests to validate").Required().StringsVar(&result.Validate.Paths)
+
+	mutate := kingpin.Command(CommandMutate, "Run the mutating webhook handlers for manifests without a management cluster and print the mutated manifests")
+	mutate.Flag("base-domain", "The base domain of the installation").Required().StringVar(&result.BaseDomain)
+	mutate.Flag("location", "The azure region of the installation").Required().StringVar(&result.Location)
+	mutate.Flag("fixtures", "YAML file or directory with the Release, Organization and Cluster objects the handlers look up").Default("").StringVar(&result.Mutate.Fixtures)
+	mutate.Flag("sku-catalog", "JSON or YAML file with the VM SKUs as printed by 'az vm list-skus' or the dump-sku-catalog command, VM sizes can't be looked up when empty").Default("").StringVar(&result.Mutate.SKUCatalog)
+	mutate.Flag("old", "YAML file or directory with the current versions of the manifests, which are mutated like an update").Default("").StringVar(&result.Mutate.Old)
+	mutate.Flag("diff", "Print a diff of the changes instead of the mutated manifests").BoolVar(&result.Mutate.Diff)
+	mutate.Arg("path", "YAML files or directories of the manifests to mutate").Required().StringsVar(&result.Mutate.Paths)
+
+	replay := kingpin.Command(CommandReplay, "Replay recorded admission traffic against the webhook handlers without a management cluster and print the responses which changed")
+	replay.Flag("base-domain", "The base domain of the installation the traffic was recorded in").Required().StringVar(&result.BaseDomain)
+	replay.Flag("location", "The azure region of the installation the traffic was recorded in").Required().StringVar(&result.Location)
+	replay.Flag("fixtures", "YAML file or directory with the Release, Organization and Cluster objects the handlers look up").Default("").StringVar(&result.Replay.Fixtures)
+	replay.Flag("sku-catalog", "JSON or YAML file with the VM SKUs as printed by 'az vm list-skus' or the dump-sku-catalog command, VM sizes can't be looked up when empty").Default("").StringVar(&result.Replay.SKUCatalog)
+	replay.Flag("enforcement-config-file", "YAML file enabling and disabling webhook handlers and their checks, everything enabled when empty").Default("").StringVar(&result.EnforcementConfigFile)
+	replay.Arg("path", "Files of the recorded admission traffic").Required().StringsVar(&result.Replay.Paths)
+
+	dumpSKUCatalog := kingpin.Command(CommandDumpSKUCatalog, "Print the VM SKUs of the Azure API as a catalog for the 'file' backend and the --sku-catalog flags")
+	dumpSKUCatalog.Flag("format", "Format of the catalog, 'json' or 'yaml'").Default("json").EnumVar(&result.DumpSKUCatalog.Format, "json", "yaml")
+	dumpSKUCatalog.Flag("output", "File the catalog is written to, '-' for stdout").Default("-").StringVar(&result.DumpSKUCatalog.Output)
+	dumpSKUCatalog.Arg("location", "Azure regions whose VM SKUs are dumped").Required().StringsVar(&result.DumpSKUCatalog.Locations)
 
-	kingpin.Parse()
+	result.Command = kingpin.Parse()
 	return result, nil
 }
@@ -0,0 +1,233 @@
+// Package enforcement holds the configuration which turns webhook handlers and
+// their named checks on and off without a new release, e.g. to turn off a
+// misbehaving check during an incident.
+//
+// The configuration is a YAML file like:
+//
+//	handlers:
+//	  azuremachinepool:
+//	    checks:
+//	      checkDataDisks:
+//	        enabled: false
+//	  spark:
+//	    enabled: false
+//	checks:
+//	  validateOrganizationLabel*:
+//	    enabled: false
+//
+// Handlers are identified by their resource, e.g. "azuremachinepool", and
+// checks by the name they are run with in the validation chains, see
+// validator.ErrorList.Check. Check names in the configuration may be
+// path.Match patterns. Checks configured for a handler take precedence over
+// the ones configured for all handlers. Everything is enabled by default.
+package enforcement
+
+import (
+	"path"
+	"sort"
+
+	"github.com/giantswarm/microerror"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// ModeEnforce denies requests violating the check. It is the default.
+	ModeEnforce = "enforce"
+	// ModeAudit allows requests violating the check with a warning.
+	ModeAudit = "audit"
+)
+
+// forbiddenBypassGroups are groups every user or every service account
+// belongs to, which must never be allowed to bypass checks.
+var forbiddenBypassGroups = map[string]bool{
+	"system:authenticated":   true,
+	"system:unauthenticated": true,
+	"system:serviceaccounts": true,
+}
+
+type Config struct {
+	// Handlers configures the webhook handlers by resource.
+	Handlers map[string]HandlerConfig `json:"handlers,omitempty"`
+	// Checks configures the named checks of all webhook handlers.
+	Checks map[string]CheckConfig `json:"checks,omitempty"`
+	// Bypass configures who may bypass checks with BypassAnnotation.
+	Bypass BypassConfig `json:"bypass,omitempty"`
+}
+
+type BypassConfig struct {
+	// Groups are the groups whose members may bypass checks. Nobody may when
+	// empty, which is the default.
+	Groups []string `json:"groups,omitempty"`
+}
+
+type HandlerConfig struct {
+	// Enabled turns off both the validating and the mutating webhooks of the
+	// handler when false. Requests are then allowed without changes.
+	Enabled *bool `json:"enabled,omitempty"`
+	// Mode is the mode of the checks of the handler which don't configure
+	// their own, ModeEnforce when empty.
+	Mode string `json:"mode,omitempty"`
+	// Checks configures the named checks of the handler.
+	Checks map[string]CheckConfig `json:"checks,omitempty"`
+}
+
+type CheckConfig struct {
+	// Enabled turns off the check when false.
+	Enabled *bool `json:"enabled,omitempty"`
+	// Mode is either ModeEnforce or ModeAudit.
+	Mode string `json:"mode,omitempty"`
+}
+
+// Parse parses the YAML enforcement configuration. Unknown fields are
+// rejected, so that a typo does not silently leave a check enabled.
+func Parse(data []byte) (*Config, error) {
+	var config Config
+	err := yaml.UnmarshalStrict(data, &config)
+	if err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "unable to parse enforcement configuration: %s", err.Error())
+	}
+
+	for _, group := range config.Bypass.Groups {
+		if group == "" || forbiddenBypassGroups[group] {
+			return nil, microerror.Maskf(invalidConfigError, "group %q must not bypass checks", group)
+		}
+	}
+
+	for resource, handler := range config.Handlers {
+		if !validMode(handler.Mode) {
+			return nil, microerror.Maskf(invalidConfigError, "invalid mode %q for handler %q", handler.Mode, resource)
+		}
+	}
+
+	for _, checks := range config.checkMaps() {
+		for pattern, check := range checks {
+			_, err := path.Match(pattern, "")
+			if err != nil {
+				return nil, microerror.Maskf(invalidConfigError, "invalid check name pattern %q", pattern)
+			}
+			if !validMode(check.Mode) {
+				return nil, microerror.Maskf(invalidConfigError, "invalid mode %q for check %q", check.Mode, pattern)
+			}
+		}
+	}
+
+	return &config, nil
+}
+
+// HandlerEnabled reports whether the webhook handler for the specified
+// resource is enabled. A nil configuration enables everything.
+func (c *Config) HandlerEnabled(resource string) bool {
+	if c == nil {
+		return true
+	}
+
+	handler, ok := c.Handlers[resource]
+	if !ok || handler.Enabled == nil {
+		return true
+	}
+
+	return *handler.Enabled
+}
+
+// CheckEnabled reports whether the named check of the webhook handler for
+// the specified resource is enabled. A nil configuration enables everything.
+func (c *Config) CheckEnabled(resource, name string) bool {
+	if c == nil {
+		return true
+	}
+
+	for _, check := range c.lookupCheck(resource, name) {
+		if check.Enabled != nil {
+			return *check.Enabled
+		}
+	}
+
+	return true
+}
+
+// CheckMode returns the mode of the named check of the webhook handler for
+// the specified resource. A nil configuration enforces everything.
+func (c *Config) CheckMode(resource, name string) string {
+	if c == nil {
+		return ModeEnforce
+	}
+
+	for _, check := range c.lookupCheck(resource, name) {
+		if check.Mode != "" {
+			return check.Mode
+		}
+	}
+
+	if mode := c.Handlers[resource].Mode; mode != "" {
+		return mode
+	}
+
+	return ModeEnforce
+}
+
+// BypassAllowed reports whether the specified user belongs to one of the
+// groups allowed to bypass checks. A nil configuration allows nobody.
+func (c *Config) BypassAllowed(userInfo authenticationv1.UserInfo) bool {
+	if c == nil {
+		return false
+	}
+
+	for _, group := range c.Bypass.Groups {
+		for _, userGroup := range userInfo.Groups {
+			if userGroup == group {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// lookupCheck returns the configurations matching the named check of the
+// webhook handler for the specified resource, in order of precedence.
+func (c *Config) lookupCheck(resource, name string) []CheckConfig {
+	var result []CheckConfig
+	result = append(result, matchCheck(c.Handlers[resource].Checks, name)...)
+	result = append(result, matchCheck(c.Checks, name)...)
+
+	return result
+}
+
+func (c *Config) checkMaps() []map[string]CheckConfig {
+	checkMaps := []map[string]CheckConfig{c.Checks}
+	for _, handler := range c.Handlers {
+		checkMaps = append(checkMaps, handler.Checks)
+	}
+
+	return checkMaps
+}
+
+// matchCheck returns the configurations matching the named check. An exact
+// match comes first, followed by the matching patterns in lexical order.
+func matchCheck(checks map[string]CheckConfig, name string) []CheckConfig {
+	var result []CheckConfig
+	if check, ok := checks[name]; ok {
+		result = append(result, check)
+	}
+
+	patterns := make([]string, 0, len(checks))
+	for pattern := range checks {
+		if pattern != name {
+			patterns = append(patterns, pattern)
+		}
+	}
+	sort.Strings(patterns)
+
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			result = append(result, checks[pattern])
+		}
+	}
+
+	return result
+}
+
+func validMode(mode string) bool {
+	return mode == "" || mode == ModeEnforce || mode == ModeAudit
+}
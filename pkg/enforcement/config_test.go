@@ -0,0 +1,363 @@
+package enforcement
+
+import (
+	"reflect"
+	"testing"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+)
+
+func Test_Parse(t *testing.T) {
+	testCases := []struct {
+		name         string
+		data         string
+		errorMatcher func(err error) bool
+	}{
+		{
+			name: "case 0: empty configuration",
+			data: "",
+		},
+		{
+			name: "case 1: valid configuration",
+			data: `
+handlers:
+  azuremachinepool:
+    checks:
+      checkDataDisks:
+        enabled: false
+  spark:
+    enabled: false
+checks:
+  validateOrganizationLabel*:
+    enabled: false
+`,
+		},
+		{
+			name: "case 2: unknown field",
+			data: `
+handlers:
+  azuremachinepool:
+    enable: false
+`,
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name: "case 3: invalid handler mode",
+			data: `
+handlers:
+  azuremachinepool:
+    mode: warn
+`,
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name: "case 4: invalid check mode",
+			data: `
+checks:
+  checkDataDisks:
+    mode: warn
+`,
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name: "case 5: invalid check name pattern",
+			data: `
+checks:
+  "validate[":
+    enabled: false
+`,
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name: "case 6: valid bypass groups",
+			data: `
+bypass:
+  groups:
+  - giantswarm:sre
+`,
+		},
+		{
+			name: "case 7: bypass group every user belongs to",
+			data: `
+bypass:
+  groups:
+  - system:authenticated
+`,
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name: "case 8: empty bypass group",
+			data: `
+bypass:
+  groups:
+  - ""
+`,
+			errorMatcher: IsInvalidConfig,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse([]byte(tc.data))
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+		})
+	}
+}
+
+func Test_Config_Enabled(t *testing.T) {
+	config, err := Parse([]byte(`
+handlers:
+  azuremachinepool:
+    checks:
+      checkDataDisks:
+        enabled: false
+      validateOrganizationLabelUnchanged:
+        enabled: true
+  spark:
+    enabled: false
+checks:
+  validateOrganizationLabel*:
+    enabled: false
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name                   string
+		config                 *Config
+		resource               string
+		check                  string
+		expectedHandlerEnabled bool
+		expectedCheckEnabled   bool
+	}{
+		{
+			name:                   "case 0: nil configuration enables everything",
+			resource:               "azuremachinepool",
+			check:                  "checkDataDisks",
+			expectedHandlerEnabled: true,
+			expectedCheckEnabled:   true,
+		},
+		{
+			name:                   "case 1: check disabled for the handler",
+			config:                 config,
+			resource:               "azuremachinepool",
+			check:                  "checkDataDisks",
+			expectedHandlerEnabled: true,
+			expectedCheckEnabled:   false,
+		},
+		{
+			name:                   "case 2: check with the same name enabled for other handlers",
+			config:                 config,
+			resource:               "azuremachine",
+			check:                  "checkDataDisks",
+			expectedHandlerEnabled: true,
+			expectedCheckEnabled:   true,
+		},
+		{
+			name:                   "case 3: check disabled by a pattern for all handlers",
+			config:                 config,
+			resource:               "azuremachine",
+			check:                  "validateOrganizationLabelUnchanged",
+			expectedHandlerEnabled: true,
+			expectedCheckEnabled:   false,
+		},
+		{
+			name:                   "case 4: handler configuration takes precedence",
+			config:                 config,
+			resource:               "azuremachinepool",
+			check:                  "validateOrganizationLabelUnchanged",
+			expectedHandlerEnabled: true,
+			expectedCheckEnabled:   true,
+		},
+		{
+			name:                   "case 5: handler disabled",
+			config:                 config,
+			resource:               "spark",
+			check:                  "upstreamWebhook",
+			expectedHandlerEnabled: false,
+			expectedCheckEnabled:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handlerEnabled := tc.config.HandlerEnabled(tc.resource)
+			if handlerEnabled != tc.expectedHandlerEnabled {
+				t.Fatalf("handler enabled == %t, want %t", handlerEnabled, tc.expectedHandlerEnabled)
+			}
+
+			checkEnabled := tc.config.CheckEnabled(tc.resource, tc.check)
+			if checkEnabled != tc.expectedCheckEnabled {
+				t.Fatalf("check enabled == %t, want %t", checkEnabled, tc.expectedCheckEnabled)
+			}
+		})
+	}
+}
+
+func Test_Config_CheckMode(t *testing.T) {
+	config, err := Parse([]byte(`
+handlers:
+  azuremachinepool:
+    checks:
+      checkDataDisks:
+        mode: enforce
+  machinepool:
+    mode: audit
+checks:
+  checkDataDisks:
+    mode: audit
+  checkInstanceTypeIsValid:
+    enabled: true
+  checkAvailabilityZonesUnchanged:
+    mode: enforce
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name         string
+		config       *Config
+		resource     string
+		check        string
+		expectedMode string
+	}{
+		{
+			name:         "case 0: nil configuration enforces everything",
+			resource:     "azuremachinepool",
+			check:        "checkDataDisks",
+			expectedMode: ModeEnforce,
+		},
+		{
+			name:         "case 1: check in audit mode for all handlers",
+			config:       config,
+			resource:     "azuremachine",
+			check:        "checkDataDisks",
+			expectedMode: ModeAudit,
+		},
+		{
+			name:         "case 2: handler check mode takes precedence",
+			config:       config,
+			resource:     "azuremachinepool",
+			check:        "checkDataDisks",
+			expectedMode: ModeEnforce,
+		},
+		{
+			name:         "case 3: handler in audit mode",
+			config:       config,
+			resource:     "machinepool",
+			check:        "checkAvailabilityZones",
+			expectedMode: ModeAudit,
+		},
+		{
+			name:         "case 4: check mode takes precedence over the handler mode",
+			config:       config,
+			resource:     "machinepool",
+			check:        "checkAvailabilityZonesUnchanged",
+			expectedMode: ModeEnforce,
+		},
+		{
+			name:         "case 5: check configured without a mode",
+			config:       config,
+			resource:     "azuremachinepool",
+			check:        "checkInstanceTypeIsValid",
+			expectedMode: ModeEnforce,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mode := tc.config.CheckMode(tc.resource, tc.check)
+			if mode != tc.expectedMode {
+				t.Fatalf("mode == %q, want %q", mode, tc.expectedMode)
+			}
+		})
+	}
+}
+
+func Test_Config_BypassAllowed(t *testing.T) {
+	config := &Config{
+		Bypass: BypassConfig{
+			Groups: []string{"giantswarm:sre"},
+		},
+	}
+
+	testCases := []struct {
+		name            string
+		config          *Config
+		userInfo        authenticationv1.UserInfo
+		expectedAllowed bool
+	}{
+		{
+			name:            "case 0: nil configuration allows nobody",
+			userInfo:        authenticationv1.UserInfo{Username: "jane", Groups: []string{"giantswarm:sre"}},
+			expectedAllowed: false,
+		},
+		{
+			name:            "case 1: no bypass groups allow nobody",
+			config:          &Config{},
+			userInfo:        authenticationv1.UserInfo{Username: "jane", Groups: []string{"giantswarm:sre"}},
+			expectedAllowed: false,
+		},
+		{
+			name:            "case 2: member of a bypass group",
+			config:          config,
+			userInfo:        authenticationv1.UserInfo{Username: "jane", Groups: []string{"system:authenticated", "giantswarm:sre"}},
+			expectedAllowed: true,
+		},
+		{
+			name:            "case 3: customer user",
+			config:          config,
+			userInfo:        authenticationv1.UserInfo{Username: "john", Groups: []string{"system:authenticated", "customer:admins"}},
+			expectedAllowed: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			allowed := tc.config.BypassAllowed(tc.userInfo)
+			if allowed != tc.expectedAllowed {
+				t.Fatalf("allowed == %t, want %t", allowed, tc.expectedAllowed)
+			}
+		})
+	}
+}
+
+func Test_BypassChecks(t *testing.T) {
+	testCases := []struct {
+		name           string
+		annotations    map[string]string
+		expectedChecks []string
+	}{
+		{
+			name: "case 0: no annotation",
+		},
+		{
+			name: "case 1: checks listed with spaces and empty entries",
+			annotations: map[string]string{
+				BypassAnnotation: "checkStorageAccountTypeUnchanged, validateControlPlaneEndpointUnchanged,",
+			},
+			expectedChecks: []string{"checkStorageAccountTypeUnchanged", "validateControlPlaneEndpointUnchanged"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			checks := BypassChecks(tc.annotations)
+			if !reflect.DeepEqual(checks, tc.expectedChecks) {
+				t.Fatalf("checks == %v, want %v", checks, tc.expectedChecks)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package enforcement
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+)
+
+const (
+	// BypassAnnotation lists the comma-separated names of the checks which a
+	// request bypasses, see Config.BypassAllowed.
+	BypassAnnotation = "admission.giantswarm.io/bypass-checks"
+)
+
+type contextKey struct{}
+
+type requestConfig struct {
+	config     *Config
+	resource   string
+	bypass     map[string]bool
+	violations *violations
+}
+
+// Violation is a violation found by a check in ModeAudit or a bypassed check,
+// which did not deny the request.
+type Violation struct {
+	Check string
+	Field string
+	Err   error
+	// Bypassed is true when the check was bypassed with BypassAnnotation.
+	Bypassed bool
+}
+
+type violations struct {
+	mutex      sync.Mutex
+	violations []Violation
+}
+
+// NewContext returns a context carrying the specified configuration for the
+// webhook handler of the specified resource. The configuration is captured
+// once per request, so a reload never applies to half of a validation chain.
+// The context also collects the violations added with AddViolation.
+func NewContext(ctx context.Context, config *Config, resource string) context.Context {
+	return context.WithValue(ctx, contextKey{}, requestConfig{config: config, resource: resource, violations: &violations{}})
+}
+
+// HandlerEnabled reports whether the webhook handler the context was created
+// for is enabled. It is when the context carries no configuration.
+func HandlerEnabled(ctx context.Context) bool {
+	c, ok := ctx.Value(contextKey{}).(requestConfig)
+	if !ok {
+		return true
+	}
+
+	return c.config.HandlerEnabled(c.resource)
+}
+
+// CheckEnabled reports whether the named check is enabled for the webhook
+// handler the context was created for. It is when the context carries no
+// configuration.
+func CheckEnabled(ctx context.Context, name string) bool {
+	c, ok := ctx.Value(contextKey{}).(requestConfig)
+	if !ok {
+		return true
+	}
+
+	return c.config.CheckEnabled(c.resource, name)
+}
+
+// CheckMode returns the mode of the named check for the webhook handler the
+// context was created for. It is ModeEnforce when the context carries no
+// configuration.
+func CheckMode(ctx context.Context, name string) string {
+	c, ok := ctx.Value(contextKey{}).(requestConfig)
+	if !ok {
+		return ModeEnforce
+	}
+
+	return c.config.CheckMode(c.resource, name)
+}
+
+// BypassAllowed reports whether the specified user may bypass checks of the
+// webhook handler the context was created for. Nobody may when the context
+// carries no configuration.
+func BypassAllowed(ctx context.Context, userInfo authenticationv1.UserInfo) bool {
+	c, ok := ctx.Value(contextKey{}).(requestConfig)
+	if !ok {
+		return false
+	}
+
+	return c.config.BypassAllowed(userInfo)
+}
+
+// WithBypass returns a copy of the specified context in which the specified
+// checks are bypassed. Callers must check BypassAllowed first.
+func WithBypass(ctx context.Context, checks []string) context.Context {
+	c, ok := ctx.Value(contextKey{}).(requestConfig)
+	if !ok {
+		return ctx
+	}
+
+	c.bypass = map[string]bool{}
+	for _, check := range checks {
+		c.bypass[check] = true
+	}
+
+	return context.WithValue(ctx, contextKey{}, c)
+}
+
+// CheckBypassed reports whether the named check is bypassed, see WithBypass.
+func CheckBypassed(ctx context.Context, name string) bool {
+	c, ok := ctx.Value(contextKey{}).(requestConfig)
+	if !ok {
+		return false
+	}
+
+	return c.bypass[name]
+}
+
+// BypassChecks returns the names of the checks listed in BypassAnnotation of
+// an object with the specified annotations.
+func BypassChecks(annotations map[string]string) []string {
+	var checks []string
+	for _, check := range strings.Split(annotations[BypassAnnotation], ",") {
+		check = strings.TrimSpace(check)
+		if check != "" {
+			checks = append(checks, check)
+		}
+	}
+
+	return checks
+}
+
+// WithConfig returns a copy of the specified context carrying the specified
+// configuration for the webhook handler of the specified resource instead,
+// with the same bypassed checks, which collects its own violations, e.g. for a
+// candidate handler evaluated with another configuration in shadow mode.
+func WithConfig(ctx context.Context, config *Config, resource string) context.Context {
+	c, _ := ctx.Value(contextKey{}).(requestConfig)
+	c.config = config
+	c.resource = resource
+	c.violations = &violations{}
+
+	return context.WithValue(ctx, contextKey{}, c)
+}
+
+// WithViolations returns a copy of the specified context with the same
+// configuration and bypassed checks, which collects its own violations, e.g.
+// for a shadow evaluation whose violations are not the ones of the request.
+func WithViolations(ctx context.Context) context.Context {
+	c, ok := ctx.Value(contextKey{}).(requestConfig)
+	if !ok {
+		return ctx
+	}
+
+	c.violations = &violations{}
+
+	return context.WithValue(ctx, contextKey{}, c)
+}
+
+// AddViolation adds a violation found by a check in ModeAudit or a bypassed
+// check to the request being handled. When the context does not collect
+// violations, e.g. in unit tests, the violation is discarded.
+func AddViolation(ctx context.Context, violation Violation) {
+	c, ok := ctx.Value(contextKey{}).(requestConfig)
+	if !ok {
+		return
+	}
+
+	c.violations.mutex.Lock()
+	defer c.violations.mutex.Unlock()
+	c.violations.violations = append(c.violations.violations, violation)
+}
+
+// Violations returns the violations added to the specified context so far.
+func Violations(ctx context.Context) []Violation {
+	c, ok := ctx.Value(contextKey{}).(requestConfig)
+	if !ok {
+		return nil
+	}
+
+	c.violations.mutex.Lock()
+	defer c.violations.mutex.Unlock()
+	return append([]Violation(nil), c.violations.violations...)
+}
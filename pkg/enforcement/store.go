@@ -0,0 +1,123 @@
+package enforcement
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+)
+
+const (
+	// reloadInterval is how often the configuration file is checked for
+	// changes. The kubelet takes up to a minute to update a mounted ConfigMap
+	// anyway, and polling works with the symlink swaps it does.
+	reloadInterval = 10 * time.Second
+)
+
+type StoreConfig struct {
+	Logger micrologger.Logger
+	// Path is the path of the YAML configuration file. Everything is enabled
+	// when it is empty.
+	Path string
+}
+
+// Store holds the current enforcement configuration and reloads it when the
+// file changes. It is safe for concurrent use.
+type Store struct {
+	logger micrologger.Logger
+	path   string
+
+	mutex  sync.RWMutex
+	config *Config
+	data   []byte
+}
+
+func NewStore(config StoreConfig) (*Store, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	s := &Store{
+		logger: config.Logger,
+		path:   config.Path,
+	}
+
+	if s.path != "" {
+		_, err := s.Reload()
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	return s, nil
+}
+
+// Config returns the current configuration.
+func (s *Store) Config() *Config {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.config
+}
+
+// Reload reads the configuration file and reports whether it changed. An
+// invalid file is rejected and the current configuration kept.
+func (s *Store) Reload() (bool, error) {
+	if s.path == "" {
+		return false, nil
+	}
+
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	s.mutex.RLock()
+	unchanged := s.config != nil && bytes.Equal(data, s.data)
+	s.mutex.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	config, err := Parse(data)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	s.mutex.Lock()
+	s.config = config
+	s.data = data
+	s.mutex.Unlock()
+
+	return true, nil
+}
+
+// Run reloads the configuration every reloadInterval until the context is
+// done.
+func (s *Store) Run(ctx context.Context) {
+	if s.path == "" {
+		return
+	}
+
+	ticker := time.NewTicker(reloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := s.Reload()
+			if err != nil {
+				s.logger.LogCtx(ctx, "level", "error", "message", fmt.Sprintf("unable to reload enforcement configuration from %s, keeping the current one", s.path), "stack", microerror.JSON(err))
+			} else if changed {
+				s.logger.LogCtx(ctx, "level", "info", "message", fmt.Sprintf("reloaded enforcement configuration from %s", s.path))
+			}
+		}
+	}
+}
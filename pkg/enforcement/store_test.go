@@ -0,0 +1,72 @@
+package enforcement
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+)
+
+func Test_Store_Reload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "enforcement")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "enforcement.yaml")
+	writeFile := func(data string) {
+		err := ioutil.WriteFile(path, []byte(data), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	logger, err := micrologger.New(micrologger.Config{IOWriter: ioutil.Discard})
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+
+	writeFile("handlers:\n  spark:\n    enabled: false\n")
+
+	store, err := NewStore(StoreConfig{Logger: logger, Path: path})
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+	if store.Config().HandlerEnabled("spark") {
+		t.Fatalf("spark handler enabled, want disabled")
+	}
+
+	changed, err := store.Reload()
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+	if changed {
+		t.Fatalf("changed == true for an unchanged file, want false")
+	}
+
+	writeFile("handlers:\n  spark:\n    enabled: true\n")
+	changed, err = store.Reload()
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+	if !changed {
+		t.Fatalf("changed == false for a changed file, want true")
+	}
+	if !store.Config().HandlerEnabled("spark") {
+		t.Fatalf("spark handler disabled, want enabled")
+	}
+
+	// An invalid file keeps the current configuration.
+	writeFile("handlers:\n  spark:\n    enable: false\n")
+	_, err = store.Reload()
+	if !IsInvalidConfig(err) {
+		t.Fatalf("error == %#v, want invalid config error", err)
+	}
+	if !store.Config().HandlerEnabled("spark") {
+		t.Fatalf("spark handler disabled after invalid reload, want enabled")
+	}
+}
@@ -0,0 +1,120 @@
+// Package events records Kubernetes Events about admission decisions, so that
+// denials of requests sent by controllers are visible on the objects they
+// concern and not only in our pod logs.
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/azure-admission-controller/pkg/project"
+)
+
+const (
+	// rateLimitInterval is the minimum time between two events recorded for
+	// the same object. Controllers retry denied requests in a tight loop and
+	// we don't want to flood the API with one event per retry.
+	rateLimitInterval = 5 * time.Minute
+)
+
+type RecorderConfig struct {
+	CtrlClient client.Client
+}
+
+// Recorder creates Warning Events, rate-limited per involved object. It is
+// safe for concurrent use.
+type Recorder struct {
+	ctrlClient client.Client
+
+	mutex    sync.Mutex
+	lastSeen map[string]time.Time
+	now      func() time.Time
+}
+
+func NewRecorder(config RecorderConfig) (*Recorder, error) {
+	if config.CtrlClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.CtrlClient must not be empty", config)
+	}
+
+	r := &Recorder{
+		ctrlClient: config.CtrlClient,
+
+		lastSeen: map[string]time.Time{},
+		now:      time.Now,
+	}
+
+	return r, nil
+}
+
+// Warn creates a Warning Event with the specified reason and message on the
+// specified object. The event is dropped when another one was recorded for
+// the same object less than rateLimitInterval ago.
+func (r *Recorder) Warn(ctx context.Context, object corev1.ObjectReference, reason, message string) error {
+	now := r.now()
+	if !r.allow(object, now) {
+		return nil
+	}
+
+	namespace := object.Namespace
+	if namespace == "" {
+		// Events about cluster-scoped objects go to the default namespace,
+		// like client-go's event recorder does.
+		namespace = metav1.NamespaceDefault
+	}
+
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s.%x", object.Name, now.UnixNano()),
+			Namespace: namespace,
+		},
+		InvolvedObject: object,
+		Reason:         reason,
+		Message:        message,
+		Type:           corev1.EventTypeWarning,
+		Source: corev1.EventSource{
+			Component: project.Name(),
+		},
+		FirstTimestamp: metav1.NewTime(now),
+		LastTimestamp:  metav1.NewTime(now),
+		Count:          1,
+	}
+
+	err := r.ctrlClient.Create(ctx, event)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// allow reports whether an event may be recorded for the specified object
+// and, if so, remembers that one was recorded now.
+func (r *Recorder) allow(object corev1.ObjectReference, now time.Time) bool {
+	key := fmt.Sprintf("%s/%s/%s/%s", object.APIVersion, object.Kind, object.Namespace, object.Name)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if last, ok := r.lastSeen[key]; ok && now.Sub(last) < rateLimitInterval {
+		return false
+	}
+
+	// Forget about objects which are out of the rate limit window, so the map
+	// does not grow with every object ever denied.
+	for k, last := range r.lastSeen {
+		if now.Sub(last) >= rateLimitInterval {
+			delete(r.lastSeen, k)
+		}
+	}
+
+	r.lastSeen[key] = now
+
+	return true
+}
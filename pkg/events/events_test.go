@@ -0,0 +1,100 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/giantswarm/azure-admission-controller/pkg/unittest"
+)
+
+func Test_Warn(t *testing.T) {
+	cluster := corev1.ObjectReference{
+		APIVersion: "cluster.x-k8s.io/v1alpha3",
+		Kind:       "Cluster",
+		Namespace:  "org-giantswarm",
+		Name:       "ab123",
+	}
+	otherCluster := cluster
+	otherCluster.Name = "cd456"
+
+	type warning struct {
+		object corev1.ObjectReference
+		after  time.Duration
+	}
+
+	testCases := []struct {
+		name           string
+		warnings       []warning
+		expectedEvents int
+	}{
+		{
+			name: "case 0: single warning",
+			warnings: []warning{
+				{object: cluster},
+			},
+			expectedEvents: 1,
+		},
+		{
+			name: "case 1: repeated warnings for the same object are rate-limited",
+			warnings: []warning{
+				{object: cluster},
+				{object: cluster, after: time.Second},
+				{object: cluster, after: time.Minute},
+			},
+			expectedEvents: 1,
+		},
+		{
+			name: "case 2: warnings for different objects are not rate-limited together",
+			warnings: []warning{
+				{object: cluster},
+				{object: otherCluster, after: time.Second},
+			},
+			expectedEvents: 2,
+		},
+		{
+			name: "case 3: warnings after the rate limit interval are recorded",
+			warnings: []warning{
+				{object: cluster},
+				{object: cluster, after: rateLimitInterval},
+			},
+			expectedEvents: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			ctrlClient := unittest.FakeK8sClient().CtrlClient()
+
+			recorder, err := NewRecorder(RecorderConfig{CtrlClient: ctrlClient})
+			if err != nil {
+				t.Fatal(microerror.JSON(err))
+			}
+
+			now := time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC)
+			for _, w := range tc.warnings {
+				now = now.Add(w.after)
+				recorder.now = func() time.Time { return now }
+
+				err = recorder.Warn(ctx, w.object, "Forbidden", "update denied")
+				if err != nil {
+					t.Fatal(microerror.JSON(err))
+				}
+			}
+
+			var eventList corev1.EventList
+			err = ctrlClient.List(ctx, &eventList)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(eventList.Items) != tc.expectedEvents {
+				t.Fatalf("expected %d events, got %d", tc.expectedEvents, len(eventList.Items))
+			}
+		})
+	}
+}
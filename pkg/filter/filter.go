@@ -12,11 +12,15 @@ import (
 
 	"github.com/giantswarm/azure-admission-controller/pkg/generic"
 	"github.com/giantswarm/azure-admission-controller/pkg/release"
+	"github.com/giantswarm/azure-admission-controller/pkg/tracing"
 )
 
 // IsObjectReconciledByLegacyRelease checks if the object is reconciled by an operator which is the
 // part of a legacy Giant Swarm release (a release that does not have Cluster API controllers).
 func IsObjectReconciledByLegacyRelease(ctx context.Context, logger micrologger.Logger, ctrlReader client.Reader, object metav1.ObjectMetaAccessor, ownerClusterGetter generic.OwnerClusterGetter) (bool, error) {
+	ctx, span := tracing.Start(ctx, "filter.IsObjectReconciledByLegacyRelease")
+	defer span.End()
+
 	objectName := fmt.Sprintf("%s/%s", object.GetObjectMeta().GetNamespace(), object.GetObjectMeta().GetName())
 	var releaseVersionLabel string
 	if object.GetObjectMeta().GetAnnotations() != nil && object.GetObjectMeta().GetAnnotations()[label.ReleaseVersion] != "" {
@@ -29,6 +33,7 @@ func IsObjectReconciledByLegacyRelease(ctx context.Context, logger micrologger.L
 		logger.Debugf(ctx, "Object %s not reconciled by a legacy release (Release CR %s not found).", objectName, releaseVersionLabel)
 		return false, nil
 	} else if err != nil {
+		span.RecordError(err)
 		return false, microerror.Mask(err)
 	}
 
@@ -40,6 +45,7 @@ func IsObjectReconciledByLegacyRelease(ctx context.Context, logger micrologger.L
 
 	// Now when we have release CR, let's check if this is a legacy release.
 	isLegacy := release.IsLegacy(releaseCR)
+	span.SetAttributes(tracing.String("release", releaseCR.Name), tracing.Bool("legacy", isLegacy))
 	if isLegacy {
 		logger.Debugf(ctx, "Object %s is reconciled by a legacy release %s.", objectName, releaseVersionLabel)
 	} else {
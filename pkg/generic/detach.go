@@ -0,0 +1,27 @@
+package generic
+
+import (
+	"context"
+	"time"
+)
+
+// detachedContext carries the values of its parent, like the warnings or the
+// span of a request, but is neither cancelled nor has a deadline.
+type detachedContext struct {
+	parent context.Context
+}
+
+// Detach returns a context with the values of the specified context which is
+// not cancelled with it, e.g. for work started by a request which has to
+// outlive it. Callers must bound the work with a timeout of their own.
+func Detach(ctx context.Context) context.Context {
+	return detachedContext{parent: ctx}
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+
+func (detachedContext) Done() <-chan struct{} { return nil }
+
+func (detachedContext) Err() error { return nil }
+
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
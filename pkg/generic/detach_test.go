@@ -0,0 +1,23 @@
+package generic
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDetach(t *testing.T) {
+	ctx, cancel := context.WithTimeout(WithDryRun(context.Background(), true), time.Minute)
+	detached := Detach(ctx)
+	cancel()
+
+	if detached.Err() != nil {
+		t.Fatalf("expected detached context not to be cancelled, got %#v", detached.Err())
+	}
+	if _, ok := detached.Deadline(); ok {
+		t.Fatal("expected detached context to have no deadline")
+	}
+	if !IsDryRun(detached) {
+		t.Fatal("expected detached context to carry the values of its parent")
+	}
+}
@@ -0,0 +1,24 @@
+package generic
+
+import (
+	"context"
+)
+
+type dryRunKey struct{}
+
+// WithDryRun returns a copy of the specified context which tells whether the
+// admission request being handled is a dry run. The HTTP handler factories
+// call it for every admission request.
+func WithDryRun(ctx context.Context, dryRun bool) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, dryRun)
+}
+
+// IsDryRun reports whether the admission request being handled is a dry run.
+// Dry run requests are validated and mutated like any other request, but all
+// the webhooks are registered with sideEffects: NoneOnDryRun, so any code
+// path with side effects, e.g. creating or updating objects or recording
+// events, must be skipped when it returns true.
+func IsDryRun(ctx context.Context) bool {
+	dryRun, ok := ctx.Value(dryRunKey{}).(bool)
+	return ok && dryRun
+}
@@ -0,0 +1,38 @@
+package generic
+
+import (
+	"context"
+	"testing"
+)
+
+func TestIsDryRun(t *testing.T) {
+	testCases := []struct {
+		name     string
+		ctx      context.Context
+		expected bool
+	}{
+		{
+			name:     "case 0: context without dry run",
+			ctx:      context.Background(),
+			expected: false,
+		},
+		{
+			name:     "case 1: regular request",
+			ctx:      WithDryRun(context.Background(), false),
+			expected: false,
+		},
+		{
+			name:     "case 2: dry run request",
+			ctx:      WithDryRun(context.Background(), true),
+			expected: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if IsDryRun(tc.ctx) != tc.expected {
+				t.Fatalf("expected IsDryRun to be %t", tc.expected)
+			}
+		})
+	}
+}
@@ -2,6 +2,9 @@ package generic
 
 import (
 	"github.com/giantswarm/microerror"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/azure-admission-controller/internal/errors"
 )
 
 var clusterNotFoundError = &microerror.Error{
@@ -93,3 +96,8 @@ var parsingFailedError = &microerror.Error{
 func IsParsingFailed(err error) bool {
 	return microerror.Cause(err) == parsingFailedError
 }
+
+func init() {
+	errors.RegisterStatusReason(metav1.StatusReasonBadRequest, parsingFailedError)
+	errors.RegisterStatusReason(metav1.StatusReasonForbidden, organizationLabelWasChangedError)
+}
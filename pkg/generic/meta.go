@@ -7,8 +7,11 @@ import (
 	"github.com/giantswarm/microerror"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	capi "sigs.k8s.io/cluster-api/api/v1alpha3"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/azure-admission-controller/pkg/tracing"
 )
 
 type OwnerClusterGetter func(metav1.ObjectMetaAccessor) (capi.Cluster, bool, error)
@@ -50,6 +53,9 @@ func TryGetOwnerCluster(ctx context.Context, ctrlReader client.Reader, object me
 		return capi.Cluster{}, false, nil
 	}
 
+	ctx, span := tracing.Start(ctx, "generic.TryGetOwnerCluster", tracing.String("cluster", clusterName))
+	defer span.End()
+
 	var cluster capi.Cluster
 	key := client.ObjectKey{
 		Namespace: object.GetObjectMeta().GetNamespace(),
@@ -59,8 +65,27 @@ func TryGetOwnerCluster(ctx context.Context, ctrlReader client.Reader, object me
 	if apierrors.IsNotFound(err) {
 		return capi.Cluster{}, false, nil
 	} else if err != nil {
+		span.RecordError(err)
 		return capi.Cluster{}, false, microerror.Mask(err)
 	}
 
 	return cluster, true, nil
 }
+
+// DeepCopy returns a deep copy of the specified object, or the object itself
+// when it can't be copied, i.e. when it is not a runtime.Object. Handlers may
+// modify the objects they are passed, so an object handled by two handlers
+// concurrently must be copied.
+func DeepCopy(object metav1.ObjectMetaAccessor) metav1.ObjectMetaAccessor {
+	runtimeObject, ok := object.(runtime.Object)
+	if !ok {
+		return object
+	}
+
+	copied, ok := runtimeObject.DeepCopyObject().(metav1.ObjectMetaAccessor)
+	if !ok {
+		return object
+	}
+
+	return copied
+}
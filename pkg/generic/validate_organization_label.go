@@ -30,14 +30,14 @@ func ValidateOrganizationLabelUnchanged(old, new metav1.Object) error {
 	return nil
 }
 
-func ValidateOrganizationLabelContainsExistingOrganization(ctx context.Context, ctrlClient client.Client, obj metav1.Object) error {
+func ValidateOrganizationLabelContainsExistingOrganization(ctx context.Context, ctrlReader client.Reader, obj metav1.Object) error {
 	organizationName, ok := obj.GetLabels()[label.Organization]
 	if !ok {
 		return microerror.Maskf(organizationLabelNotFoundError, "CR doesn't contain Organization label %#q", label.Organization)
 	}
 
 	organization := &securityv1alpha1.Organization{}
-	err := ctrlClient.Get(ctx, client.ObjectKey{Name: normalize.AsDNSLabelName(organizationName)}, organization)
+	err := ctrlReader.Get(ctx, client.ObjectKey{Name: normalize.AsDNSLabelName(organizationName)}, organization)
 	if apierrors.IsNotFound(err) {
 		return microerror.Maskf(organizationNotFoundError, "Organization label %#q must contain an existing organization, got %#q but didn't find any CR with name %#q", label.Organization, organizationName, normalize.AsDNSLabelName(organizationName))
 	} else if err != nil {
@@ -47,7 +47,7 @@ func ValidateOrganizationLabelContainsExistingOrganization(ctx context.Context,
 	return nil
 }
 
-func ValidateOrganizationLabelMatchesCluster(ctx context.Context, ctrlClient client.Client, obj metav1.Object) error {
+func ValidateOrganizationLabelMatchesCluster(ctx context.Context, ctrlReader client.Reader, obj metav1.Object) error {
 	organizationName, ok := obj.GetLabels()[label.Organization]
 	if !ok {
 		return microerror.Maskf(organizationLabelNotFoundError, "CR doesn't contain Organization label %#q", label.Organization)
@@ -61,7 +61,7 @@ func ValidateOrganizationLabelMatchesCluster(ctx context.Context, ctrlClient cli
 	cluster := capi.Cluster{}
 	{
 		clusters := &capi.ClusterList{}
-		err := ctrlClient.List(ctx, clusters, client.MatchingLabels{label.Cluster: clusterName})
+		err := ctrlReader.List(ctx, clusters, client.MatchingLabels{label.Cluster: clusterName})
 		if err != nil {
 			return microerror.Mask(err)
 		}
@@ -0,0 +1,49 @@
+package generic
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+type warningsKey struct{}
+
+type warnings struct {
+	mutex    sync.Mutex
+	messages []string
+}
+
+// WithWarnings returns a copy of the specified context which collects the
+// admission warnings added with AddWarning. The HTTP handler factories call
+// it for every admission request and send the collected warnings back to the
+// API server, so they are shown to the user, e.g. by kubectl.
+func WithWarnings(ctx context.Context) context.Context {
+	return context.WithValue(ctx, warningsKey{}, &warnings{})
+}
+
+// AddWarning adds a non-fatal warning to the admission response of the
+// request being handled. Warnings are returned for both allowed and denied
+// requests. When the context does not collect warnings, e.g. in unit tests,
+// the warning is discarded.
+func AddWarning(ctx context.Context, format string, args ...interface{}) {
+	w, ok := ctx.Value(warningsKey{}).(*warnings)
+	if !ok {
+		return
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.messages = append(w.messages, fmt.Sprintf(format, args...))
+}
+
+// Warnings returns the warnings added to the specified context so far.
+func Warnings(ctx context.Context) []string {
+	w, ok := ctx.Value(warningsKey{}).(*warnings)
+	if !ok {
+		return nil
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return append([]string(nil), w.messages...)
+}
@@ -0,0 +1,31 @@
+package generic
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestWarnings(t *testing.T) {
+	t.Run("collects warnings", func(t *testing.T) {
+		ctx := WithWarnings(context.Background())
+
+		AddWarning(ctx, "first warning")
+		AddWarning(ctx, "second warning for %s", "ab123")
+
+		expected := []string{"first warning", "second warning for ab123"}
+		if !reflect.DeepEqual(Warnings(ctx), expected) {
+			t.Fatalf("expected warnings %v, got %v", expected, Warnings(ctx))
+		}
+	})
+
+	t.Run("discards warnings without collector", func(t *testing.T) {
+		ctx := context.Background()
+
+		AddWarning(ctx, "discarded warning")
+
+		if len(Warnings(ctx)) != 0 {
+			t.Fatalf("expected no warnings, got %v", Warnings(ctx))
+		}
+	})
+}
@@ -2,6 +2,9 @@ package machinepool
 
 import (
 	"github.com/giantswarm/microerror"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/azure-admission-controller/internal/errors"
 )
 
 var azureMachinePoolNotFoundError = &microerror.Error{
@@ -57,3 +60,8 @@ var failureDomainWasChangedError = &microerror.Error{
 func IsFailureDomainWasChangedError(err error) bool {
 	return microerror.Cause(err) == failureDomainWasChangedError
 }
+
+func init() {
+	errors.RegisterStatusReason(metav1.StatusReasonBadRequest, parsingFailedError)
+	errors.RegisterStatusReason(metav1.StatusReasonForbidden, failureDomainWasChangedError)
+}
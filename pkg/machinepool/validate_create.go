@@ -10,6 +10,7 @@ import (
 
 	"github.com/giantswarm/azure-admission-controller/pkg/generic"
 	"github.com/giantswarm/azure-admission-controller/pkg/key"
+	"github.com/giantswarm/azure-admission-controller/pkg/validator"
 )
 
 func (h *WebhookHandler) OnCreateValidate(ctx context.Context, object interface{}) error {
@@ -18,22 +19,14 @@ func (h *WebhookHandler) OnCreateValidate(ctx context.Context, object interface{
 		return microerror.Mask(err)
 	}
 
-	err = machinePoolNewCR.ValidateCreate()
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = generic.ValidateOrganizationLabelMatchesCluster(ctx, h.ctrlClient, machinePoolNewCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = h.checkAvailabilityZones(ctx, machinePoolNewCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
+	var errs validator.ErrorList
+	errs.Check(ctx, "upstreamWebhook", "", func() error { return machinePoolNewCR.ValidateCreate() })
+	errs.Check(ctx, "validateOrganizationLabelMatchesCluster", "metadata.labels", func() error {
+		return generic.ValidateOrganizationLabelMatchesCluster(ctx, h.ctrlReader, machinePoolNewCR)
+	})
+	errs.Check(ctx, "checkAvailabilityZones", "spec.failureDomains", func() error { return h.checkAvailabilityZones(ctx, machinePoolNewCR) })
 
-	return nil
+	return microerror.Mask(errs.ToAggregate())
 }
 
 func (h *WebhookHandler) checkAvailabilityZones(ctx context.Context, mp *capiexp.MachinePool) error {
@@ -42,7 +35,7 @@ func (h *WebhookHandler) checkAvailabilityZones(ctx context.Context, mp *capiexp
 		return microerror.Maskf(azureMachinePoolNotFoundError, "MachinePool's InfrastructureRef has to be set")
 	}
 	amp := capzexp.AzureMachinePool{}
-	err := h.ctrlClient.Get(ctx, client.ObjectKey{Namespace: mp.Spec.Template.Spec.InfrastructureRef.Namespace, Name: mp.Spec.Template.Spec.InfrastructureRef.Name}, &amp)
+	err := h.ctrlReader.Get(ctx, client.ObjectKey{Namespace: mp.Spec.Template.Spec.InfrastructureRef.Namespace, Name: mp.Spec.Template.Spec.InfrastructureRef.Name}, &amp)
 	if err != nil {
 		return microerror.Maskf(azureMachinePoolNotFoundError, "AzureMachinePool has to be created before the related MachinePool")
 	}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/giantswarm/azure-admission-controller/pkg/generic"
 	"github.com/giantswarm/azure-admission-controller/pkg/key"
+	"github.com/giantswarm/azure-admission-controller/pkg/validator"
 )
 
 func (h *WebhookHandler) OnUpdateValidate(ctx context.Context, oldObject interface{}, object interface{}) error {
@@ -26,22 +27,12 @@ func (h *WebhookHandler) OnUpdateValidate(ctx context.Context, oldObject interfa
 		return microerror.Mask(err)
 	}
 
-	err = machinePoolNewCR.ValidateUpdate(machinePoolOldCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = generic.ValidateOrganizationLabelUnchanged(machinePoolOldCR, machinePoolNewCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
-	err = checkAvailabilityZonesUnchanged(ctx, machinePoolOldCR, machinePoolNewCR)
-	if err != nil {
-		return microerror.Mask(err)
-	}
+	var errs validator.ErrorList
+	errs.Check(ctx, "upstreamWebhook", "", func() error { return machinePoolNewCR.ValidateUpdate(machinePoolOldCR) })
+	errs.Check(ctx, "validateOrganizationLabelUnchanged", "metadata.labels", func() error { return generic.ValidateOrganizationLabelUnchanged(machinePoolOldCR, machinePoolNewCR) })
+	errs.Check(ctx, "checkAvailabilityZonesUnchanged", "spec.failureDomains", func() error { return checkAvailabilityZonesUnchanged(ctx, machinePoolOldCR, machinePoolNewCR) })
 
-	return nil
+	return microerror.Mask(errs.ToAggregate())
 }
 
 func checkAvailabilityZonesUnchanged(_ context.Context, oldMP *capiexp.MachinePool, newMP *capiexp.MachinePool) error {
@@ -5,6 +5,7 @@ import (
 	"github.com/giantswarm/micrologger"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	capiexp "sigs.k8s.io/cluster-api/exp/api/v1alpha3"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -13,22 +14,22 @@ import (
 )
 
 type WebhookHandler struct {
-	ctrlClient client.Client
+	ctrlReader client.Reader
 	decoder    runtime.Decoder
 	logger     micrologger.Logger
 	vmcaps     *vmcapabilities.VMSKU
 }
 
 type WebhookHandlerConfig struct {
-	CtrlClient client.Client
+	CtrlReader client.Reader
 	Decoder    runtime.Decoder
 	Logger     micrologger.Logger
 	VMcaps     *vmcapabilities.VMSKU
 }
 
 func NewWebhookHandler(config WebhookHandlerConfig) (*WebhookHandler, error) {
-	if config.CtrlClient == nil {
-		return nil, microerror.Maskf(invalidConfigError, "%T.CtrlClient must not be empty", config)
+	if config.CtrlReader == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.CtrlReader must not be empty", config)
 	}
 	if config.Decoder == nil {
 		return nil, microerror.Maskf(invalidConfigError, "%T.Decoder must not be empty", config)
@@ -41,7 +42,7 @@ func NewWebhookHandler(config WebhookHandlerConfig) (*WebhookHandler, error) {
 	}
 
 	handler := &WebhookHandler{
-		ctrlClient: config.CtrlClient,
+		ctrlReader: config.CtrlReader,
 		decoder:    config.Decoder,
 		logger:     config.Logger,
 		vmcaps:     config.VMcaps,
@@ -58,6 +59,12 @@ func (h *WebhookHandler) Resource() string {
 	return "machinepool"
 }
 
+// GroupVersionResource returns the resource the webhooks of this handler are
+// registered for.
+func (h *WebhookHandler) GroupVersionResource() schema.GroupVersionResource {
+	return capiexp.GroupVersion.WithResource("machinepools")
+}
+
 func (h *WebhookHandler) Decode(rawObject runtime.RawExtension) (metav1.ObjectMetaAccessor, error) {
 	cr := &capiexp.MachinePool{}
 	if _, _, err := h.decoder.Decode(rawObject.Raw, nil, cr); err != nil {
@@ -0,0 +1,255 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	namespace = "azure_admission_controller"
+	subsystem = "webhook"
+
+	skuCacheSubsystem = "vm_sku_cache"
+)
+
+const (
+	// WebhookMutate is the webhook label value for mutating webhooks.
+	WebhookMutate = "mutate"
+	// WebhookValidate is the webhook label value for validating webhooks.
+	WebhookValidate = "validate"
+)
+
+const (
+	// OperationCreate is the operation label value for create requests.
+	OperationCreate = "create"
+	// OperationUpdate is the operation label value for update requests.
+	OperationUpdate = "update"
+)
+
+const (
+	// OutcomeAllowed is used when the webhook handler admitted the request.
+	OutcomeAllowed = "allowed"
+	// OutcomeDenied is used when the webhook handler rejected the request.
+	OutcomeDenied = "denied"
+	// OutcomeError is used when the request could not be processed, e.g. when
+	// it could not be decoded or when the legacy release filter failed.
+	OutcomeError = "error"
+	// OutcomeSkipped is used when the object is not reconciled by a legacy
+	// release, so the webhook handler was not called at all.
+	OutcomeSkipped = "skipped"
+)
+
+const (
+	// ShadowMatch is used when the candidate handler of a shadow evaluation
+	// decided like the live handler.
+	ShadowMatch = "match"
+	// ShadowDecisionDivergence is used when the candidate handler allowed a
+	// request the live handler denied, or the other way round.
+	ShadowDecisionDivergence = "decision"
+	// ShadowPatchDivergence is used when the candidate mutating handler
+	// returned other patches than the live handler.
+	ShadowPatchDivergence = "patch"
+	// ShadowTimeout is used when the candidate handler did not decide in
+	// time to be compared.
+	ShadowTimeout = "timeout"
+)
+
+const (
+	// RefreshSuccess is used when the VM SKUs of a location were fetched from
+	// the Azure API.
+	RefreshSuccess = "success"
+	// RefreshFailure is used when fetching the VM SKUs of a location failed,
+	// so the previous ones are still served.
+	RefreshFailure = "failure"
+)
+
+var (
+	labels = []string{"resource", "operation", "webhook", "outcome"}
+
+	requestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "requests_total",
+			Help:      "Number of admission requests handled, partitioned by resource, operation, webhook and outcome.",
+		},
+		labels,
+	)
+
+	requestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "request_duration_seconds",
+			Help:      "Time it took to handle an admission request, partitioned by resource, operation, webhook and outcome.",
+			Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+		},
+		labels,
+	)
+
+	patchesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "patches_total",
+			Help:      "Number of JSON patch operations returned by mutating webhooks, partitioned by resource and operation.",
+		},
+		[]string{"resource", "operation"},
+	)
+
+	panicsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "panics_total",
+			Help:      "Number of admission requests for which the webhook handler panicked, partitioned by resource, operation and webhook.",
+		},
+		[]string{"resource", "operation", "webhook"},
+	)
+
+	auditViolationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "audit_violations_total",
+			Help:      "Number of violations found by checks in audit mode, which did not deny the request, partitioned by resource, operation and check.",
+		},
+		[]string{"resource", "operation", "check"},
+	)
+
+	shadowEvaluationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "shadow_evaluations_total",
+			Help:      "Number of admission requests evaluated by a candidate handler in shadow mode, partitioned by resource, operation, webhook and result.",
+		},
+		[]string{"resource", "operation", "webhook", "result"},
+	)
+
+	bypassesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "bypasses_total",
+			Help:      "Number of violations of bypassed checks, which did not deny the request, partitioned by resource, operation and check.",
+		},
+		[]string{"resource", "operation", "check"},
+	)
+
+	skuCacheRefreshesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: skuCacheSubsystem,
+			Name:      "refreshes_total",
+			Help:      "Number of times the VM SKUs of a location were fetched from the Azure API, partitioned by location and result.",
+		},
+		[]string{"location", "result"},
+	)
+
+	skuCacheLastRefresh = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: skuCacheSubsystem,
+			Name:      "last_refresh_timestamp_seconds",
+			Help:      "Unix time the VM SKUs of a location were last fetched from the Azure API, partitioned by location.",
+		},
+		[]string{"location"},
+	)
+
+	skuCacheStale = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: skuCacheSubsystem,
+			Name:      "stale",
+			Help:      "Whether the VM SKUs cached for a location were loaded from the snapshot because the Azure API failed, partitioned by location.",
+		},
+		[]string{"location"},
+	)
+
+	skuCacheSKUs = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: skuCacheSubsystem,
+			Name:      "skus",
+			Help:      "Number of VM SKUs cached for a location, partitioned by location.",
+		},
+		[]string{"location"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(requestsTotal)
+	prometheus.MustRegister(requestDuration)
+	prometheus.MustRegister(patchesTotal)
+	prometheus.MustRegister(panicsTotal)
+	prometheus.MustRegister(auditViolationsTotal)
+	prometheus.MustRegister(bypassesTotal)
+	prometheus.MustRegister(shadowEvaluationsTotal)
+	prometheus.MustRegister(skuCacheRefreshesTotal)
+	prometheus.MustRegister(skuCacheLastRefresh)
+	prometheus.MustRegister(skuCacheSKUs)
+	prometheus.MustRegister(skuCacheStale)
+}
+
+// ObserveRequest records the outcome and the latency of an admission request
+// which started at the specified time.
+func ObserveRequest(resource, operation, webhook, outcome string, start time.Time) {
+	requestsTotal.WithLabelValues(resource, operation, webhook, outcome).Inc()
+	requestDuration.WithLabelValues(resource, operation, webhook, outcome).Observe(time.Since(start).Seconds())
+}
+
+// ObservePatches records the number of JSON patch operations that a mutating
+// webhook returned for a single admission request.
+func ObservePatches(resource, operation string, count int) {
+	patchesTotal.WithLabelValues(resource, operation).Add(float64(count))
+}
+
+// ObservePanic records that a webhook handler panicked while handling an
+// admission request.
+func ObservePanic(resource, operation, webhook string) {
+	panicsTotal.WithLabelValues(resource, operation, webhook).Inc()
+}
+
+// ObserveAuditViolation records that the named check, which is in audit mode,
+// found a violation in an admission request.
+func ObserveAuditViolation(resource, operation, check string) {
+	auditViolationsTotal.WithLabelValues(resource, operation, check).Inc()
+}
+
+// ObserveBypass records that a violation of the named check was bypassed with
+// the bypass annotation in an admission request.
+func ObserveBypass(resource, operation, check string) {
+	bypassesTotal.WithLabelValues(resource, operation, check).Inc()
+}
+
+// ObserveShadowEvaluation records the result of the evaluation of an
+// admission request by a candidate handler in shadow mode, e.g. ShadowMatch.
+func ObserveShadowEvaluation(resource, operation, webhook, result string) {
+	shadowEvaluationsTotal.WithLabelValues(resource, operation, webhook, result).Inc()
+}
+
+// ObserveSKUCacheRefresh records the result of fetching the VM SKUs of a
+// location from the Azure API, e.g. RefreshSuccess, at the specified time.
+// The number of cached SKUs and the last refresh time are only updated by
+// successful refreshes.
+func ObserveSKUCacheRefresh(location, result string, skus int, t time.Time) {
+	skuCacheRefreshesTotal.WithLabelValues(location, result).Inc()
+	if result != RefreshSuccess {
+		return
+	}
+
+	skuCacheLastRefresh.WithLabelValues(location).Set(float64(t.Unix()))
+	skuCacheSKUs.WithLabelValues(location).Set(float64(skus))
+	skuCacheStale.WithLabelValues(location).Set(0)
+}
+
+// ObserveSKUCacheSnapshot records that the VM SKUs of a location were loaded
+// from the snapshot, and that they were fetched from the Azure API at the
+// specified time. They are stale until the next successful refresh.
+func ObserveSKUCacheSnapshot(location string, skus int, fetched time.Time) {
+	skuCacheLastRefresh.WithLabelValues(location).Set(float64(fetched.Unix()))
+	skuCacheSKUs.WithLabelValues(location).Set(float64(skus))
+	skuCacheStale.WithLabelValues(location).Set(1)
+}
@@ -0,0 +1,153 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func Test_ObserveRequest(t *testing.T) {
+	testCases := []struct {
+		name      string
+		resource  string
+		operation string
+		webhook   string
+		outcome   string
+	}{
+		{
+			name:      "case 0: allowed create validation",
+			resource:  "azuremachinepool",
+			operation: OperationCreate,
+			webhook:   WebhookValidate,
+			outcome:   OutcomeAllowed,
+		},
+		{
+			name:      "case 1: denied update validation",
+			resource:  "azuremachinepool",
+			operation: OperationUpdate,
+			webhook:   WebhookValidate,
+			outcome:   OutcomeDenied,
+		},
+		{
+			name:      "case 2: skipped create mutation",
+			resource:  "cluster",
+			operation: OperationCreate,
+			webhook:   WebhookMutate,
+			outcome:   OutcomeSkipped,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			counter := requestsTotal.WithLabelValues(tc.resource, tc.operation, tc.webhook, tc.outcome)
+			before := testutil.ToFloat64(counter)
+
+			ObserveRequest(tc.resource, tc.operation, tc.webhook, tc.outcome, time.Now())
+
+			after := testutil.ToFloat64(counter)
+			if after-before != 1 {
+				t.Fatalf("expected counter to be incremented by 1, got %f", after-before)
+			}
+		})
+	}
+}
+
+func Test_ObservePatches(t *testing.T) {
+	counter := patchesTotal.WithLabelValues("azuremachinepool", OperationCreate)
+	before := testutil.ToFloat64(counter)
+
+	ObservePatches("azuremachinepool", OperationCreate, 3)
+	ObservePatches("azuremachinepool", OperationCreate, 0)
+
+	after := testutil.ToFloat64(counter)
+	if after-before != 3 {
+		t.Fatalf("expected counter to be incremented by 3, got %f", after-before)
+	}
+}
+
+func Test_ObservePanic(t *testing.T) {
+	counter := panicsTotal.WithLabelValues("azuremachinepool", OperationUpdate, WebhookValidate)
+	before := testutil.ToFloat64(counter)
+
+	ObservePanic("azuremachinepool", OperationUpdate, WebhookValidate)
+
+	after := testutil.ToFloat64(counter)
+	if after-before != 1 {
+		t.Fatalf("expected counter to be incremented by 1, got %f", after-before)
+	}
+}
+
+func Test_ObserveAuditViolation(t *testing.T) {
+	counter := auditViolationsTotal.WithLabelValues("azuremachinepool", OperationCreate, "checkDataDisks")
+	before := testutil.ToFloat64(counter)
+
+	ObserveAuditViolation("azuremachinepool", OperationCreate, "checkDataDisks")
+
+	after := testutil.ToFloat64(counter)
+	if after-before != 1 {
+		t.Fatalf("expected counter to be incremented by 1, got %f", after-before)
+	}
+}
+
+func Test_ObserveBypass(t *testing.T) {
+	counter := bypassesTotal.WithLabelValues("azuremachinepool", OperationUpdate, "checkStorageAccountTypeUnchanged")
+	before := testutil.ToFloat64(counter)
+
+	ObserveBypass("azuremachinepool", OperationUpdate, "checkStorageAccountTypeUnchanged")
+
+	after := testutil.ToFloat64(counter)
+	if after-before != 1 {
+		t.Fatalf("expected counter to be incremented by 1, got %f", after-before)
+	}
+}
+
+func Test_ObserveShadowEvaluation(t *testing.T) {
+	counter := shadowEvaluationsTotal.WithLabelValues("azuremachinepool", OperationUpdate, WebhookValidate, ShadowDecisionDivergence)
+	before := testutil.ToFloat64(counter)
+
+	ObserveShadowEvaluation("azuremachinepool", OperationUpdate, WebhookValidate, ShadowDecisionDivergence)
+
+	after := testutil.ToFloat64(counter)
+	if after-before != 1 {
+		t.Fatalf("expected counter to be incremented by 1, got %f", after-before)
+	}
+}
+
+func Test_ObserveSKUCacheRefresh(t *testing.T) {
+	refreshed := time.Unix(1700000000, 0)
+	ObserveSKUCacheRefresh("westeurope", RefreshSuccess, 42, refreshed)
+
+	// Failures don't change the cached SKUs.
+	counter := skuCacheRefreshesTotal.WithLabelValues("westeurope", RefreshFailure)
+	before := testutil.ToFloat64(counter)
+	ObserveSKUCacheRefresh("westeurope", RefreshFailure, 0, refreshed.Add(time.Hour))
+
+	if after := testutil.ToFloat64(counter); after-before != 1 {
+		t.Fatalf("expected counter to be incremented by 1, got %f", after-before)
+	}
+	if skus := testutil.ToFloat64(skuCacheSKUs.WithLabelValues("westeurope")); skus != 42 {
+		t.Fatalf("expected 42 SKUs, got %f", skus)
+	}
+	if timestamp := testutil.ToFloat64(skuCacheLastRefresh.WithLabelValues("westeurope")); timestamp != 1700000000 {
+		t.Fatalf("expected last refresh at 1700000000, got %f", timestamp)
+	}
+}
+
+func Test_ObserveSKUCacheSnapshot(t *testing.T) {
+	ObserveSKUCacheSnapshot("northeurope", 7, time.Unix(1600000000, 0))
+
+	if stale := testutil.ToFloat64(skuCacheStale.WithLabelValues("northeurope")); stale != 1 {
+		t.Fatalf("expected stale SKUs, got %f", stale)
+	}
+	if timestamp := testutil.ToFloat64(skuCacheLastRefresh.WithLabelValues("northeurope")); timestamp != 1600000000 {
+		t.Fatalf("expected last refresh at 1600000000, got %f", timestamp)
+	}
+
+	// Successful refreshes replace the stale SKUs.
+	ObserveSKUCacheRefresh("northeurope", RefreshSuccess, 8, time.Unix(1700000000, 0))
+
+	if stale := testutil.ToFloat64(skuCacheStale.WithLabelValues("northeurope")); stale != 0 {
+		t.Fatalf("expected fresh SKUs, got %f", stale)
+	}
+}
@@ -12,9 +12,9 @@ import (
 	"github.com/giantswarm/azure-admission-controller/pkg/release"
 )
 
-func CopyAzureOperatorVersionLabelFromAzureClusterCR(ctx context.Context, ctrlClient client.Client, meta metav1.Object) (*PatchOperation, error) {
+func CopyAzureOperatorVersionLabelFromAzureClusterCR(ctx context.Context, ctrlReader client.Reader, meta metav1.Object) (*PatchOperation, error) {
 	if meta.GetLabels()[label.AzureOperatorVersion] == "" {
-		azureOperatorVersion, err := getLabelValueFromAzureCluster(ctx, ctrlClient, meta, label.AzureOperatorVersion)
+		azureOperatorVersion, err := getLabelValueFromAzureCluster(ctx, ctrlReader, meta, label.AzureOperatorVersion)
 		if err != nil {
 			return nil, microerror.Mask(err)
 		}
@@ -15,9 +15,9 @@ import (
 	"github.com/giantswarm/azure-admission-controller/internal/errors"
 )
 
-func EnsureReleaseVersionLabel(ctx context.Context, ctrlClient client.Client, meta metav1.Object) (*PatchOperation, error) {
+func EnsureReleaseVersionLabel(ctx context.Context, ctrlReader client.Reader, meta metav1.Object) (*PatchOperation, error) {
 	if meta.GetLabels()[label.ReleaseVersion] == "" {
-		release, err := getReleaseLabelValueFromAzureCluster(ctx, ctrlClient, meta)
+		release, err := getReleaseLabelValueFromAzureCluster(ctx, ctrlReader, meta)
 		if err != nil {
 			return nil, microerror.Mask(err)
 		}
@@ -31,11 +31,11 @@ func EnsureReleaseVersionLabel(ctx context.Context, ctrlClient client.Client, me
 	return nil, nil
 }
 
-func getReleaseLabelValueFromAzureCluster(ctx context.Context, ctrlClient client.Client, meta metav1.Object) (string, error) {
-	return getLabelValueFromAzureCluster(ctx, ctrlClient, meta, label.ReleaseVersion)
+func getReleaseLabelValueFromAzureCluster(ctx context.Context, ctrlReader client.Reader, meta metav1.Object) (string, error) {
+	return getLabelValueFromAzureCluster(ctx, ctrlReader, meta, label.ReleaseVersion)
 }
 
-func getLabelValueFromAzureCluster(ctx context.Context, ctrlClient client.Client, meta metav1.Object, labelName string) (string, error) {
+func getLabelValueFromAzureCluster(ctx context.Context, ctrlReader client.Reader, meta metav1.Object, labelName string) (string, error) {
 	clusterID := meta.GetLabels()[label.Cluster]
 	if clusterID == "" {
 		return "", microerror.Maskf(clusterLabelNotFoundError, "Object has no %#q label, can't detect cluster ID.", label.Cluster)
@@ -44,7 +44,7 @@ func getLabelValueFromAzureCluster(ctx context.Context, ctrlClient client.Client
 	// Retrieve the `AzureCluster` CR related to this object.
 	cluster := &capz.AzureCluster{}
 	{
-		err := ctrlClient.Get(ctx, client.ObjectKey{Name: clusterID, Namespace: meta.GetNamespace()}, cluster)
+		err := ctrlReader.Get(ctx, client.ObjectKey{Name: clusterID, Namespace: meta.GetNamespace()}, cluster)
 		if apierrors.IsNotFound(err) {
 			return "", microerror.Maskf(errors.NotFoundError, "Looking for AzureCluster named %#q but it was not found. Can't continue.", clusterID)
 		} else if err != nil {
@@ -13,6 +13,15 @@ func IsInvalidConfig(err error) bool {
 	return microerror.Cause(err) == invalidConfigError
 }
 
+var shadowPanicError = &microerror.Error{
+	Kind: "shadowPanicError",
+}
+
+// IsShadowPanic asserts shadowPanicError.
+func IsShadowPanic(err error) bool {
+	return microerror.Cause(err) == shadowPanicError
+}
+
 var azureOperatorVersionLabelNotFoundError = &microerror.Error{
 	Kind: "azureOperatorVersionLabelNotFoundError",
 }
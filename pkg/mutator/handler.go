@@ -9,19 +9,21 @@ import (
 	"net/http"
 
 	"github.com/giantswarm/microerror"
-	"k8s.io/api/admission/v1beta1"
+	admissionv1 "k8s.io/api/admission/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	metav1beta1 "k8s.io/apimachinery/pkg/apis/meta/v1beta1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	"k8s.io/apimachinery/pkg/types"
 
+	"github.com/giantswarm/azure-admission-controller/internal/admissionreview"
+	internalerrors "github.com/giantswarm/azure-admission-controller/internal/errors"
 	"github.com/giantswarm/azure-admission-controller/pkg/generic"
 )
 
 type Mutator interface {
 	Log(keyVals ...interface{})
-	Mutate(ctx context.Context, review *v1beta1.AdmissionRequest) ([]PatchOperation, error)
+	Mutate(ctx context.Context, request *admissionv1.AdmissionRequest) ([]PatchOperation, error)
 	Resource() string
 }
 
@@ -47,40 +49,42 @@ func Handler(mutator Mutator) http.HandlerFunc {
 			return
 		}
 
-		review := v1beta1.AdmissionReview{}
-		if _, _, err := Deserializer.Decode(data, nil, &review); err != nil {
+		admissionRequest, version, err := admissionreview.Decode(data)
+		if err != nil {
 			mutator.Log("level", "error", "message", "unable to parse admission review request", "stack", microerror.JSON(err))
 			writer.WriteHeader(http.StatusBadRequest)
 			return
 		}
-		resourceName := fmt.Sprintf("%s %s/%s", review.Request.Kind, review.Request.Namespace, extractName(review.Request))
 
-		patch, err := mutator.Mutate(request.Context(), review.Request)
+		ctx := generic.WithWarnings(request.Context())
+		resourceName := fmt.Sprintf("%s %s/%s", admissionRequest.Kind, admissionRequest.Namespace, extractName(admissionRequest))
+
+		patch, err := mutator.Mutate(ctx, admissionRequest)
 		if err != nil {
-			writeResponse(mutator, writer, errorResponse(review.Request.UID, microerror.Mask(err)))
+			writeResponse(mutator, writer, version, errorResponse(admissionRequest.UID, microerror.Mask(err)), generic.Warnings(ctx))
 			return
 		}
 
 		patchData, err := json.Marshal(patch)
 		if err != nil {
 			mutator.Log("level", "error", "message", fmt.Sprintf("unable to serialize patch for %s", resourceName), "stack", microerror.JSON(err))
-			writeResponse(mutator, writer, errorResponse(review.Request.UID, InternalError))
+			writeResponse(mutator, writer, version, errorResponse(admissionRequest.UID, InternalError), generic.Warnings(ctx))
 			return
 		}
 
 		mutator.Log("level", "debug", "message", fmt.Sprintf("admitted %s (with %d patches)", resourceName, len(patch)))
 
-		pt := v1beta1.PatchTypeJSONPatch
-		writeResponse(mutator, writer, &v1beta1.AdmissionResponse{
+		pt := admissionv1.PatchTypeJSONPatch
+		writeResponse(mutator, writer, version, &admissionv1.AdmissionResponse{
 			Allowed:   true,
-			UID:       review.Request.UID,
+			UID:       admissionRequest.UID,
 			Patch:     patchData,
 			PatchType: &pt,
-		})
+		}, generic.Warnings(ctx))
 	}
 }
 
-func extractName(request *v1beta1.AdmissionRequest) string {
+func extractName(request *admissionv1.AdmissionRequest) string {
 	if request.Name != "" {
 		return request.Name
 	}
@@ -99,28 +103,28 @@ func extractName(request *v1beta1.AdmissionRequest) string {
 	return "<unknown>"
 }
 
-func writeResponse(logger generic.Logger, writer http.ResponseWriter, response *v1beta1.AdmissionResponse) {
-	resp, err := json.Marshal(v1beta1.AdmissionReview{
-		TypeMeta: metav1.TypeMeta{
-			Kind:       "AdmissionReview",
-			APIVersion: "admission.k8s.io/v1",
-		},
-		Response: response,
-	})
+func writeResponse(logger generic.Logger, writer http.ResponseWriter, version string, response *admissionv1.AdmissionResponse, warnings []string) {
+	resp, err := admissionreview.Encode(version, response, warnings)
 	if err != nil {
 		logger.Log("level", "error", "message", "unable to serialize response", "stack", microerror.JSON(err))
 		writer.WriteHeader(http.StatusInternalServerError)
+		return
 	}
 	if _, err := writer.Write(resp); err != nil {
 		logger.Log("level", "error", "message", "unable to write response", "stack", microerror.JSON(err))
 	}
 }
 
-func errorResponse(uid types.UID, err error) *v1beta1.AdmissionResponse {
-	return &v1beta1.AdmissionResponse{
+func errorResponse(uid types.UID, err error) *admissionv1.AdmissionResponse {
+	reason := internalerrors.StatusReason(err)
+
+	return &admissionv1.AdmissionResponse{
 		Allowed: false,
 		UID:     uid,
 		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Reason:  reason,
+			Code:    internalerrors.StatusCode(reason),
 			Message: err.Error(),
 		},
 	}
@@ -6,62 +6,109 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
-	"k8s.io/api/admission/v1beta1"
+	admissionv1 "k8s.io/api/admission/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	capi "sigs.k8s.io/cluster-api/api/v1alpha3"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/giantswarm/azure-admission-controller/internal/admissionreview"
+	"github.com/giantswarm/azure-admission-controller/pkg/audit"
+	"github.com/giantswarm/azure-admission-controller/pkg/enforcement"
 	"github.com/giantswarm/azure-admission-controller/pkg/filter"
 	"github.com/giantswarm/azure-admission-controller/pkg/generic"
+	"github.com/giantswarm/azure-admission-controller/pkg/metrics"
+	"github.com/giantswarm/azure-admission-controller/pkg/requestcache"
+	"github.com/giantswarm/azure-admission-controller/pkg/tracing"
 )
 
 type HttpHandlerFactoryConfig struct {
-	CtrlReader client.Reader
-	CtrlClient client.Client
-	Logger     micrologger.Logger
+	AuditSink   *audit.Sink
+	CtrlReader  client.Reader
+	Enforcement *enforcement.Store
+	Logger      micrologger.Logger
+
+	// CandidateEnforcement is the enforcement configuration candidate handlers
+	// are evaluated with in shadow mode instead of the live one. It is
+	// optional.
+	CandidateEnforcement *enforcement.Store
 }
 
 // HttpHandlerFactory creates HTTP handlers for mutating create and update requests.
 type HttpHandlerFactory struct {
-	ctrlReader client.Reader
-	ctrlClient client.Client
-	logger     micrologger.Logger
+	auditSink            *audit.Sink
+	ctrlReader           client.Reader
+	enforcement          *enforcement.Store
+	logger               micrologger.Logger
+	candidateEnforcement *enforcement.Store
+
+	// shadows tracks the comparisons of candidate handlers running in the
+	// background, see compareShadow.
+	shadows sync.WaitGroup
 }
 
 func NewHttpHandlerFactory(config HttpHandlerFactoryConfig) (*HttpHandlerFactory, error) {
+	if config.AuditSink == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.AuditSink must not be empty", config)
+	}
 	if config.CtrlReader == nil {
 		return nil, microerror.Maskf(invalidConfigError, "%T.CtrlReader must not be empty", config)
 	}
-	if config.CtrlClient == nil {
-		return nil, microerror.Maskf(invalidConfigError, "%T.CtrlClient must not be empty", config)
+	if config.Enforcement == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Enforcement must not be empty", config)
 	}
 	if config.Logger == nil {
 		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
 	}
 
 	h := &HttpHandlerFactory{
-		ctrlReader: config.CtrlReader,
-		ctrlClient: config.CtrlClient,
-		logger:     config.Logger,
+		auditSink:            config.AuditSink,
+		ctrlReader:           config.CtrlReader,
+		enforcement:          config.Enforcement,
+		logger:               config.Logger,
+		candidateEnforcement: config.CandidateEnforcement,
 	}
 
 	return h, nil
 }
 
+// Wait waits for the comparisons of candidate handlers running in the
+// background. Call it once the server stopped handling requests, so that their
+// logs and metrics are not lost on shutdown.
+func (h *HttpHandlerFactory) Wait() {
+	h.shadows.Wait()
+}
+
 // NewCreateHandler returns a HTTP handler for mutating create requests.
 func (h *HttpHandlerFactory) NewCreateHandler(mutator WebhookCreateHandler) http.HandlerFunc {
-	mutateFunc := func(ctx context.Context, review v1beta1.AdmissionReview) ([]PatchOperation, error) {
+	return h.newCreateHandler(mutator, nil)
+}
+
+// NewShadowCreateHandler returns a HTTP handler for mutating create requests
+// with the live handler, which also mutates them with the candidate handler in
+// shadow mode. The live handler's patches are returned. The candidate handler
+// mutates a copy of the same object concurrently, and is compared in the
+// background without delaying the response. When it decides otherwise or
+// returns other patches, the divergence is logged and counted, but never
+// returned to the API server.
+func (h *HttpHandlerFactory) NewShadowCreateHandler(live WebhookCreateHandler, candidate WebhookCreateHandler) http.HandlerFunc {
+	return h.newCreateHandler(live, candidate)
+}
+
+func (h *HttpHandlerFactory) newCreateHandler(mutator WebhookCreateHandler, candidate WebhookCreateHandler) http.HandlerFunc {
+	mutateFunc := func(ctx context.Context, request *admissionv1.AdmissionRequest) ([]PatchOperation, string, error) {
 		// Decode the new CR from the request.
-		object, err := mutator.Decode(review.Request.Object)
+		object, err := mutator.Decode(request.Object)
 		if err != nil {
-			return nil, microerror.Mask(err)
+			return nil, metrics.OutcomeError, microerror.Mask(err)
 		}
 
 		ownerClusterGetter := func(objectMeta metav1.ObjectMetaAccessor) (capi.Cluster, bool, error) {
-			ownerCluster, ok, err := generic.TryGetOwnerCluster(ctx, h.ctrlClient, object)
+			ownerCluster, ok, err := generic.TryGetOwnerCluster(ctx, h.ctrlReader, object)
 			if err != nil {
 				return capi.Cluster{}, false, microerror.Mask(err)
 			}
@@ -72,36 +119,57 @@ func (h *HttpHandlerFactory) NewCreateHandler(mutator WebhookCreateHandler) http
 		// Check if the CR should be mutated by the azure-admission-controller.
 		ok, err := filter.IsObjectReconciledByLegacyRelease(ctx, h.logger, h.ctrlReader, object, ownerClusterGetter)
 		if err != nil {
-			return nil, microerror.Mask(err)
+			return nil, metrics.OutcomeError, microerror.Mask(err)
 		}
 
-		var patch []PatchOperation
+		if !ok {
+			return nil, metrics.OutcomeSkipped, nil
+		}
 
-		if ok {
-			// Mutate the CR and get patch for those mutations.
-			patch, err = mutator.OnCreateMutate(ctx, object)
-			if err != nil {
-				return nil, microerror.Mask(err)
-			}
+		var shadow <-chan shadowResult
+		if candidate != nil {
+			shadow = h.startShadow(ctx, candidate.Resource(), object, func(ctx context.Context, object interface{}) ([]PatchOperation, error) {
+				return candidate.OnCreateMutate(ctx, object)
+			})
 		}
 
-		return patch, nil
+		// Mutate the CR and get patch for those mutations.
+		patch, err := mutator.OnCreateMutate(ctx, object)
+		if shadow != nil {
+			h.compareShadow(mutator, metrics.OperationCreate, request, patch, err, shadow)
+		}
+		if err != nil {
+			return nil, metrics.OutcomeDenied, microerror.Mask(err)
+		}
+
+		return patch, metrics.OutcomeAllowed, nil
 	}
 
-	return h.newHttpHandler(mutator, mutateFunc)
+	return h.newHttpHandler(mutator, metrics.OperationCreate, mutateFunc)
 }
 
 // NewUpdateHandler returns a HTTP handler for mutating update requests.
 func (h *HttpHandlerFactory) NewUpdateHandler(mutator WebhookUpdateHandler) http.HandlerFunc {
-	mutateFunc := func(ctx context.Context, review v1beta1.AdmissionReview) ([]PatchOperation, error) {
+	return h.newUpdateHandler(mutator, nil)
+}
+
+// NewShadowUpdateHandler returns a HTTP handler for mutating update requests
+// with the live handler, which also mutates them with the candidate handler in
+// shadow mode, like NewShadowCreateHandler.
+func (h *HttpHandlerFactory) NewShadowUpdateHandler(live WebhookUpdateHandler, candidate WebhookUpdateHandler) http.HandlerFunc {
+	return h.newUpdateHandler(live, candidate)
+}
+
+func (h *HttpHandlerFactory) newUpdateHandler(mutator WebhookUpdateHandler, candidate WebhookUpdateHandler) http.HandlerFunc {
+	mutateFunc := func(ctx context.Context, request *admissionv1.AdmissionRequest) ([]PatchOperation, string, error) {
 		// Decode the new updated CR from the request.
-		object, err := mutator.Decode(review.Request.Object)
+		object, err := mutator.Decode(request.Object)
 		if err != nil {
-			return nil, microerror.Mask(err)
+			return nil, metrics.OutcomeError, microerror.Mask(err)
 		}
 
 		ownerClusterGetter := func(objectMeta metav1.ObjectMetaAccessor) (capi.Cluster, bool, error) {
-			ownerCluster, ok, err := generic.TryGetOwnerCluster(ctx, h.ctrlClient, object)
+			ownerCluster, ok, err := generic.TryGetOwnerCluster(ctx, h.ctrlReader, object)
 			if err != nil {
 				return capi.Cluster{}, false, microerror.Mask(err)
 			}
@@ -112,29 +180,40 @@ func (h *HttpHandlerFactory) NewUpdateHandler(mutator WebhookUpdateHandler) http
 		// Check if the CR should be mutated by the azure-admission-controller.
 		ok, err := filter.IsObjectReconciledByLegacyRelease(ctx, h.logger, h.ctrlReader, object, ownerClusterGetter)
 		if err != nil {
-			return nil, microerror.Mask(err)
+			return nil, metrics.OutcomeError, microerror.Mask(err)
 		}
 
-		var patch []PatchOperation
+		if !ok {
+			return nil, metrics.OutcomeSkipped, nil
+		}
 
-		if ok {
-			// Decode the old CR from the request (before the update).
-			oldObject, err := mutator.Decode(review.Request.OldObject)
-			if err != nil {
-				return nil, microerror.Mask(err)
-			}
+		// Decode the old CR from the request (before the update).
+		oldObject, err := mutator.Decode(request.OldObject)
+		if err != nil {
+			return nil, metrics.OutcomeError, microerror.Mask(err)
+		}
 
-			// Mutate the CR and get patch for those mutations.
-			patch, err = mutator.OnUpdateMutate(ctx, oldObject, object)
-			if err != nil {
-				return nil, microerror.Mask(err)
-			}
+		var shadow <-chan shadowResult
+		if candidate != nil {
+			oldObject := generic.DeepCopy(oldObject)
+			shadow = h.startShadow(ctx, candidate.Resource(), object, func(ctx context.Context, object interface{}) ([]PatchOperation, error) {
+				return candidate.OnUpdateMutate(ctx, oldObject, object)
+			})
 		}
 
-		return patch, nil
+		// Mutate the CR and get patch for those mutations.
+		patch, err := mutator.OnUpdateMutate(ctx, oldObject, object)
+		if shadow != nil {
+			h.compareShadow(mutator, metrics.OperationUpdate, request, patch, err, shadow)
+		}
+		if err != nil {
+			return nil, metrics.OutcomeDenied, microerror.Mask(err)
+		}
+
+		return patch, metrics.OutcomeAllowed, nil
 	}
 
-	return h.newHttpHandler(mutator, mutateFunc)
+	return h.newHttpHandler(mutator, metrics.OperationUpdate, mutateFunc)
 }
 
 // newHttpHandler returns a HTTP handler for mutating a request with the specified mutation
@@ -142,8 +221,36 @@ func (h *HttpHandlerFactory) NewUpdateHandler(mutator WebhookUpdateHandler) http
 // This function is basically the same as the existing Handler func, with the only difference that
 // it is now wrapped into the New...Handler funcs above in order to first decode the CR and check
 // if it should be mutated by azure-admission-controller.
-func (h *HttpHandlerFactory) newHttpHandler(webhookHandler WebhookHandlerBase, mutateFunc func(ctx context.Context, review v1beta1.AdmissionReview) ([]PatchOperation, error)) http.HandlerFunc {
+//
+// Every request is recorded in the metrics with the outcome returned by the mutation function,
+// together with the number of emitted patch operations.
+func (h *HttpHandlerFactory) newHttpHandler(webhookHandler WebhookHandlerBase, operation string, mutateFunc func(ctx context.Context, request *admissionv1.AdmissionRequest) ([]PatchOperation, string, error)) http.HandlerFunc {
 	return func(writer http.ResponseWriter, request *http.Request) {
+		start := time.Now()
+		outcome := metrics.OutcomeError
+		var admissionRequest *admissionv1.AdmissionRequest
+		var admissionResponse *admissionv1.AdmissionResponse
+		defer func() {
+			metrics.ObserveRequest(webhookHandler.Resource(), operation, metrics.WebhookMutate, outcome, start)
+
+			span := tracing.FromContext(request.Context())
+			span.SetAttributes(tracing.String("admission.resource", webhookHandler.Resource()), tracing.String("admission.operation", operation), tracing.String("admission.outcome", outcome))
+			if admissionRequest != nil {
+				span.SetAttributes(tracing.String("admission.uid", string(admissionRequest.UID)), tracing.String("admission.object", admissionRequest.Namespace+"/"+admissionRequest.Name))
+			}
+
+			// Requests which could not be decoded are only logged. Requests
+			// whose handler panicked have no response yet, they are recorded
+			// by recovery.Middleware.
+			if admissionRequest != nil && admissionResponse != nil {
+				entry := audit.NewEntry(webhookHandler.Resource(), metrics.WebhookMutate, operation, outcome, admissionRequest, admissionResponse)
+				err := h.auditSink.Record(entry)
+				if err != nil {
+					webhookHandler.Log("level", "error", "message", "unable to write audit log entry", "stack", microerror.JSON(err))
+				}
+			}
+		}()
+
 		if request.Header.Get("Content-Type") != "application/json" {
 			webhookHandler.Log("level", "error", "message", fmt.Sprintf("invalid content-type: %q", request.Header.Get("Content-Type")))
 			writer.WriteHeader(http.StatusBadRequest)
@@ -157,40 +264,59 @@ func (h *HttpHandlerFactory) newHttpHandler(webhookHandler WebhookHandlerBase, m
 			return
 		}
 
-		review := v1beta1.AdmissionReview{}
-		if _, _, err := Deserializer.Decode(data, nil, &review); err != nil {
+		admissionRequest, version, err := admissionreview.Decode(data)
+		if err != nil {
 			webhookHandler.Log("level", "error", "message", "unable to parse admission review request", "stack", microerror.JSON(err))
 			writer.WriteHeader(http.StatusBadRequest)
 			return
 		}
 
+		ctx := generic.WithWarnings(request.Context())
+		// Dry run requests are mutated like any other request, so that users see the
+		// object as it would be persisted. Side effects must be guarded with
+		// generic.IsDryRun.
+		ctx = generic.WithDryRun(ctx, admissionRequest.DryRun != nil && *admissionRequest.DryRun)
+
+		// The enforcement configuration is captured once, so that a reload
+		// doesn't change it in the middle of the request.
+		ctx = enforcement.NewContext(ctx, h.enforcement.Config(), webhookHandler.Resource())
+
+		// Every object is read at most once while handling the request.
+		ctx = requestcache.NewContext(ctx)
+
 		var patch []PatchOperation
-		if review.Request.DryRun != nil && *review.Request.DryRun {
-			webhookHandler.Log("level", "debug", "message", "Dry run is not supported. Request processing stopped.", "stack", microerror.JSON(err))
+		if enforcement.HandlerEnabled(ctx) {
+			patch, outcome, err = mutateFunc(ctx, admissionRequest)
 		} else {
-			patch, err = mutateFunc(request.Context(), review)
-			if err != nil {
-				writeResponse(webhookHandler, writer, errorResponse(review.Request.UID, microerror.Mask(err)))
-				return
-			}
+			webhookHandler.Log("level", "debug", "message", "webhook handler is disabled in the enforcement configuration")
+			outcome = metrics.OutcomeSkipped
+		}
+		if err != nil {
+			admissionResponse = errorResponse(admissionRequest.UID, microerror.Mask(err))
+			writeResponse(webhookHandler, writer, version, admissionResponse, generic.Warnings(ctx))
+			return
 		}
 
-		resourceName := fmt.Sprintf("%s %s/%s", review.Request.Kind, review.Request.Namespace, extractName(review.Request))
+		resourceName := fmt.Sprintf("%s %s/%s", admissionRequest.Kind, admissionRequest.Namespace, extractName(admissionRequest))
 		patchData, err := json.Marshal(patch)
 		if err != nil {
 			webhookHandler.Log("level", "error", "message", fmt.Sprintf("unable to serialize patch for %s", resourceName), "stack", microerror.JSON(err))
-			writeResponse(webhookHandler, writer, errorResponse(review.Request.UID, InternalError))
+			admissionResponse = errorResponse(admissionRequest.UID, InternalError)
+			writeResponse(webhookHandler, writer, version, admissionResponse, generic.Warnings(ctx))
+			outcome = metrics.OutcomeError
 			return
 		}
 
 		webhookHandler.Log("level", "debug", "message", fmt.Sprintf("admitted %s (with %d patches)", resourceName, len(patch)))
+		metrics.ObservePatches(webhookHandler.Resource(), operation, len(patch))
 
-		pt := v1beta1.PatchTypeJSONPatch
-		writeResponse(webhookHandler, writer, &v1beta1.AdmissionResponse{
+		pt := admissionv1.PatchTypeJSONPatch
+		admissionResponse = &admissionv1.AdmissionResponse{
 			Allowed:   true,
-			UID:       review.Request.UID,
+			UID:       admissionRequest.UID,
 			Patch:     patchData,
 			PatchType: &pt,
-		})
+		}
+		writeResponse(webhookHandler, writer, version, admissionResponse, generic.Warnings(ctx))
 	}
 }
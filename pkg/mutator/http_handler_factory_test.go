@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -16,13 +17,18 @@ import (
 	"github.com/giantswarm/apiextensions/v3/pkg/label"
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
+	"github.com/prometheus/client_golang/prometheus"
 	admission "k8s.io/api/admission/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/yaml"
 
+	"github.com/giantswarm/azure-admission-controller/internal/admissionreview"
 	builder "github.com/giantswarm/azure-admission-controller/internal/test/cluster"
+	"github.com/giantswarm/azure-admission-controller/pkg/audit"
+	"github.com/giantswarm/azure-admission-controller/pkg/enforcement"
+	"github.com/giantswarm/azure-admission-controller/pkg/metrics"
 	"github.com/giantswarm/azure-admission-controller/pkg/release"
 	"github.com/giantswarm/azure-admission-controller/pkg/unittest"
 )
@@ -52,7 +58,13 @@ func TestHttpHandler(t *testing.T) {
 		object        object
 		oldObject     object
 		operation     admission.Operation
+		dryRun        bool
+		patches       []PatchOperation
 		expectedError *microerror.Error
+		// candidatePatches are returned by the candidate handler evaluated in
+		// shadow mode when shadow is set to the expected result.
+		candidatePatches []PatchOperation
+		shadow           string
 	}
 
 	testCases := []testCase{
@@ -84,119 +96,254 @@ func TestHttpHandler(t *testing.T) {
 			expectedError: release.ReleaseNotFoundError,
 			operation:     admission.Create,
 		},
+		{
+			name: "Mutate Cluster creation for dry run requests",
+			object: builder.BuildCluster(
+				builder.Name("ab123"),
+				builder.Labels(map[string]string{
+					label.ReleaseVersion: legacyRelease,
+				})),
+			operation: admission.Create,
+			dryRun:    true,
+			patches: []PatchOperation{
+				PatchReplace("/metadata/labels/test", "value"),
+			},
+		},
+		{
+			name: "Mutate Cluster creation with the live handler when the candidate handler in shadow mode patches differently",
+			object: builder.BuildCluster(
+				builder.Name("ab123"),
+				builder.Labels(map[string]string{
+					label.ReleaseVersion: legacyRelease,
+				})),
+			operation: admission.Create,
+			patches: []PatchOperation{
+				PatchReplace("/metadata/labels/test", "value"),
+			},
+			candidatePatches: []PatchOperation{
+				PatchReplace("/metadata/labels/test", "candidate"),
+			},
+			shadow: metrics.ShadowPatchDivergence,
+		},
+		{
+			name: "Mutate Cluster update when the candidate handler in shadow mode patches the same",
+			object: builder.BuildCluster(
+				builder.Name("ab123"),
+				builder.Labels(map[string]string{
+					label.ReleaseVersion: legacyRelease,
+				})),
+			oldObject: builder.BuildCluster(
+				builder.Name("ab123"),
+				builder.Labels(map[string]string{
+					label.ReleaseVersion: legacyRelease,
+				})),
+			operation: admission.Update,
+			patches: []PatchOperation{
+				PatchReplace("/metadata/labels/test", "value"),
+			},
+			candidatePatches: []PatchOperation{
+				PatchReplace("/metadata/labels/test", "value"),
+			},
+			shadow: metrics.ShadowMatch,
+		},
 	}
 
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			var err error
-			ctx := context.Background()
-			logger, _ := micrologger.New(micrologger.Config{})
-			fakeK8sClient := unittest.FakeK8sClient()
-			ctrlClient := fakeK8sClient.CtrlClient()
-			loadReleases(t, ctx, ctrlClient)
-
-			//
-			// We want to test webhook HTTP handler for mutating create/update requests. For creating
-			// the handler we will use HttpHandlerFactory to create the HTTP handler, so here we
-			// are basically testing the handlers created by the factory.
-			//
-			// Mutation logic itself here does not matter, so we are using a generic
-			// WebhookHandlerMock as a WebhookCreateHandler/WebhookUpdateHandler interface implementation.
-			//
-			var httpHandlerFactory *HttpHandlerFactory
-			{
-				c := HttpHandlerFactoryConfig{
-					CtrlReader: ctrlClient, // Passing client here, for the sake of simpler test code
-					CtrlClient: ctrlClient,
-					Logger:     logger,
-				}
-				httpHandlerFactory, err = NewHttpHandlerFactory(c)
-				if err != nil {
-					t.Fatal(err)
-				}
-			}
+	for _, version := range []string{admissionreview.V1, admissionreview.V1beta1} {
+		for _, tc := range testCases {
+			t.Run(fmt.Sprintf("%s %s", version, tc.name), func(t *testing.T) {
+				var err error
+				ctx := context.Background()
+				logger, _ := micrologger.New(micrologger.Config{})
+				fakeK8sClient := unittest.FakeK8sClient()
+				ctrlClient := fakeK8sClient.CtrlClient()
+				loadReleases(t, ctx, ctrlClient)
+
+				//
+				// We want to test webhook HTTP handler for mutating create/update requests. For creating
+				// the handler we will use HttpHandlerFactory to create the HTTP handler, so here we
+				// are basically testing the handlers created by the factory.
+				//
+				// Mutation logic itself here does not matter, so we are using a generic
+				// WebhookHandlerMock as a WebhookCreateHandler/WebhookUpdateHandler interface implementation.
+				//
+				var httpHandlerFactory *HttpHandlerFactory
+				{
+					auditSink, err := audit.NewSink(audit.SinkConfig{
+						Writer: ioutil.Discard,
+					})
+					if err != nil {
+						t.Fatal(err)
+					}
 
-			webhookHandlerMock := WebhookHandlerMock{
-				DecodeFunc: func(runtime.RawExtension) (metav1.ObjectMetaAccessor, error) {
-					return tc.object, nil
-				},
-			}
+					enforcementStore, err := enforcement.NewStore(enforcement.StoreConfig{
+						Logger: logger,
+					})
+					if err != nil {
+						t.Fatal(err)
+					}
 
-			var httpHandler http.HandlerFunc
-			switch tc.operation {
-			case admission.Create:
-				httpHandler = httpHandlerFactory.NewCreateHandler(&webhookHandlerMock)
-			case admission.Update:
-				httpHandler = httpHandlerFactory.NewUpdateHandler(&webhookHandlerMock)
-			default:
-				t.Fatal("Unsupported operation")
-			}
+					c := HttpHandlerFactoryConfig{
+						AuditSink:   auditSink,
+						CtrlReader:  ctrlClient, // Passing client here, for the sake of simpler test code
+						Enforcement: enforcementStore,
+						Logger:      logger,
+					}
+					httpHandlerFactory, err = NewHttpHandlerFactory(c)
+					if err != nil {
+						t.Fatal(err)
+					}
+				}
 
-			//
-			// Now that we have an HTTP handler to test, we want to send a request to it.
-			//
-			// That request will contain admission review for creating/updating an object. This is
-			// basically the request body that API server would send to the webhook.
-			//
-			admissionReviewJson := getAdmissionReview(t, tc.operation, tc.object, tc.oldObject)
-			request := getHttpRequest(t, admissionReviewJson)
-
-			//
-			// Finally let's call the webhook HTTP handler and get the response.
-			//
-			// Since we this a test, we will not call the handler with the ResponseWriter, but with
-			// a ResponseRecorder from httptest package, so we can easily check the response.
-			//
-			httpRecorder := httptest.NewRecorder()
-			httpHandler.ServeHTTP(httpRecorder, request)
-
-			var admissionReview admission.AdmissionReview
-			err = json.Unmarshal(httpRecorder.Body.Bytes(), &admissionReview)
-			if err != nil {
-				t.Fatal(err)
-			}
+				webhookHandlerMock := WebhookHandlerMock{
+					DecodeFunc: func(runtime.RawExtension) (metav1.ObjectMetaAccessor, error) {
+						return tc.object, nil
+					},
+					Patches: tc.patches,
+				}
 
-			//
-			// Now let's check the handler response.
-			//
-			if !admissionReview.Response.Allowed {
-				// webhook handler returned an error and it is rejecting the request
+				candidateMock := WebhookHandlerMock{
+					Patches: tc.candidatePatches,
+				}
 
-				if tc.expectedError == nil {
-					// we did not expect any errors
-					msg := "Request is not allowed."
+				var httpHandler http.HandlerFunc
+				var operation string
+				switch {
+				case tc.operation == admission.Create && tc.shadow == "":
+					httpHandler = httpHandlerFactory.NewCreateHandler(&webhookHandlerMock)
+					operation = metrics.OperationCreate
+				case tc.operation == admission.Create:
+					httpHandler = httpHandlerFactory.NewShadowCreateHandler(&webhookHandlerMock, &candidateMock)
+					operation = metrics.OperationCreate
+				case tc.operation == admission.Update && tc.shadow == "":
+					httpHandler = httpHandlerFactory.NewUpdateHandler(&webhookHandlerMock)
+					operation = metrics.OperationUpdate
+				case tc.operation == admission.Update:
+					httpHandler = httpHandlerFactory.NewShadowUpdateHandler(&webhookHandlerMock, &candidateMock)
+					operation = metrics.OperationUpdate
+				default:
+					t.Fatal("Unsupported operation")
+				}
+				shadowEvaluationsBefore := shadowEvaluations(t, operation, tc.shadow)
+
+				//
+				// Now that we have an HTTP handler to test, we want to send a request to it.
+				//
+				// That request will contain admission review for creating/updating an object. This is
+				// basically the request body that API server would send to the webhook.
+				//
+				admissionReviewJson := getAdmissionReview(t, version, tc.operation, tc.dryRun, tc.object, tc.oldObject)
+				request := getHttpRequest(t, admissionReviewJson)
+
+				//
+				// Finally let's call the webhook HTTP handler and get the response.
+				//
+				// Since we this a test, we will not call the handler with the ResponseWriter, but with
+				// a ResponseRecorder from httptest package, so we can easily check the response.
+				//
+				httpRecorder := httptest.NewRecorder()
+				httpHandler.ServeHTTP(httpRecorder, request)
+
+				var admissionReview admission.AdmissionReview
+				err = json.Unmarshal(httpRecorder.Body.Bytes(), &admissionReview)
+				if err != nil {
+					t.Fatal(err)
+				}
 
-					if admissionReview.Response.Result != nil {
-						msg += fmt.Sprintf(" Got status code %d. Error message: %s.",
-							admissionReview.Response.Result.Code,
-							admissionReview.Response.Result.Message)
-					}
+				// The response must be sent with the same apiVersion as the request.
+				if admissionReview.APIVersion != version {
+					t.Fatalf("expected response apiVersion %q, got %q", version, admissionReview.APIVersion)
+				}
 
-					t.Error(msg)
-				} else {
-					// we expected an error, let's check if we got what we wanted
-					expectedErrorMessage := microerror.Mask(tc.expectedError).Error()
-					returnedErrorMessage := ""
+				httpHandlerFactory.Wait()
+				if tc.shadow != "" {
+					evaluations := shadowEvaluations(t, operation, tc.shadow) - shadowEvaluationsBefore
+					if evaluations != 1 {
+						t.Fatalf("expected 1 shadow evaluation with result %q, got %f", tc.shadow, evaluations)
+					}
+				}
 
-					if admissionReview.Response.Result != nil {
-						// we set error messages in the Result, let's get it from there
-						returnedErrorMessage = admissionReview.Response.Result.Message
+				// Patches must be returned for dry run requests as well, and
+				// only the ones of the live handler in shadow mode.
+				if len(tc.patches) > 0 {
+					var patches []PatchOperation
+					err = json.Unmarshal(admissionReview.Response.Patch, &patches)
+					if err != nil {
+						t.Fatal(err)
 					}
+					if !reflect.DeepEqual(patches, tc.patches) {
+						t.Fatalf("expected patches %v, got %v", tc.patches, patches)
+					}
+				}
+
+				//
+				// Now let's check the handler response.
+				//
+				if !admissionReview.Response.Allowed {
+					// webhook handler returned an error and it is rejecting the request
 
-					// Full error message can have more info, but it contains expected error message.
-					if !strings.Contains(returnedErrorMessage, expectedErrorMessage) {
+					if tc.expectedError == nil {
+						// we did not expect any errors
 						msg := "Request is not allowed."
-						msg += fmt.Sprintf(" Got status code %d. Expected error message '%s', got '%s'.",
-							admissionReview.Response.Result.Code,
-							expectedErrorMessage,
-							returnedErrorMessage)
 
-						t.Fatalf(msg)
+						if admissionReview.Response.Result != nil {
+							msg += fmt.Sprintf(" Got status code %d. Error message: %s.",
+								admissionReview.Response.Result.Code,
+								admissionReview.Response.Result.Message)
+						}
+
+						t.Error(msg)
+					} else {
+						// we expected an error, let's check if we got what we wanted
+						expectedErrorMessage := microerror.Mask(tc.expectedError).Error()
+						returnedErrorMessage := ""
+
+						if admissionReview.Response.Result != nil {
+							// we set error messages in the Result, let's get it from there
+							returnedErrorMessage = admissionReview.Response.Result.Message
+						}
+
+						// Full error message can have more info, but it contains expected error message.
+						if !strings.Contains(returnedErrorMessage, expectedErrorMessage) {
+							msg := "Request is not allowed."
+							msg += fmt.Sprintf(" Got status code %d. Expected error message '%s', got '%s'.",
+								admissionReview.Response.Result.Code,
+								expectedErrorMessage,
+								returnedErrorMessage)
+
+							t.Fatalf(msg)
+						}
 					}
 				}
+			})
+		}
+	}
+}
+
+// shadowEvaluations returns the number of shadow evaluations of the mock
+// handler for the specified operation with the specified result.
+func shadowEvaluations(t *testing.T, operation, result string) float64 {
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, family := range families {
+		if family.GetName() != "azure_admission_controller_webhook_shadow_evaluations_total" {
+			continue
+		}
+
+		for _, m := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
 			}
-		})
+			if labels["resource"] == "mock_type" && labels["operation"] == operation && labels["webhook"] == metrics.WebhookMutate && labels["result"] == result {
+				return m.GetCounter().GetValue()
+			}
+		}
 	}
+
+	return 0
 }
 
 func getHttpRequest(t *testing.T, admissionReview []byte) *http.Request {
@@ -210,7 +357,7 @@ func getHttpRequest(t *testing.T, admissionReview []byte) *http.Request {
 	return request
 }
 
-func getAdmissionReview(t *testing.T, operation admission.Operation, object runtime.Object, oldObject runtime.Object) []byte {
+func getAdmissionReview(t *testing.T, version string, operation admission.Operation, dryRun bool, object runtime.Object, oldObject runtime.Object) []byte {
 	objectJson, err := json.Marshal(object)
 	if err != nil {
 		t.Fatal(err)
@@ -222,6 +369,7 @@ func getAdmissionReview(t *testing.T, operation admission.Operation, object runt
 			Resource: object.GetObjectKind().GroupVersionKind().Kind,
 		},
 		Operation: operation,
+		DryRun:    &dryRun,
 		Object: runtime.RawExtension{
 			Raw:    objectJson,
 			Object: nil,
@@ -241,8 +389,11 @@ func getAdmissionReview(t *testing.T, operation admission.Operation, object runt
 	}
 
 	admissionReview := admission.AdmissionReview{
-		TypeMeta: metav1.TypeMeta{},
-		Request:  admissionRequest,
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "AdmissionReview",
+			APIVersion: version,
+		},
+		Request: admissionRequest,
 	}
 
 	admissionReviewJson, err := json.Marshal(admissionReview)
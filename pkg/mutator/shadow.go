@@ -0,0 +1,146 @@
+package mutator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/azure-admission-controller/pkg/enforcement"
+	"github.com/giantswarm/azure-admission-controller/pkg/generic"
+	"github.com/giantswarm/azure-admission-controller/pkg/metrics"
+)
+
+// shadowTimeout is how long the candidate handler may take. Slower candidates
+// are counted as timeouts and not compared.
+const shadowTimeout = 10 * time.Second
+
+type shadowResult struct {
+	patch []PatchOperation
+	err   error
+}
+
+// startShadow mutates a copy of the specified object with the specified
+// mutation function of a candidate handler in the background and returns the
+// channel its result is sent to. The candidate has its own warnings and
+// violations, so it can't change the response, and it always runs as a dry
+// run. It is not cancelled with the request, but bounded by shadowTimeout, and
+// its result is not sent when it runs out of time. Panics are turned into
+// errors. The candidate is evaluated with the candidate enforcement
+// configuration when the factory has one, and skipped like the live handler
+// when it disables the handler of the specified resource.
+func (h *HttpHandlerFactory) startShadow(ctx context.Context, resource string, object metav1.ObjectMetaAccessor, mutate func(ctx context.Context, object interface{}) ([]PatchOperation, error)) <-chan shadowResult {
+	ctx = generic.Detach(ctx)
+	ctx = generic.WithWarnings(ctx)
+	ctx = generic.WithDryRun(ctx, true)
+	if h.candidateEnforcement != nil {
+		ctx = enforcement.WithConfig(ctx, h.candidateEnforcement.Config(), resource)
+	} else {
+		ctx = enforcement.WithViolations(ctx)
+	}
+	object = generic.DeepCopy(object)
+
+	ctx, cancel := context.WithTimeout(ctx, shadowTimeout)
+	result := make(chan shadowResult, 1)
+	go func() {
+		defer cancel()
+		defer func() {
+			if r := recover(); r != nil {
+				result <- shadowResult{err: microerror.Maskf(shadowPanicError, "%v\n%s", r, debug.Stack())}
+			}
+		}()
+
+		var patch []PatchOperation
+		var err error
+		if enforcement.HandlerEnabled(ctx) {
+			patch, err = mutate(ctx, object)
+		}
+		if ctx.Err() == nil {
+			result <- shadowResult{patch: patch, err: err}
+		}
+	}()
+
+	return result
+}
+
+// compareShadow compares the result of the candidate handler started with
+// startShadow with the decision and the patches of the live handler in the
+// background, so that the response is never delayed by the candidate, and
+// logs and counts it when it diverges.
+func (h *HttpHandlerFactory) compareShadow(webhookHandler WebhookHandlerBase, operation string, request *admissionv1.AdmissionRequest, livePatch []PatchOperation, liveErr error, shadow <-chan shadowResult) {
+	h.shadows.Add(1)
+	go func() {
+		defer h.shadows.Done()
+
+		var candidate shadowResult
+		select {
+		case candidate = <-shadow:
+		case <-time.After(shadowTimeout):
+			metrics.ObserveShadowEvaluation(webhookHandler.Resource(), operation, metrics.WebhookMutate, metrics.ShadowTimeout)
+			webhookHandler.Log("level", "warning", "message", fmt.Sprintf("candidate handler did not mutate %s of %s %s/%s within %s", operation, request.Kind.Kind, request.Namespace, request.Name, shadowTimeout), "uid", request.UID)
+			return
+		}
+
+		if (liveErr == nil) != (candidate.err == nil) {
+			metrics.ObserveShadowEvaluation(webhookHandler.Resource(), operation, metrics.WebhookMutate, metrics.ShadowDecisionDivergence)
+			webhookHandler.Log("level", "warning", "message", fmt.Sprintf("candidate handler %s %s of %s %s/%s, live handler %s it", decision(candidate.err), operation, request.Kind.Kind, request.Namespace, request.Name, decision(liveErr)), "uid", request.UID, "live", errorMessage(liveErr), "candidate", errorMessage(candidate.err))
+			return
+		}
+
+		if liveErr == nil {
+			livePatchData, err := json.Marshal(livePatch)
+			if err != nil {
+				webhookHandler.Log("level", "error", "message", "unable to serialize patch of live handler", "stack", microerror.JSON(err))
+				return
+			}
+			candidatePatchData, err := json.Marshal(candidate.patch)
+			if err != nil {
+				webhookHandler.Log("level", "error", "message", "unable to serialize patch of candidate handler", "stack", microerror.JSON(err))
+				return
+			}
+
+			if !equalPatches(livePatchData, candidatePatchData) {
+				metrics.ObserveShadowEvaluation(webhookHandler.Resource(), operation, metrics.WebhookMutate, metrics.ShadowPatchDivergence)
+				webhookHandler.Log("level", "warning", "message", fmt.Sprintf("candidate handler patched %s of %s %s/%s differently than live handler", operation, request.Kind.Kind, request.Namespace, request.Name), "uid", request.UID, "live", string(livePatchData), "candidate", string(candidatePatchData))
+				return
+			}
+		}
+
+		metrics.ObserveShadowEvaluation(webhookHandler.Resource(), operation, metrics.WebhookMutate, metrics.ShadowMatch)
+	}()
+}
+
+// equalPatches returns whether the specified serialized patches are equal. No
+// patch is serialized as null or as an empty array.
+func equalPatches(a, b []byte) bool {
+	empty := func(patch []byte) bool {
+		return string(patch) == "null" || string(patch) == "[]"
+	}
+	if empty(a) && empty(b) {
+		return true
+	}
+
+	return bytes.Equal(a, b)
+}
+
+func decision(err error) string {
+	if err != nil {
+		return "denied"
+	}
+
+	return "allowed"
+}
+
+func errorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	return err.Error()
+}
@@ -12,11 +12,21 @@ type WebhookHandlerBase interface {
 	Resource() string
 }
 
+// WebhookCreateHandler mutates create requests. Besides the patches,
+// OnCreateMutate can report non-fatal findings with generic.AddWarning on the
+// passed context, they are returned to the user as admission warnings. Dry run
+// requests are mutated too, so side effects must be guarded with
+// generic.IsDryRun.
 type WebhookCreateHandler interface {
 	WebhookHandlerBase
 	OnCreateMutate(ctx context.Context, object interface{}) ([]PatchOperation, error)
 }
 
+// WebhookUpdateHandler mutates update requests. Besides the patches,
+// OnUpdateMutate can report non-fatal findings with generic.AddWarning on the
+// passed context, they are returned to the user as admission warnings. Dry run
+// requests are mutated too, so side effects must be guarded with
+// generic.IsDryRun.
 type WebhookUpdateHandler interface {
 	WebhookHandlerBase
 	OnUpdateMutate(ctx context.Context, oldObject interface{}, object interface{}) ([]PatchOperation, error)
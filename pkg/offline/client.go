@@ -0,0 +1,83 @@
+package offline
+
+import (
+	"encoding/json"
+	"fmt"
+
+	corev1alpha1 "github.com/giantswarm/apiextensions/v3/pkg/apis/core/v1alpha1"
+	providerv1alpha1 "github.com/giantswarm/apiextensions/v3/pkg/apis/provider/v1alpha1"
+	releasev1alpha1 "github.com/giantswarm/apiextensions/v3/pkg/apis/release/v1alpha1"
+	securityv1alpha1 "github.com/giantswarm/apiextensions/v3/pkg/apis/security/v1alpha1"
+	"github.com/giantswarm/microerror"
+	"k8s.io/apimachinery/pkg/runtime"
+	capz "sigs.k8s.io/cluster-api-provider-azure/api/v1alpha3"
+	capzexp "sigs.k8s.io/cluster-api-provider-azure/exp/api/v1alpha3"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha3"
+	capiexp "sigs.k8s.io/cluster-api/exp/api/v1alpha3"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake" //nolint:staticcheck
+)
+
+// NewScheme returns a scheme with the types the webhook handlers read, like
+// the one of the management cluster client.
+func NewScheme() (*runtime.Scheme, error) {
+	scheme := runtime.NewScheme()
+	builders := []func(*runtime.Scheme) error{
+		capi.AddToScheme,
+		capiexp.AddToScheme,
+		capz.AddToScheme,
+		capzexp.AddToScheme,
+		corev1alpha1.AddToScheme,
+		providerv1alpha1.AddToScheme,
+		releasev1alpha1.AddToScheme,
+		securityv1alpha1.AddToScheme,
+	}
+	for _, addToScheme := range builders {
+		err := addToScheme(scheme)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	return scheme, nil
+}
+
+// NewClient returns a fake client serving the specified manifests, e.g. the
+// Release, Organization and Cluster objects the webhook handlers look up.
+// Manifests of types the scheme doesn't know are ignored. When the same
+// object is found more than once, the last manifest wins.
+func NewClient(manifests []Manifest) (client.Client, error) {
+	scheme, err := NewScheme()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var keys []string
+	objects := map[string]runtime.Object{}
+	for _, m := range manifests {
+		object, err := scheme.New(m.GroupVersionKind())
+		if runtime.IsNotRegisteredError(err) {
+			continue
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		err = json.Unmarshal(m.Object, object)
+		if err != nil {
+			return nil, microerror.Maskf(invalidManifestError, "%s: %s", m, err.Error())
+		}
+
+		key := fmt.Sprintf("%s/%s/%s", m.GroupVersionKind(), m.Namespace, m.Name)
+		if _, ok := objects[key]; !ok {
+			keys = append(keys, key)
+		}
+		objects[key] = object
+	}
+
+	var initObjects []runtime.Object
+	for _, key := range keys {
+		initObjects = append(initObjects, objects[key])
+	}
+
+	return fake.NewFakeClientWithScheme(scheme, initObjects...), nil
+}
@@ -0,0 +1,14 @@
+package offline
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+var invalidManifestError = &microerror.Error{
+	Kind: "invalidManifestError",
+}
+
+// IsInvalidManifest asserts invalidManifestError.
+func IsInvalidManifest(err error) bool {
+	return microerror.Cause(err) == invalidManifestError
+}
@@ -0,0 +1,201 @@
+// Package offline loads Kubernetes manifests from YAML files and serves them
+// with a fake controller-runtime client, so that the webhook handlers can run
+// without a management cluster, e.g. to validate the manifests of a GitOps
+// repository in CI.
+package offline
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"gopkg.in/yaml.v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var indexRegexp = regexp.MustCompile(`^(.*)\[(\d+)\]$`)
+
+// Manifest is a Kubernetes object read from a YAML file.
+type Manifest struct {
+	// File is the path of the file the object was read from.
+	File string
+	// Line is the line of the file the object starts at.
+	Line int
+	// Object is the object encoded as JSON.
+	Object []byte
+
+	metav1.TypeMeta
+	metav1.ObjectMeta
+
+	node *yaml.Node
+}
+
+// String returns the location and the identity of the object, e.g.
+// "clusters/ab123.yaml:12: Cluster org-acme/ab123".
+func (m Manifest) String() string {
+	name := m.Name
+	if m.Namespace != "" {
+		name = m.Namespace + "/" + name
+	}
+
+	return fmt.Sprintf("%s:%d: %s %s", m.File, m.Line, m.Kind, name)
+}
+
+// FieldLine returns the line of the field with the specified path, e.g.
+// "spec.template.vmSize" or "spec.subnets[0].name". When the field is not
+// set, the line of its closest parent which is set is returned.
+func (m Manifest) FieldLine(field string) int {
+	line := m.Line
+	if field == "" || m.node == nil {
+		return line
+	}
+
+	node := m.node
+	for _, part := range strings.Split(field, ".") {
+		index := -1
+		if matches := indexRegexp.FindStringSubmatch(part); matches != nil {
+			part = matches[1]
+			index, _ = strconv.Atoi(matches[2])
+		}
+
+		key, value := mappingEntry(node, part)
+		if value == nil {
+			return line
+		}
+		node = value
+		line = key.Line
+
+		if index >= 0 {
+			if node.Kind != yaml.SequenceNode || index >= len(node.Content) {
+				return line
+			}
+			node = node.Content[index]
+			line = node.Line
+		}
+	}
+
+	return line
+}
+
+// Load reads the manifests from the YAML files at the specified paths.
+// Directories are walked for files with a .yaml or .yml extension. Documents
+// which are not Kubernetes objects, i.e. have no kind, are ignored.
+func Load(paths ...string) ([]Manifest, error) {
+	var manifests []Manifest
+	for _, path := range paths {
+		err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return microerror.Mask(err)
+			}
+			if info.IsDir() {
+				return nil
+			}
+			// Files given explicitly are read whatever their extension.
+			if file != path && !isYAMLFile(file) {
+				return nil
+			}
+
+			fileManifests, err := loadFile(file)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+			manifests = append(manifests, fileManifests...)
+
+			return nil
+		})
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	return manifests, nil
+}
+
+func loadFile(file string) ([]Manifest, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var manifests []Manifest
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var document yaml.Node
+		err = decoder.Decode(&document)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, microerror.Maskf(invalidManifestError, "%s: %s", file, err.Error())
+		}
+
+		if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+			continue
+		}
+		node := document.Content[0]
+
+		var object interface{}
+		err = node.Decode(&object)
+		if err != nil {
+			return nil, microerror.Maskf(invalidManifestError, "%s:%d: %s", file, node.Line, err.Error())
+		}
+
+		objectJSON, err := json.Marshal(object)
+		if err != nil {
+			return nil, microerror.Maskf(invalidManifestError, "%s:%d: %s", file, node.Line, err.Error())
+		}
+
+		m := Manifest{
+			File:   file,
+			Line:   node.Line,
+			Object: objectJSON,
+			node:   node,
+		}
+		err = json.Unmarshal(objectJSON, &struct {
+			*metav1.TypeMeta `json:",inline"`
+			Metadata         *metav1.ObjectMeta `json:"metadata"`
+		}{
+			TypeMeta: &m.TypeMeta,
+			Metadata: &m.ObjectMeta,
+		})
+		if err != nil {
+			return nil, microerror.Maskf(invalidManifestError, "%s:%d: %s", file, node.Line, err.Error())
+		}
+
+		if m.Kind == "" {
+			continue
+		}
+
+		manifests = append(manifests, m)
+	}
+
+	return manifests, nil
+}
+
+func isYAMLFile(file string) bool {
+	ext := filepath.Ext(file)
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// mappingEntry returns the key and the value nodes of the specified key of a
+// mapping node, or nils when the node is not a mapping or does not have the key.
+func mappingEntry(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+
+	return nil, nil
+}
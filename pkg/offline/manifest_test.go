@@ -0,0 +1,78 @@
+package offline
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/giantswarm/microerror"
+)
+
+func Test_Load(t *testing.T) {
+	manifests, err := Load(filepath.Join("testdata", "manifests"))
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+
+	var actual []string
+	for _, m := range manifests {
+		actual = append(actual, m.String())
+	}
+
+	expected := []string{
+		"testdata/manifests/machinepool.yaml:4: AzureMachinePool org-acme/np001",
+		"testdata/manifests/nested/values.yaml:4: ConfigMap org-acme/values",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("manifests == %#v, want %#v", actual, expected)
+	}
+}
+
+func Test_Manifest_FieldLine(t *testing.T) {
+	manifests, err := Load(filepath.Join("testdata", "manifests", "machinepool.yaml"))
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+	m := manifests[0]
+
+	testCases := []struct {
+		name  string
+		field string
+		line  int
+	}{
+		{
+			name:  "case 0: empty field",
+			field: "",
+			line:  4,
+		},
+		{
+			name:  "case 1: nested field",
+			field: "spec.template.vmSize",
+			line:  11,
+		},
+		{
+			name:  "case 2: list item",
+			field: "spec.template.dataDisks[1].nameSuffix",
+			line:  15,
+		},
+		{
+			name:  "case 3: missing field falls back to parent",
+			field: "spec.template.osDisk.diskSizeGB",
+			line:  10,
+		},
+		{
+			name:  "case 4: index out of range falls back to list",
+			field: "spec.template.dataDisks[2]",
+			line:  12,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			line := m.FieldLine(tc.field)
+			if line != tc.line {
+				t.Fatalf("FieldLine(%q) == %d, want %d", tc.field, line, tc.line)
+			}
+		})
+	}
+}
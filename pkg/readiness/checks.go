@@ -0,0 +1,97 @@
+package readiness
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+)
+
+const (
+	// minCertificateValidity is the minimum remaining validity of the serving
+	// certificate. cert-manager renews it long before, so a certificate this
+	// close to expiring means the renewal is not picked up.
+	minCertificateValidity = 24 * time.Hour
+)
+
+// CacheSynced returns a check which passes once all the informers of the
+// specified controller-runtime cache have synced.
+func CacheSynced(c cache.Informers) Check {
+	return Check{
+		Name: "cache-sync",
+		Func: func(ctx context.Context) error {
+			if !c.WaitForCacheSync(ctx.Done()) {
+				return microerror.Maskf(notReadyError, "controller-runtime cache has not synced")
+			}
+
+			return nil
+		},
+	}
+}
+
+// Warmer is implemented by caches which must be filled before the first
+// request, e.g. vmcapabilities.VMSKU.
+type Warmer interface {
+	// Warm fills the cache for the specified location. It does nothing when
+	// the cache is already filled.
+	Warm(ctx context.Context, location string) error
+}
+
+// CacheWarm returns a check which passes once the specified cache is filled
+// for the specified location. The check fills the cache itself, so filling
+// it is retried until it succeeds.
+func CacheWarm(name string, warmer Warmer, location string) Check {
+	return Check{
+		Name: name,
+		Func: func(ctx context.Context) error {
+			err := warmer.Warm(ctx, location)
+			if err != nil {
+				return microerror.Maskf(notReadyError, "cache for location %q is not warm: %s", location, err.Error())
+			}
+
+			return nil
+		},
+	}
+}
+
+// CertificateValid returns a check which passes when the certificate returned
+// by getCertificate, e.g. certman.CertMan.GetCertificate, is valid and does
+// not expire within minCertificateValidity.
+func CertificateValid(getCertificate func(*tls.ClientHelloInfo) (*tls.Certificate, error)) Check {
+	return Check{
+		Name: "serving-certificate",
+		Func: func(ctx context.Context) error {
+			return checkCertificate(getCertificate, time.Now())
+		},
+	}
+}
+
+func checkCertificate(getCertificate func(*tls.ClientHelloInfo) (*tls.Certificate, error), now time.Time) error {
+	certificate, err := getCertificate(&tls.ClientHelloInfo{})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	if certificate == nil || len(certificate.Certificate) == 0 {
+		return microerror.Maskf(notReadyError, "serving certificate is not loaded")
+	}
+
+	leaf := certificate.Leaf
+	if leaf == nil {
+		leaf, err = x509.ParseCertificate(certificate.Certificate[0])
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	if now.Before(leaf.NotBefore) {
+		return microerror.Maskf(notReadyError, "serving certificate is not valid before %s", leaf.NotBefore.Format(time.RFC3339))
+	}
+	if leaf.NotAfter.Sub(now) < minCertificateValidity {
+		return microerror.Maskf(notReadyError, "serving certificate expires at %s", leaf.NotAfter.Format(time.RFC3339))
+	}
+
+	return nil
+}
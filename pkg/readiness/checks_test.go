@@ -0,0 +1,92 @@
+package readiness
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func Test_checkCertificate(t *testing.T) {
+	now := time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name         string
+		certificate  *tls.Certificate
+		errorMatcher func(err error) bool
+	}{
+		{
+			name:        "case 0: valid certificate",
+			certificate: newCertificate(t, now.Add(-time.Hour), now.Add(30*24*time.Hour)),
+		},
+		{
+			name:         "case 1: certificate about to expire",
+			certificate:  newCertificate(t, now.Add(-time.Hour), now.Add(time.Hour)),
+			errorMatcher: IsNotReady,
+		},
+		{
+			name:         "case 2: expired certificate",
+			certificate:  newCertificate(t, now.Add(-48*time.Hour), now.Add(-time.Hour)),
+			errorMatcher: IsNotReady,
+		},
+		{
+			name:         "case 3: certificate not valid yet",
+			certificate:  newCertificate(t, now.Add(time.Hour), now.Add(30*24*time.Hour)),
+			errorMatcher: IsNotReady,
+		},
+		{
+			name:         "case 4: certificate not loaded",
+			certificate:  nil,
+			errorMatcher: IsNotReady,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			getCertificate := func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+				return tc.certificate, nil
+			}
+
+			err := checkCertificate(getCertificate, now)
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+		})
+	}
+}
+
+func newCertificate(t *testing.T, notBefore, notAfter time.Time) *tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "azure-admission-controller"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+	}
+}
@@ -0,0 +1,105 @@
+// Package readiness serves the readiness endpoint, which only reports ready
+// once everything needed to answer admission requests quickly and from fresh
+// state is in place, so the API server does not send us requests before.
+package readiness
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+)
+
+const (
+	// checkTimeout is the time a single check is given to pass. It is well
+	// below the timeout of the readiness probe.
+	checkTimeout = 3 * time.Second
+)
+
+// Check is a single readiness check. Func returns an error describing what is
+// not ready yet.
+type Check struct {
+	Name string
+	Func func(ctx context.Context) error
+}
+
+type HandlerConfig struct {
+	Checks []Check
+	Logger micrologger.Logger
+}
+
+// Handler is a HTTP handler which runs all the readiness checks and answers
+// with 200 when all of them pass and 503 otherwise. The body lists the result
+// of every check, like the readiness endpoint of the Kubernetes API server.
+type Handler struct {
+	checks []Check
+	logger micrologger.Logger
+}
+
+func NewHandler(config HandlerConfig) (*Handler, error) {
+	if len(config.Checks) == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Checks must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	for _, check := range config.Checks {
+		if check.Name == "" {
+			return nil, microerror.Maskf(invalidConfigError, "%T.Checks[].Name must not be empty", config)
+		}
+		if check.Func == nil {
+			return nil, microerror.Maskf(invalidConfigError, "%T.Checks[%q].Func must not be empty", config, check.Name)
+		}
+	}
+
+	h := &Handler{
+		checks: config.Checks,
+		logger: config.Logger,
+	}
+
+	return h, nil
+}
+
+func (h *Handler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	ready := true
+	var body strings.Builder
+	for _, check := range h.checks {
+		err := h.run(request.Context(), check)
+		if err != nil {
+			ready = false
+			h.logger.LogCtx(request.Context(), "level", "warning", "message", fmt.Sprintf("readiness check %q failed", check.Name), "stack", microerror.JSON(err))
+			fmt.Fprintf(&body, "[-]%s failed: %s\n", check.Name, err.Error())
+			continue
+		}
+
+		fmt.Fprintf(&body, "[+]%s ok\n", check.Name)
+	}
+
+	if ready {
+		writer.WriteHeader(http.StatusOK)
+	} else {
+		writer.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	_, err := writer.Write([]byte(body.String()))
+	if err != nil {
+		h.logger.LogCtx(request.Context(), "level", "error", "message", "unable to write readiness response", "stack", microerror.JSON(err))
+	}
+}
+
+func (h *Handler) run(ctx context.Context, check Check) error {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	err := check.Func(ctx)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package readiness
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+)
+
+func Test_Handler(t *testing.T) {
+	passing := Check{
+		Name: "passing",
+		Func: func(ctx context.Context) error { return nil },
+	}
+	failing := Check{
+		Name: "failing",
+		Func: func(ctx context.Context) error { return errors.New("not synced") },
+	}
+
+	testCases := []struct {
+		name           string
+		checks         []Check
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "case 0: all checks pass",
+			checks:         []Check{passing},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "[+]passing ok\n",
+		},
+		{
+			name:           "case 1: a check fails",
+			checks:         []Check{passing, failing},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   "[+]passing ok\n[-]failing failed: not synced\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			logger, err := micrologger.New(micrologger.Config{})
+			if err != nil {
+				t.Fatal(microerror.JSON(err))
+			}
+
+			handler, err := NewHandler(HandlerConfig{
+				Checks: tc.checks,
+				Logger: logger,
+			})
+			if err != nil {
+				t.Fatal(microerror.JSON(err))
+			}
+
+			request, err := http.NewRequest("GET", "/readyz", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			if recorder.Code != tc.expectedStatus {
+				t.Fatalf("status == %d, want %d", recorder.Code, tc.expectedStatus)
+			}
+			if recorder.Body.String() != tc.expectedBody {
+				t.Fatalf("body == %q, want %q", recorder.Body.String(), tc.expectedBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package recorder
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var invalidRecordError = &microerror.Error{
+	Kind: "invalidRecordError",
+}
+
+// IsInvalidRecord asserts invalidRecordError.
+func IsInvalidRecord(err error) bool {
+	return microerror.Cause(err) == invalidRecordError
+}
@@ -0,0 +1,171 @@
+// Package recorder records the admission traffic of the webhooks, i.e. the
+// AdmissionReview requests the API server sends and the responses they are
+// answered with, as JSON lines. The recorded traffic can be replayed against
+// another build of the webhook handlers to find the decisions and patches
+// which changed.
+package recorder
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+)
+
+// Record is a single admission request and the response it was answered
+// with.
+type Record struct {
+	Time time.Time `json:"time"`
+	// Path is the path of the webhook the request was sent to, e.g.
+	// "/validate/azuremachinepool/update".
+	Path string `json:"path"`
+	// Request is the AdmissionReview sent by the API server, with user names
+	// and secrets redacted, see Redact.
+	Request json.RawMessage `json:"request"`
+	// Response is the AdmissionReview the request was answered with.
+	Response json.RawMessage `json:"response"`
+}
+
+type Config struct {
+	Logger micrologger.Logger
+	Writer io.Writer
+}
+
+// Recorder writes records to a writer, one JSON object per line. It is safe
+// for concurrent use.
+type Recorder struct {
+	logger micrologger.Logger
+
+	mutex   sync.Mutex
+	encoder *json.Encoder
+}
+
+func New(config Config) (*Recorder, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.Writer == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Writer must not be empty", config)
+	}
+
+	r := &Recorder{
+		logger: config.Logger,
+
+		encoder: json.NewEncoder(config.Writer),
+	}
+
+	return r, nil
+}
+
+// Middleware returns a HTTP handler which calls next and records the requests
+// sent to the webhooks, i.e. to the paths under /mutate/ and /validate/, and
+// their responses. Requests which can't be recorded are served anyway.
+func (r *Recorder) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if !isWebhookPath(request.URL.Path) || request.Body == nil {
+			next.ServeHTTP(writer, request)
+			return
+		}
+
+		data, err := ioutil.ReadAll(request.Body)
+		if err != nil {
+			r.logger.LogCtx(request.Context(), "level", "error", "message", "unable to read request")
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		request.Body = ioutil.NopCloser(bytes.NewReader(data))
+
+		w := &responseWriter{ResponseWriter: writer}
+		next.ServeHTTP(w, request)
+
+		err = r.record(request.URL.Path, data, w.body.Bytes())
+		if err != nil {
+			r.logger.LogCtx(request.Context(), "level", "warning", "message", "unable to record admission request", "path", request.URL.Path, "stack", microerror.JSON(err))
+		}
+	})
+}
+
+func (r *Recorder) record(path string, request, response []byte) error {
+	redacted, err := Redact(request)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	record := Record{
+		Time:     time.Now().UTC(),
+		Path:     path,
+		Request:  redacted,
+		Response: response,
+	}
+	// Responses which are not JSON, e.g. empty ones after an internal error,
+	// are recorded as null.
+	if !json.Valid(response) {
+		record.Response = nil
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	err = r.encoder.Encode(record)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// Load reads the records of the file at the specified path.
+func Load(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	// Records carry whole objects, they don't fit in the default buffer.
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var record Record
+		err = json.Unmarshal(scanner.Bytes(), &record)
+		if err != nil {
+			return nil, microerror.Maskf(invalidRecordError, "%s:%d: %s", path, line, err.Error())
+		}
+		records = append(records, record)
+	}
+	err = scanner.Err()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return records, nil
+}
+
+func isWebhookPath(path string) bool {
+	return strings.HasPrefix(path, "/mutate/") || strings.HasPrefix(path, "/validate/")
+}
+
+// responseWriter keeps a copy of the response body.
+type responseWriter struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
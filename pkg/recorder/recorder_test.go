@@ -0,0 +1,202 @@
+package recorder
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+)
+
+const (
+	review   = `{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview","request":{"uid":"abc","userInfo":{"username":"jane@acme.com","groups":["customer:admins"]}}}`
+	response = `{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview","response":{"uid":"abc","allowed":true}}`
+)
+
+func Test_Middleware(t *testing.T) {
+	testCases := []struct {
+		name     string
+		path     string
+		recorded bool
+	}{
+		{
+			name:     "case 0: validating webhook",
+			path:     "/validate/azuremachinepool/update",
+			recorded: true,
+		},
+		{
+			name:     "case 1: mutating webhook",
+			path:     "/mutate/cluster/create",
+			recorded: true,
+		},
+		{
+			name: "case 2: metrics",
+			path: "/metrics",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			logger, err := micrologger.New(micrologger.Config{IOWriter: ioutil.Discard})
+			if err != nil {
+				t.Fatal(microerror.JSON(err))
+			}
+
+			var buffer bytes.Buffer
+			r, err := New(Config{
+				Logger: logger,
+				Writer: &buffer,
+			})
+			if err != nil {
+				t.Fatal(microerror.JSON(err))
+			}
+
+			handler := r.Middleware(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				// The request must be passed on untouched.
+				body, err := ioutil.ReadAll(request.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(body) != review {
+					t.Fatalf("request body == %q, want %q", body, review)
+				}
+
+				_, _ = writer.Write([]byte(response))
+			}))
+
+			writer := httptest.NewRecorder()
+			handler.ServeHTTP(writer, httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(review)))
+
+			if writer.Body.String() != response {
+				t.Fatalf("response == %q, want %q", writer.Body.String(), response)
+			}
+
+			if !tc.recorded {
+				if buffer.Len() != 0 {
+					t.Fatalf("recorded %q, want nothing", buffer.String())
+				}
+				return
+			}
+
+			var record Record
+			err = json.Unmarshal(buffer.Bytes(), &record)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if record.Path != tc.path {
+				t.Fatalf("Path == %q, want %q", record.Path, tc.path)
+			}
+			if strings.Contains(string(record.Request), "jane") {
+				t.Fatalf("Request == %s, want user name redacted", record.Request)
+			}
+			if string(record.Response) != response {
+				t.Fatalf("Response == %s, want %s", record.Response, response)
+			}
+		})
+	}
+}
+
+func Test_Redact(t *testing.T) {
+	testCases := []struct {
+		name         string
+		review       string
+		expected     string
+		errorMatcher func(error) bool
+	}{
+		{
+			name:     "case 0: user name, uid and extra are redacted, groups are kept",
+			review:   `{"request":{"userInfo":{"username":"jane@acme.com","uid":"123","groups":["customer:admins"],"extra":{"scopes":["a"]}}}}`,
+			expected: `{"request":{"userInfo":{"groups":["customer:admins"],"username":"REDACTED"}}}`,
+		},
+		{
+			name:     "case 1: secret data is redacted",
+			review:   `{"request":{"object":{"kind":"Secret","data":{"password":"c2VjcmV0"},"stringData":{"key":"value"}}}}`,
+			expected: `{"request":{"object":{"data":{"password":"REDACTED"},"kind":"Secret","stringData":{"key":"REDACTED"}}}}`,
+		},
+		{
+			name:     "case 2: fields named like secrets are redacted",
+			review:   `{"request":{"oldObject":{"kind":"AzureConfig","spec":{"azure":{"credentialSecret":{"name":"credential-default"},"clientSecret":"s3cr3t"},"vmSize":"Standard_D4s_v3"}}}}`,
+			expected: `{"request":{"oldObject":{"kind":"AzureConfig","spec":{"azure":{"clientSecret":"REDACTED","credentialSecret":{"name":"credential-default"}},"vmSize":"Standard_D4s_v3"}}}}`,
+		},
+		{
+			name:     "case 3: last applied configuration is redacted",
+			review:   `{"request":{"object":{"kind":"Cluster","metadata":{"annotations":{"kubectl.kubernetes.io/last-applied-configuration":"{}","giantswarm.io/docs":"https://docs.giantswarm.io"}}}}}`,
+			expected: `{"request":{"object":{"kind":"Cluster","metadata":{"annotations":{"giantswarm.io/docs":"https://docs.giantswarm.io","kubectl.kubernetes.io/last-applied-configuration":"REDACTED"}}}}}`,
+		},
+		{
+			name:         "case 4: no request",
+			review:       `{"response":{}}`,
+			errorMatcher: IsInvalidRecord,
+		},
+		{
+			name:         "case 5: invalid JSON",
+			review:       `{`,
+			errorMatcher: IsInvalidRecord,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			redacted, err := Redact([]byte(tc.review))
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if tc.errorMatcher != nil {
+				return
+			}
+
+			if string(redacted) != tc.expected {
+				t.Fatalf("Redact() == %s, want %s", redacted, tc.expected)
+			}
+		})
+	}
+}
+
+func Test_Load(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "records.jsonl")
+	data := `{"path":"/validate/cluster/create","request":` + review + `,"response":` + response + "}\n\n" +
+		`{"path":"/mutate/cluster/create","request":` + review + `,"response":null}` + "\n"
+	err := ioutil.WriteFile(path, []byte(data), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := Load(path)
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+
+	var paths []string
+	for _, r := range records {
+		paths = append(paths, r.Path)
+	}
+	expected := []string{"/validate/cluster/create", "/mutate/cluster/create"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Fatalf("paths == %#v, want %#v", paths, expected)
+	}
+
+	err = ioutil.WriteFile(path, []byte("{\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Load(path)
+	if !IsInvalidRecord(err) {
+		t.Fatalf("error == %#v, want invalidRecordError", err)
+	}
+}
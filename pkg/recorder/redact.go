@@ -0,0 +1,115 @@
+package recorder
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+)
+
+const (
+	// Redacted replaces redacted values.
+	Redacted = "REDACTED"
+
+	lastAppliedConfigurationAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+)
+
+// sensitiveKeys are the parts of the names of the object fields whose string
+// values are redacted, compared in lower case.
+var sensitiveKeys = []string{
+	"credential",
+	"password",
+	"privatekey",
+	"secret",
+	"token",
+}
+
+// Redact returns the specified AdmissionReview with the user name, UID and
+// extra attributes of the requester, and the secrets of the objects redacted.
+// Groups are kept, decisions depend on them. Secrets are the data of Secret
+// objects and the string values of fields named like a secret, e.g.
+// "clientSecret". The last applied configuration annotation set by kubectl is
+// redacted as a whole, it may contain any of them.
+func Redact(review []byte) ([]byte, error) {
+	var r map[string]interface{}
+	err := json.Unmarshal(review, &r)
+	if err != nil {
+		return nil, microerror.Maskf(invalidRecordError, "%s", err.Error())
+	}
+
+	request, ok := r["request"].(map[string]interface{})
+	if !ok {
+		return nil, microerror.Maskf(invalidRecordError, "AdmissionReview has no request")
+	}
+
+	if userInfo, ok := request["userInfo"].(map[string]interface{}); ok {
+		if _, ok := userInfo["username"]; ok {
+			userInfo["username"] = Redacted
+		}
+		delete(userInfo, "uid")
+		delete(userInfo, "extra")
+	}
+
+	for _, key := range []string{"object", "oldObject"} {
+		if object, ok := request[key].(map[string]interface{}); ok {
+			redactObject(object)
+		}
+	}
+
+	redacted, err := json.Marshal(r)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return redacted, nil
+}
+
+func redactObject(object map[string]interface{}) {
+	if object["kind"] == "Secret" {
+		for _, key := range []string{"data", "stringData"} {
+			if data, ok := object[key].(map[string]interface{}); ok {
+				for k := range data {
+					data[k] = Redacted
+				}
+			}
+		}
+	}
+
+	if metadata, ok := object["metadata"].(map[string]interface{}); ok {
+		if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+			if _, ok := annotations[lastAppliedConfigurationAnnotation]; ok {
+				annotations[lastAppliedConfigurationAnnotation] = Redacted
+			}
+		}
+	}
+
+	redactFields(object)
+}
+
+func redactFields(value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if _, ok := field.(string); ok && isSensitive(key) {
+				v[key] = Redacted
+				continue
+			}
+			redactFields(field)
+		}
+	case []interface{}:
+		for _, item := range v {
+			redactFields(item)
+		}
+	}
+}
+
+func isSensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, k := range sensitiveKeys {
+		if strings.Contains(key, k) {
+			return true
+		}
+	}
+
+	return false
+}
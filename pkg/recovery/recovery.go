@@ -0,0 +1,142 @@
+// Package recovery provides the HTTP middleware which turns panics of webhook
+// handlers into admission denials, so that the API server gets a well-formed
+// response instead of a dropped connection.
+package recovery
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/giantswarm/azure-admission-controller/internal/admissionreview"
+	"github.com/giantswarm/azure-admission-controller/pkg/audit"
+	"github.com/giantswarm/azure-admission-controller/pkg/metrics"
+)
+
+// Middleware returns a HTTP handler which calls next and recovers when it
+// panics. The panic is logged with its stack and the UID of the admission
+// request, counted in the metrics, and the request is denied with an
+// InternalError status, unless next already wrote a response. The denial is
+// recorded in the specified audit log with the decision "error". It refers to
+// the UID of the request, the panic itself is only logged, so that internal
+// details are not returned to the user.
+func Middleware(logger micrologger.Logger, auditSink *audit.Sink, resource, operation, webhook string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		// The body is kept so the AdmissionReview can be decoded again to
+		// answer with the right UID and apiVersion after a panic.
+		var data []byte
+		if request.Body != nil {
+			var err error
+			data, err = ioutil.ReadAll(request.Body)
+			if err != nil {
+				logger.LogCtx(request.Context(), "level", "error", "message", "unable to read request")
+				writer.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			request.Body = ioutil.NopCloser(bytes.NewReader(data))
+		}
+
+		w := &responseWriter{ResponseWriter: writer}
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			// http.ErrAbortHandler is the way to abort a response on purpose
+			// and net/http does not log it, keep it that way.
+			if r == http.ErrAbortHandler {
+				panic(r)
+			}
+
+			metrics.ObservePanic(resource, operation, webhook)
+			response := recoverPanic(request.Context(), logger, w, data, r)
+			if response != nil {
+				entry := audit.NewEntry(resource, webhook, operation, metrics.OutcomeError, response.request, response.response)
+				err := auditSink.Record(entry)
+				if err != nil {
+					logger.LogCtx(request.Context(), "level", "error", "message", "unable to write audit log entry", "stack", microerror.JSON(err))
+				}
+			}
+		}()
+
+		next.ServeHTTP(w, request)
+	})
+}
+
+// panicResponse is the denial of an admission request whose handler panicked.
+type panicResponse struct {
+	request  *admissionv1.AdmissionRequest
+	response *admissionv1.AdmissionResponse
+}
+
+// recoverPanic logs the panic and denies the request, when it is not too late
+// to answer. It returns the denial, or nil when the request was not answered
+// with an AdmissionReview.
+func recoverPanic(ctx context.Context, logger micrologger.Logger, writer *responseWriter, data []byte, r interface{}) *panicResponse {
+	var uid types.UID
+	admissionRequest, version, err := admissionreview.Decode(data)
+	if err == nil {
+		uid = admissionRequest.UID
+	}
+
+	logger.LogCtx(ctx, "level", "error", "message", fmt.Sprintf("webhook handler panicked: %v", r), "uid", string(uid), "stack", string(debug.Stack()))
+
+	if writer.written {
+		// Too late to answer, the API server gets whatever was written.
+		return nil
+	}
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		return nil
+	}
+
+	response := &admissionv1.AdmissionResponse{
+		Allowed: false,
+		UID:     uid,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Reason:  metav1.StatusReasonInternalError,
+			Code:    http.StatusInternalServerError,
+			Message: fmt.Sprintf("internal error while handling the admission request with UID %s", uid),
+		},
+	}
+
+	resp, err := admissionreview.Encode(version, response, nil)
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		return nil
+	}
+
+	_, err = writer.Write(resp)
+	if err != nil {
+		logger.LogCtx(ctx, "level", "error", "message", "unable to write response")
+	}
+
+	return &panicResponse{request: admissionRequest, response: response}
+}
+
+// responseWriter records whether anything was written to the response, in
+// which case it is too late to answer with a denial.
+type responseWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (w *responseWriter) WriteHeader(statusCode int) {
+	w.written = true
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,146 @@
+package recovery
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/azure-admission-controller/internal/admissionreview"
+	"github.com/giantswarm/azure-admission-controller/pkg/audit"
+	"github.com/giantswarm/azure-admission-controller/pkg/metrics"
+)
+
+func Test_Middleware(t *testing.T) {
+	testCases := []struct {
+		name               string
+		version            string
+		handler            http.HandlerFunc
+		expectedStatusCode int
+		expectedAllowed    bool
+		expectedReason     metav1.StatusReason
+		// expectedAudit is the decision of the expected audit log entry,
+		// none is expected when it is empty.
+		expectedAudit string
+	}{
+		{
+			name:    "case 0: handler does not panic",
+			version: admissionreview.V1,
+			handler: func(writer http.ResponseWriter, request *http.Request) {
+				_, _ = writer.Write([]byte(`{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview","response":{"uid":"abc","allowed":true}}`))
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedAllowed:    true,
+		},
+		{
+			name:    "case 1: handler panics with v1 review",
+			version: admissionreview.V1,
+			handler: func(writer http.ResponseWriter, request *http.Request) {
+				var port *int32
+				_ = *port
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedReason:     metav1.StatusReasonInternalError,
+			expectedAudit:      metrics.OutcomeError,
+		},
+		{
+			name:    "case 2: handler panics with v1beta1 review",
+			version: admissionreview.V1beta1,
+			handler: func(writer http.ResponseWriter, request *http.Request) {
+				panic("boom")
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedReason:     metav1.StatusReasonInternalError,
+			expectedAudit:      metrics.OutcomeError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			logger, err := micrologger.New(micrologger.Config{})
+			if err != nil {
+				t.Fatal(microerror.JSON(err))
+			}
+
+			review := admissionv1beta1.AdmissionReview{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: tc.version,
+					Kind:       "AdmissionReview",
+				},
+				Request: &admissionv1beta1.AdmissionRequest{
+					UID:       "abc",
+					Operation: admissionv1beta1.Update,
+				},
+			}
+			body, err := json.Marshal(review)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			request, err := http.NewRequest("POST", "", bytes.NewReader(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			request.Header.Set("Content-Type", "application/json")
+
+			var auditLog bytes.Buffer
+			auditSink, err := audit.NewSink(audit.SinkConfig{Writer: &auditLog})
+			if err != nil {
+				t.Fatal(microerror.JSON(err))
+			}
+
+			recorder := httptest.NewRecorder()
+			Middleware(logger, auditSink, "azurecluster", metrics.OperationUpdate, metrics.WebhookValidate, tc.handler).ServeHTTP(recorder, request)
+
+			if recorder.Code != tc.expectedStatusCode {
+				t.Fatalf("status code == %d, want %d", recorder.Code, tc.expectedStatusCode)
+			}
+
+			var response admissionv1.AdmissionReview
+			err = json.Unmarshal(recorder.Body.Bytes(), &response)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if response.APIVersion != tc.version {
+				t.Fatalf("apiVersion == %q, want %q", response.APIVersion, tc.version)
+			}
+			if response.Response == nil {
+				t.Fatalf("response == nil, want non-nil")
+			}
+			if response.Response.UID != "abc" {
+				t.Fatalf("uid == %q, want %q", response.Response.UID, "abc")
+			}
+			if response.Response.Allowed != tc.expectedAllowed {
+				t.Fatalf("allowed == %t, want %t", response.Response.Allowed, tc.expectedAllowed)
+			}
+			if !tc.expectedAllowed && response.Response.Result.Reason != tc.expectedReason {
+				t.Fatalf("reason == %q, want %q", response.Response.Result.Reason, tc.expectedReason)
+			}
+			// The panic is not returned to the user.
+			if !tc.expectedAllowed && response.Response.Result.Message != "internal error while handling the admission request with UID abc" {
+				t.Fatalf("message == %q, want the UID only", response.Response.Result.Message)
+			}
+
+			var decision string
+			if auditLog.Len() > 0 {
+				var entry audit.Entry
+				err = json.Unmarshal(auditLog.Bytes(), &entry)
+				if err != nil {
+					t.Fatal(err)
+				}
+				decision = entry.Decision
+			}
+			if decision != tc.expectedAudit {
+				t.Fatalf("audit decision == %q, want %q", decision, tc.expectedAudit)
+			}
+		})
+	}
+}
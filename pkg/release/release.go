@@ -13,6 +13,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/giantswarm/azure-admission-controller/pkg/generic"
+	"github.com/giantswarm/azure-admission-controller/pkg/tracing"
 )
 
 const (
@@ -65,13 +66,19 @@ func FindRelease(ctx context.Context, ctrlReader client.Reader, releaseVersion s
 		releaseVersion = fmt.Sprintf("v%s", releaseVersion)
 	}
 
+	ctx, span := tracing.Start(ctx, "release.FindRelease", tracing.String("release", releaseVersion))
+	defer span.End()
+
 	// Retrieve the `Release` CR.
 	release := releasev1alpha1.Release{}
 	{
 		err := ctrlReader.Get(ctx, client.ObjectKey{Name: releaseVersion}, &release)
 		if apierrors.IsNotFound(err) {
-			return releasev1alpha1.Release{}, microerror.Maskf(ReleaseNotFoundError, "Looking for Release %s but it was not found. Can't continue.", releaseVersion)
+			err = microerror.Maskf(ReleaseNotFoundError, "Looking for Release %s but it was not found. Can't continue.", releaseVersion)
+			span.RecordError(err)
+			return releasev1alpha1.Release{}, err
 		} else if err != nil {
+			span.RecordError(err)
 			return releasev1alpha1.Release{}, microerror.Mask(err)
 		}
 	}
@@ -0,0 +1,132 @@
+// Package requestcache memoizes the Kubernetes reads of an admission request.
+//
+// Validating and mutating an object often reads the same objects several
+// times, e.g. the owner Cluster is read to find the release of the object,
+// and then again by the checks and the mutations comparing labels with it.
+// NewReader returns a client.Reader which reads every object and list at most
+// once for a context carrying a cache created with NewContext, and hands out
+// copies of the first result to later reads. Reads with other contexts are
+// passed through, so the same reader can be used for background work.
+package requestcache
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type cacheKey struct{}
+
+type cache struct {
+	mutex   sync.Mutex
+	entries map[string]*entry
+}
+
+// entry is the result of a read. Concurrent reads of the same key, e.g. of a
+// live and a candidate handler in shadow mode, wait for the first one to be
+// done instead of reading again.
+type entry struct {
+	done   chan struct{}
+	object runtime.Object
+	err    error
+}
+
+// NewContext returns a copy of the specified context carrying an empty cache
+// for the reads of one admission request.
+func NewContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheKey{}, &cache{entries: map[string]*entry{}})
+}
+
+func fromContext(ctx context.Context) *cache {
+	c, _ := ctx.Value(cacheKey{}).(*cache)
+	return c
+}
+
+// NewReader returns a client.Reader which memoizes the reads of the specified
+// reader in the cache carried by the context of every call. Objects which are
+// not found are memoized too, other errors are not.
+func NewReader(r client.Reader) client.Reader {
+	return &reader{reader: r}
+}
+
+type reader struct {
+	reader client.Reader
+}
+
+func (r *reader) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	k := fmt.Sprintf("get %s %s", typeKey(obj), key)
+	return r.read(ctx, k, obj, func() error {
+		return r.reader.Get(ctx, key, obj)
+	})
+}
+
+func (r *reader) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	o := (&client.ListOptions{}).ApplyOptions(opts)
+	var labelSelector, fieldSelector string
+	if o.LabelSelector != nil {
+		labelSelector = o.LabelSelector.String()
+	}
+	if o.FieldSelector != nil {
+		fieldSelector = o.FieldSelector.String()
+	}
+
+	k := fmt.Sprintf("list %s %s/%s/%s/%d/%s", typeKey(list), o.Namespace, labelSelector, fieldSelector, o.Limit, o.Continue)
+	return r.read(ctx, k, list, func() error {
+		return r.reader.List(ctx, list, opts...)
+	})
+}
+
+// read fills obj with the memoized result of the read with the specified key,
+// and calls the specified function to fill it when there is none.
+func (r *reader) read(ctx context.Context, key string, obj runtime.Object, readFunc func() error) error {
+	c := fromContext(ctx)
+	if c == nil {
+		return readFunc()
+	}
+
+	c.mutex.Lock()
+	e, ok := c.entries[key]
+	if !ok {
+		e = &entry{done: make(chan struct{})}
+		c.entries[key] = e
+	}
+	c.mutex.Unlock()
+
+	if ok {
+		select {
+		case <-e.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		if e.object != nil && reflect.TypeOf(e.object) == reflect.TypeOf(obj) {
+			reflect.ValueOf(obj).Elem().Set(reflect.ValueOf(e.object.DeepCopyObject()).Elem())
+		}
+		return e.err
+	}
+
+	err := readFunc()
+	if err != nil && !apierrors.IsNotFound(err) {
+		// Forget the failed read, so the next one tries again.
+		c.mutex.Lock()
+		delete(c.entries, key)
+		c.mutex.Unlock()
+	} else if err == nil {
+		e.object = obj.DeepCopyObject()
+	}
+	e.err = err
+	close(e.done)
+
+	return err
+}
+
+// typeKey tells objects of different types apart, including unstructured
+// objects of different kinds.
+func typeKey(obj runtime.Object) string {
+	return fmt.Sprintf("%T %s", obj, obj.GetObjectKind().GroupVersionKind())
+}
@@ -0,0 +1,234 @@
+package requestcache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha3"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// countingReader counts the reads reaching it, and fails them with err when
+// it is set.
+type countingReader struct {
+	reader client.Reader
+	err    error
+
+	mutex sync.Mutex
+	reads int
+}
+
+func (r *countingReader) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	r.count()
+	if r.err != nil {
+		return r.err
+	}
+	return r.reader.Get(ctx, key, obj)
+}
+
+func (r *countingReader) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	r.count()
+	if r.err != nil {
+		return r.err
+	}
+	return r.reader.List(ctx, list, opts...)
+}
+
+func (r *countingReader) count() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.reads++
+}
+
+func Test_Reader(t *testing.T) {
+	testCases := []struct {
+		name string
+		// read does the reads of a request with the specified reader.
+		read          func(t *testing.T, ctx context.Context, r client.Reader)
+		err           error
+		requestCache  bool
+		expectedReads int
+	}{
+		{
+			name:         "case 0: same object read twice",
+			requestCache: true,
+			read: func(t *testing.T, ctx context.Context, r client.Reader) {
+				var cluster capi.Cluster
+				get(t, ctx, r, "org-giantswarm", "ab123", &cluster)
+				cluster.Labels["modified"] = "true"
+
+				var again capi.Cluster
+				get(t, ctx, r, "org-giantswarm", "ab123", &again)
+				if again.Name != "ab123" || again.Labels["modified"] != "" {
+					t.Fatalf("cluster == %#v, want a copy of the read cluster", again)
+				}
+			},
+			expectedReads: 1,
+		},
+		{
+			name: "case 1: no request cache",
+			read: func(t *testing.T, ctx context.Context, r client.Reader) {
+				get(t, ctx, r, "org-giantswarm", "ab123", &capi.Cluster{})
+				get(t, ctx, r, "org-giantswarm", "ab123", &capi.Cluster{})
+			},
+			expectedReads: 2,
+		},
+		{
+			name:         "case 2: different objects and types",
+			requestCache: true,
+			read: func(t *testing.T, ctx context.Context, r client.Reader) {
+				get(t, ctx, r, "org-giantswarm", "ab123", &capi.Cluster{})
+				get(t, ctx, r, "org-giantswarm", "ab123", &capi.Machine{})
+				get(t, ctx, r, "default", "ab123", &capi.Cluster{})
+				get(t, ctx, r, "org-giantswarm", "cd456", &capi.Cluster{})
+			},
+			expectedReads: 4,
+		},
+		{
+			name:         "case 3: missing object read twice",
+			requestCache: true,
+			read: func(t *testing.T, ctx context.Context, r client.Reader) {
+				for i := 0; i < 2; i++ {
+					err := r.Get(ctx, client.ObjectKey{Namespace: "org-giantswarm", Name: "missing"}, &capi.Cluster{})
+					if !apierrors.IsNotFound(err) {
+						t.Fatalf("error == %#v, want not found", err)
+					}
+				}
+			},
+			expectedReads: 1,
+		},
+		{
+			name:         "case 4: failed reads are not memoized",
+			requestCache: true,
+			err:          errors.New("connection refused"),
+			read: func(t *testing.T, ctx context.Context, r client.Reader) {
+				for i := 0; i < 2; i++ {
+					err := r.Get(ctx, client.ObjectKey{Namespace: "org-giantswarm", Name: "ab123"}, &capi.Cluster{})
+					if err == nil {
+						t.Fatal("error == nil, want connection refused")
+					}
+				}
+			},
+			expectedReads: 2,
+		},
+		{
+			name:         "case 5: lists",
+			requestCache: true,
+			read: func(t *testing.T, ctx context.Context, r client.Reader) {
+				for i := 0; i < 2; i++ {
+					var clusters capi.ClusterList
+					err := r.List(ctx, &clusters, client.MatchingLabels{"giantswarm.io/cluster": "ab123"})
+					if err != nil {
+						t.Fatal(err)
+					}
+					if len(clusters.Items) != 1 {
+						t.Fatalf("%d clusters, want 1", len(clusters.Items))
+					}
+				}
+
+				var clusters capi.ClusterList
+				err := r.List(ctx, &clusters, client.MatchingLabels{"giantswarm.io/cluster": "cd456"})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(clusters.Items) != 0 {
+					t.Fatalf("%d clusters, want 0", len(clusters.Items))
+				}
+			},
+			expectedReads: 2,
+		},
+		{
+			name:         "case 6: concurrent reads",
+			requestCache: true,
+			read: func(t *testing.T, ctx context.Context, r client.Reader) {
+				var wg sync.WaitGroup
+				for i := 0; i < 10; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						var cluster capi.Cluster
+						err := r.Get(ctx, client.ObjectKey{Namespace: "org-giantswarm", Name: "ab123"}, &cluster)
+						if err != nil || cluster.Name != "ab123" {
+							t.Errorf("cluster == %#v, error == %#v", cluster, err)
+						}
+					}()
+				}
+				wg.Wait()
+			},
+			expectedReads: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			err := capi.AddToScheme(scheme)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var objects []runtime.Object
+			for _, o := range []struct{ namespace, name string }{{"org-giantswarm", "ab123"}, {"org-giantswarm", "cd456"}, {"default", "ab123"}} {
+				objects = append(objects,
+					&capi.Cluster{ObjectMeta: metav1.ObjectMeta{Namespace: o.namespace, Name: o.name, Labels: map[string]string{"giantswarm.io/cluster": o.name + "-" + o.namespace}}},
+					&capi.Machine{ObjectMeta: metav1.ObjectMeta{Namespace: o.namespace, Name: o.name}},
+				)
+			}
+			objects[0].(*capi.Cluster).Labels["giantswarm.io/cluster"] = "ab123"
+
+			counting := &countingReader{
+				reader: fake.NewFakeClientWithScheme(scheme, objects...),
+				err:    tc.err,
+			}
+
+			ctx := context.Background()
+			if tc.requestCache {
+				ctx = NewContext(ctx)
+			}
+
+			tc.read(t, ctx, NewReader(counting))
+
+			if counting.reads != tc.expectedReads {
+				t.Fatalf("%d reads, want %d", counting.reads, tc.expectedReads)
+			}
+		})
+	}
+}
+
+func Test_NewContext(t *testing.T) {
+	scheme := runtime.NewScheme()
+	err := capi.AddToScheme(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cluster := &capi.Cluster{ObjectMeta: metav1.ObjectMeta{Namespace: "org-giantswarm", Name: "ab123"}}
+	counting := &countingReader{reader: fake.NewFakeClientWithScheme(scheme, cluster)}
+	r := NewReader(counting)
+
+	// Every request has its own cache.
+	for i := 0; i < 2; i++ {
+		ctx := NewContext(context.Background())
+		get(t, ctx, r, "org-giantswarm", "ab123", &capi.Cluster{})
+		get(t, ctx, r, "org-giantswarm", "ab123", &capi.Cluster{})
+	}
+
+	if counting.reads != 2 {
+		t.Fatalf("%d reads, want 2", counting.reads)
+	}
+}
+
+func get(t *testing.T, ctx context.Context, r client.Reader, namespace, name string, obj runtime.Object) {
+	t.Helper()
+
+	err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -16,7 +16,7 @@ func (h *WebhookHandler) OnCreateMutate(ctx context.Context, object interface{})
 		return []mutator.PatchOperation{}, microerror.Mask(err)
 	}
 
-	patch, err := mutator.EnsureReleaseVersionLabel(ctx, h.ctrlClient, sparkCR.GetObjectMeta())
+	patch, err := mutator.EnsureReleaseVersionLabel(ctx, h.ctrlReader, sparkCR.GetObjectMeta())
 	if err != nil {
 		return []mutator.PatchOperation{}, microerror.Mask(err)
 	}
@@ -0,0 +1,142 @@
+package tracing
+
+import (
+	"context"
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NewClient returns a client.Client which records a span for every call of
+// the specified client in a traced request.
+func NewClient(c client.Client) client.Client {
+	return &tracingClient{
+		tracingReader: tracingReader{reader: c},
+		client:        c,
+	}
+}
+
+// NewReader returns a client.Reader which records a span for every call of
+// the specified reader, e.g. of the controller-runtime cache, in a traced
+// request.
+func NewReader(r client.Reader) client.Reader {
+	return &tracingReader{reader: r}
+}
+
+type tracingReader struct {
+	reader client.Reader
+}
+
+func (r *tracingReader) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	ctx, span := startKubernetesSpan(ctx, "Get", obj, String("k8s.namespace", key.Namespace), String("k8s.name", key.Name))
+	defer span.End()
+
+	err := r.reader.Get(ctx, key, obj)
+	span.RecordError(err)
+
+	return err
+}
+
+func (r *tracingReader) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	ctx, span := startKubernetesSpan(ctx, "List", list, String("k8s.namespace", (&client.ListOptions{}).ApplyOptions(opts).Namespace))
+	defer span.End()
+
+	err := r.reader.List(ctx, list, opts...)
+	span.RecordError(err)
+	if err == nil {
+		span.SetAttributes(Int("k8s.items", meta.LenList(list)))
+	}
+
+	return err
+}
+
+type tracingClient struct {
+	tracingReader
+	client client.Client
+}
+
+func (c *tracingClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	ctx, span := startKubernetesSpan(ctx, "Create", obj, objectAttributes(obj)...)
+	defer span.End()
+
+	err := c.client.Create(ctx, obj, opts...)
+	span.RecordError(err)
+
+	return err
+}
+
+func (c *tracingClient) Delete(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) error {
+	ctx, span := startKubernetesSpan(ctx, "Delete", obj, objectAttributes(obj)...)
+	defer span.End()
+
+	err := c.client.Delete(ctx, obj, opts...)
+	span.RecordError(err)
+
+	return err
+}
+
+func (c *tracingClient) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	ctx, span := startKubernetesSpan(ctx, "Update", obj, objectAttributes(obj)...)
+	defer span.End()
+
+	err := c.client.Update(ctx, obj, opts...)
+	span.RecordError(err)
+
+	return err
+}
+
+func (c *tracingClient) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	ctx, span := startKubernetesSpan(ctx, "Patch", obj, objectAttributes(obj)...)
+	defer span.End()
+
+	err := c.client.Patch(ctx, obj, patch, opts...)
+	span.RecordError(err)
+
+	return err
+}
+
+func (c *tracingClient) DeleteAllOf(ctx context.Context, obj runtime.Object, opts ...client.DeleteAllOfOption) error {
+	ctx, span := startKubernetesSpan(ctx, "DeleteAllOf", obj)
+	defer span.End()
+
+	err := c.client.DeleteAllOf(ctx, obj, opts...)
+	span.RecordError(err)
+
+	return err
+}
+
+// Status updates are not traced, the webhooks don't make any.
+func (c *tracingClient) Status() client.StatusWriter {
+	return c.client.Status()
+}
+
+// startKubernetesSpan starts a span named after the verb and the kind of the
+// specified object, e.g. "kubernetes Get Release".
+func startKubernetesSpan(ctx context.Context, verb string, obj runtime.Object, attributes ...Attribute) (context.Context, *Span) {
+	if FromContext(ctx) == nil {
+		return ctx, nil
+	}
+
+	kind := obj.GetObjectKind().GroupVersionKind().Kind
+	if kind == "" {
+		kind = reflect.Indirect(reflect.ValueOf(obj)).Type().Name()
+	}
+
+	attributes = append([]Attribute{String("k8s.verb", verb), String("k8s.kind", kind)}, attributes...)
+
+	return StartClient(ctx, "kubernetes "+verb+" "+kind, attributes...)
+}
+
+func objectAttributes(obj runtime.Object) []Attribute {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return nil
+	}
+
+	return []Attribute{
+		String("k8s.namespace", accessor.GetNamespace()),
+		String("k8s.name", accessor.GetName()),
+	}
+}
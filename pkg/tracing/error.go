@@ -0,0 +1,23 @@
+package tracing
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var exportFailedError = &microerror.Error{
+	Kind: "exportFailedError",
+}
+
+// IsExportFailed asserts exportFailedError.
+func IsExportFailed(err error) bool {
+	return microerror.Cause(err) == exportFailedError
+}
@@ -0,0 +1,246 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/giantswarm/microerror"
+)
+
+// Exporter sends ended spans to a tracing backend.
+type Exporter interface {
+	Export(ctx context.Context, spans []SpanData) error
+}
+
+type OTLPExporterConfig struct {
+	// Endpoint is the base URL of the OTLP/HTTP receiver, e.g.
+	// "http://otel-collector:4318". Spans are sent to its /v1/traces path.
+	Endpoint string
+	// HTTPClient is http.DefaultClient when it is nil.
+	HTTPClient *http.Client
+	// ServiceName and ServiceVersion describe the service the spans belong
+	// to.
+	ServiceName    string
+	ServiceVersion string
+}
+
+// OTLPExporter sends spans to an OTLP/HTTP receiver, e.g. an OpenTelemetry
+// Collector, with the JSON encoding.
+type OTLPExporter struct {
+	httpClient *http.Client
+	resource   otlpResource
+	url        string
+}
+
+func NewOTLPExporter(config OTLPExporterConfig) (*OTLPExporter, error) {
+	if config.Endpoint == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Endpoint must not be empty", config)
+	}
+	if config.ServiceName == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.ServiceName must not be empty", config)
+	}
+
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	e := &OTLPExporter{
+		httpClient: httpClient,
+		resource:   newOTLPResource(config.ServiceName, config.ServiceVersion),
+		url:        strings.TrimSuffix(config.Endpoint, "/") + "/v1/traces",
+	}
+
+	return e, nil
+}
+
+func (e *OTLPExporter) Export(ctx context.Context, spans []SpanData) error {
+	body, err := json.Marshal(otlpRequest{
+		ResourceSpans: []otlpResourceSpans{
+			{
+				Resource: e.resource,
+				ScopeSpans: []otlpScopeSpans{
+					{
+						Scope: otlpScope{Name: e.resource.serviceName},
+						Spans: newOTLPSpans(spans),
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	request, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	request = request.WithContext(ctx)
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := e.httpClient.Do(request)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		message, _ := ioutil.ReadAll(io.LimitReader(response.Body, 1024))
+		return microerror.Maskf(exportFailedError, "%s answered %s: %s", e.url, response.Status, strings.TrimSpace(string(message)))
+	}
+
+	// Drain the body so the connection is reused.
+	_, _ = io.Copy(ioutil.Discard, response.Body)
+
+	return nil
+}
+
+// StdoutExporter writes spans to a writer, one OTLP JSON span per line, for
+// local debugging.
+type StdoutExporter struct {
+	mutex   sync.Mutex
+	encoder *json.Encoder
+}
+
+func NewStdoutExporter(writer io.Writer) *StdoutExporter {
+	return &StdoutExporter{
+		encoder: json.NewEncoder(writer),
+	}
+}
+
+func (e *StdoutExporter) Export(_ context.Context, spans []SpanData) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	for _, span := range newOTLPSpans(spans) {
+		err := e.encoder.Encode(span)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// The following types are the JSON encoding of the OTLP
+// ExportTraceServiceRequest message. Trace and span IDs are hex encoded and
+// 64 bit integers are strings, see
+// https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding.
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+
+	serviceName string
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              SpanKind       `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+	BoolValue   *bool   `json:"boolValue,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    StatusCode `json:"code,omitempty"`
+	Message string     `json:"message,omitempty"`
+}
+
+func newOTLPResource(serviceName, serviceVersion string) otlpResource {
+	attributes := []Attribute{String("service.name", serviceName)}
+	if serviceVersion != "" {
+		attributes = append(attributes, String("service.version", serviceVersion))
+	}
+
+	return otlpResource{
+		Attributes:  newOTLPKeyValues(attributes),
+		serviceName: serviceName,
+	}
+}
+
+func newOTLPSpans(spans []SpanData) []otlpSpan {
+	result := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		span := otlpSpan{
+			TraceID:           s.TraceID.String(),
+			SpanID:            s.SpanID.String(),
+			Name:              s.Name,
+			Kind:              s.Kind,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+			Attributes:        newOTLPKeyValues(s.Attributes),
+			Status: otlpStatus{
+				Code:    s.StatusCode,
+				Message: s.StatusMessage,
+			},
+		}
+		if s.ParentSpanID.IsValid() {
+			span.ParentSpanID = s.ParentSpanID.String()
+		}
+
+		result = append(result, span)
+	}
+
+	return result
+}
+
+func newOTLPKeyValues(attributes []Attribute) []otlpKeyValue {
+	var result []otlpKeyValue
+	for _, a := range attributes {
+		value := a.Value
+		kv := otlpKeyValue{Key: a.Key}
+		switch a.Type {
+		case AttributeTypeInt:
+			kv.Value.IntValue = &value
+		case AttributeTypeBool:
+			b := value == "true"
+			kv.Value.BoolValue = &b
+		default:
+			kv.Value.StringValue = &value
+		}
+
+		result = append(result, kv)
+	}
+
+	return result
+}
@@ -0,0 +1,60 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// TraceparentHeader is the W3C Trace Context header carrying the trace and
+// the span a request belongs to, e.g.
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+const TraceparentHeader = "traceparent"
+
+// SpanContext identifies a span of another service.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+}
+
+type remoteParentKey struct{}
+
+// Extract returns the span context of the traceparent header of the
+// specified HTTP header, and false when there is none or it is invalid.
+func Extract(header http.Header) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(header.Get(TraceparentHeader)), "-")
+	// Future versions may append fields, version ff is invalid.
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return SpanContext{}, false
+	}
+
+	var sc SpanContext
+	if !decodeID(parts[1], sc.TraceID[:]) || !decodeID(parts[2], sc.SpanID[:]) {
+		return SpanContext{}, false
+	}
+	if sc.TraceID == (TraceID{}) || !sc.SpanID.IsValid() {
+		return SpanContext{}, false
+	}
+
+	return sc, true
+}
+
+func withRemoteParent(ctx context.Context, parent SpanContext) context.Context {
+	return context.WithValue(ctx, remoteParentKey{}, parent)
+}
+
+func remoteParent(ctx context.Context) (SpanContext, bool) {
+	parent, ok := ctx.Value(remoteParentKey{}).(SpanContext)
+	return parent, ok
+}
+
+func decodeID(s string, id []byte) bool {
+	// Upper case hex digits are invalid.
+	if len(s) != hex.EncodedLen(len(id)) || strings.ToLower(s) != s {
+		return false
+	}
+
+	_, err := hex.Decode(id, []byte(s))
+	return err == nil
+}
@@ -0,0 +1,203 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SpanKind tells whether a span is an operation of the webhooks or a call to
+// another service, with the values of the OTLP SpanKind enum.
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+)
+
+// StatusCode is the status of a span, with the values of the OTLP StatusCode
+// enum.
+type StatusCode int
+
+const (
+	StatusUnset StatusCode = 0
+	StatusError StatusCode = 2
+)
+
+type TraceID [16]byte
+
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+type SpanID [8]byte
+
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+// Attribute is a key and a string, integer or boolean value describing a
+// span, e.g. the name of the object a Kubernetes API call reads.
+type Attribute struct {
+	Key   string
+	Value string
+	Type  AttributeType
+}
+
+type AttributeType int
+
+const (
+	AttributeTypeString AttributeType = iota
+	AttributeTypeInt
+	AttributeTypeBool
+)
+
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value, Type: AttributeTypeString}
+}
+
+func Int(key string, value int) Attribute {
+	return Attribute{Key: key, Value: strconv.Itoa(value), Type: AttributeTypeInt}
+}
+
+func Bool(key string, value bool) Attribute {
+	return Attribute{Key: key, Value: strconv.FormatBool(value), Type: AttributeTypeBool}
+}
+
+// SpanData is an ended span as it is exported.
+type SpanData struct {
+	TraceID TraceID
+	SpanID  SpanID
+	// ParentSpanID is invalid for the root span of a trace.
+	ParentSpanID  SpanID
+	Name          string
+	Kind          SpanKind
+	Start         time.Time
+	End           time.Time
+	Attributes    []Attribute
+	StatusCode    StatusCode
+	StatusMessage string
+}
+
+// Span is an operation being traced. Its methods may be called concurrently,
+// and do nothing on a nil span, which Start returns when the context carries
+// no span.
+type Span struct {
+	tracer *Tracer
+
+	mutex sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+type spanKey struct{}
+
+// Start starts a span as a child of the span carried by the specified
+// context. It returns the context unchanged and a nil span when the context
+// carries no span, i.e. when the request is not traced.
+func Start(ctx context.Context, name string, attributes ...Attribute) (context.Context, *Span) {
+	return start(ctx, name, SpanKindInternal, attributes)
+}
+
+// StartClient is like Start for spans of calls to other services, e.g. to the
+// Kubernetes or the Azure API.
+func StartClient(ctx context.Context, name string, attributes ...Attribute) (context.Context, *Span) {
+	return start(ctx, name, SpanKindClient, attributes)
+}
+
+func start(ctx context.Context, name string, kind SpanKind, attributes []Attribute) (context.Context, *Span) {
+	parent := FromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+
+	return parent.tracer.start(ctx, name, kind, attributes)
+}
+
+// FromContext returns the span carried by the specified context, or nil.
+func FromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// SetAttributes adds the specified attributes to the span.
+func (s *Span) SetAttributes(attributes ...Attribute) {
+	if s == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.data.Attributes = append(s.data.Attributes, attributes...)
+}
+
+// SetStatus sets the status of the span.
+func (s *Span) SetStatus(code StatusCode, message string) {
+	if s == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.data.StatusCode = code
+	s.data.StatusMessage = message
+}
+
+// RecordError marks the span as failed with the message of the specified
+// error. Nil errors are ignored, so the result of a call can be recorded
+// directly.
+func (s *Span) RecordError(err error) {
+	if err == nil {
+		return
+	}
+
+	s.SetStatus(StatusError, err.Error())
+}
+
+// End ends the span and queues it for export. Only the first call has an
+// effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	if s.ended {
+		s.mutex.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mutex.Unlock()
+
+	s.tracer.enqueue(data)
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	randomID(id[:])
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	randomID(id[:])
+	return id
+}
+
+func randomID(id []byte) {
+	// crypto/rand doesn't fail on the supported platforms. An ID of zeros is
+	// invalid, so a failure would show in the exported spans.
+	_, _ = rand.Read(id)
+}
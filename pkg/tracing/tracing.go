@@ -0,0 +1,207 @@
+// Package tracing records spans of the admission chain, i.e. of the HTTP
+// request, the legacy release filter, the named validation checks and the
+// Kubernetes and Azure API calls, and exports them with the OTLP/HTTP JSON
+// encoding, e.g. to an OpenTelemetry Collector, or to stdout.
+//
+// Spans are carried by context.Context. A Tracer starts the root span of a
+// request in Middleware, continuing the trace of a W3C traceparent header when
+// there is one, and Start adds child spans to the span of the context. Start
+// does nothing when the context carries no span, so code which is called with
+// and without tracing needs no checks.
+//
+// The OpenTelemetry Go SDK requires go-logr/logr v1, which controller-runtime
+// v0.6 is not compatible with, so this package implements the small part of
+// it the webhooks need.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+)
+
+const (
+	// exportInterval is how often Run exports the ended spans.
+	exportInterval = 5 * time.Second
+	// exportTimeout is how long a single export may take.
+	exportTimeout = 10 * time.Second
+	// maxQueueSize is the number of ended spans kept until they are
+	// exported. More spans are dropped, so that a slow or unreachable
+	// collector can't make the webhooks run out of memory.
+	maxQueueSize = 4096
+)
+
+type Config struct {
+	Exporter Exporter
+	Logger   micrologger.Logger
+}
+
+// Tracer starts root spans and exports ended spans in batches. It is safe for
+// concurrent use.
+type Tracer struct {
+	exporter Exporter
+	logger   micrologger.Logger
+
+	mutex   sync.Mutex
+	queue   []SpanData
+	dropped int
+}
+
+func New(config Config) (*Tracer, error) {
+	if config.Exporter == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Exporter must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	t := &Tracer{
+		exporter: config.Exporter,
+		logger:   config.Logger,
+	}
+
+	return t, nil
+}
+
+// Start starts a span as a child of the span carried by the specified
+// context, or of the remote parent extracted by Middleware, or else as the
+// root of a new trace. The returned context carries the new span, which must
+// be ended with End.
+func (t *Tracer) Start(ctx context.Context, name string, attributes ...Attribute) (context.Context, *Span) {
+	return t.start(ctx, name, SpanKindInternal, attributes)
+}
+
+// Middleware returns a HTTP handler which calls next with a span for every
+// request sent to the webhooks, i.e. to the paths under /mutate/ and
+// /validate/. Health checks and metrics scrapes are not traced.
+func (t *Tracer) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if !isWebhookPath(request.URL.Path) {
+			next.ServeHTTP(writer, request)
+			return
+		}
+
+		ctx := request.Context()
+		if parent, ok := Extract(request.Header); ok {
+			ctx = withRemoteParent(ctx, parent)
+		}
+
+		attributes := []Attribute{
+			String("http.method", request.Method),
+			String("http.target", request.URL.Path),
+		}
+		ctx, span := t.start(ctx, fmt.Sprintf("%s %s", request.Method, request.URL.Path), SpanKindServer, attributes)
+		defer span.End()
+
+		statusWriter := &statusWriter{ResponseWriter: writer, status: http.StatusOK}
+		next.ServeHTTP(statusWriter, request.WithContext(ctx))
+
+		span.SetAttributes(Int("http.status_code", statusWriter.status))
+		if statusWriter.status >= http.StatusInternalServerError {
+			span.SetStatus(StatusError, http.StatusText(statusWriter.status))
+		}
+	})
+}
+
+// Run exports the ended spans periodically until the specified context is
+// done. Call Flush afterwards to export the remaining spans.
+func (t *Tracer) Run(ctx context.Context) {
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			exportCtx, cancel := context.WithTimeout(ctx, exportTimeout)
+			err := t.Flush(exportCtx)
+			cancel()
+			if err != nil {
+				t.logger.LogCtx(ctx, "level", "warning", "message", "unable to export spans", "stack", microerror.JSON(err))
+			}
+		}
+	}
+}
+
+// Flush exports the ended spans which were not exported yet.
+func (t *Tracer) Flush(ctx context.Context) error {
+	t.mutex.Lock()
+	spans, dropped := t.queue, t.dropped
+	t.queue, t.dropped = nil, 0
+	t.mutex.Unlock()
+
+	if dropped > 0 {
+		t.logger.LogCtx(ctx, "level", "warning", "message", fmt.Sprintf("dropped %d spans, the exporter can't keep up", dropped))
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+
+	err := t.exporter.Export(ctx, spans)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (t *Tracer) start(ctx context.Context, name string, kind SpanKind, attributes []Attribute) (context.Context, *Span) {
+	data := SpanData{
+		SpanID:     newSpanID(),
+		Name:       name,
+		Kind:       kind,
+		Start:      time.Now(),
+		Attributes: append([]Attribute(nil), attributes...),
+	}
+
+	if parent := FromContext(ctx); parent != nil {
+		data.TraceID = parent.data.TraceID
+		data.ParentSpanID = parent.data.SpanID
+	} else if parent, ok := remoteParent(ctx); ok {
+		data.TraceID = parent.TraceID
+		data.ParentSpanID = parent.SpanID
+	} else {
+		data.TraceID = newTraceID()
+	}
+
+	span := &Span{
+		tracer: t,
+		data:   data,
+	}
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func (t *Tracer) enqueue(data SpanData) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if len(t.queue) >= maxQueueSize {
+		t.dropped++
+		return
+	}
+
+	t.queue = append(t.queue, data)
+}
+
+func isWebhookPath(path string) bool {
+	return strings.HasPrefix(path, "/mutate/") || strings.HasPrefix(path, "/validate/")
+}
+
+// statusWriter remembers the status code of the response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
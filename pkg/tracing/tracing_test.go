@@ -0,0 +1,390 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type memoryExporter struct {
+	mutex sync.Mutex
+	spans []SpanData
+}
+
+func (e *memoryExporter) Export(_ context.Context, spans []SpanData) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func newTestTracer(t *testing.T) (*Tracer, *memoryExporter) {
+	logger, err := micrologger.New(micrologger.Config{IOWriter: ioutil.Discard})
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+
+	exporter := &memoryExporter{}
+	tracer, err := New(Config{
+		Exporter: exporter,
+		Logger:   logger,
+	})
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+
+	return tracer, exporter
+}
+
+func Test_Middleware(t *testing.T) {
+	testCases := []struct {
+		name        string
+		path        string
+		traceparent string
+		status      int
+		// expectedSpans are the names of the exported spans and of their
+		// parents, in the order they ended.
+		expectedSpans []string
+		// expectedTraceID is the trace the spans belong to, a new one when
+		// it is empty.
+		expectedTraceID string
+		expectedStatus  StatusCode
+	}{
+		{
+			name:   "case 0: webhook request",
+			path:   "/validate/azuremachinepool/create",
+			status: http.StatusOK,
+			expectedSpans: []string{
+				"checkInstanceTypeIsValid < POST /validate/azuremachinepool/create",
+				"POST /validate/azuremachinepool/create < ",
+			},
+		},
+		{
+			name:            "case 1: webhook request continuing a trace",
+			path:            "/mutate/cluster/create",
+			traceparent:     "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			status:          http.StatusOK,
+			expectedTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			expectedSpans: []string{
+				"checkInstanceTypeIsValid < POST /mutate/cluster/create",
+				"POST /mutate/cluster/create < 00f067aa0ba902b7",
+			},
+		},
+		{
+			name:   "case 2: failed webhook request",
+			path:   "/validate/cluster/update",
+			status: http.StatusInternalServerError,
+			expectedSpans: []string{
+				"checkInstanceTypeIsValid < POST /validate/cluster/update",
+				"POST /validate/cluster/update < ",
+			},
+			expectedStatus: StatusError,
+		},
+		{
+			name:   "case 3: metrics are not traced",
+			path:   "/metrics",
+			status: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tracer, exporter := newTestTracer(t)
+
+			handler := tracer.Middleware(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				_, span := Start(request.Context(), "checkInstanceTypeIsValid")
+				span.End()
+
+				writer.WriteHeader(tc.status)
+			}))
+
+			request := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader("{}"))
+			if tc.traceparent != "" {
+				request.Header.Set(TraceparentHeader, tc.traceparent)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), request)
+
+			err := tracer.Flush(context.Background())
+			if err != nil {
+				t.Fatal(microerror.JSON(err))
+			}
+
+			names := map[SpanID]string{}
+			for _, s := range exporter.spans {
+				names[s.SpanID] = s.Name
+			}
+			var spans []string
+			for _, s := range exporter.spans {
+				parent := names[s.ParentSpanID]
+				if parent == "" && s.ParentSpanID.IsValid() {
+					parent = s.ParentSpanID.String()
+				}
+				spans = append(spans, s.Name+" < "+parent)
+
+				if s.TraceID != exporter.spans[0].TraceID {
+					t.Fatalf("span %q belongs to trace %s, want %s", s.Name, s.TraceID, exporter.spans[0].TraceID)
+				}
+			}
+			if !reflect.DeepEqual(spans, tc.expectedSpans) {
+				t.Fatalf("spans == %#v, want %#v", spans, tc.expectedSpans)
+			}
+
+			if len(exporter.spans) == 0 {
+				return
+			}
+			root := exporter.spans[len(exporter.spans)-1]
+			if tc.expectedTraceID != "" && root.TraceID.String() != tc.expectedTraceID {
+				t.Fatalf("TraceID == %s, want %s", root.TraceID, tc.expectedTraceID)
+			}
+			if root.Kind != SpanKindServer {
+				t.Fatalf("Kind == %d, want %d", root.Kind, SpanKindServer)
+			}
+			if root.StatusCode != tc.expectedStatus {
+				t.Fatalf("StatusCode == %d, want %d", root.StatusCode, tc.expectedStatus)
+			}
+		})
+	}
+}
+
+func Test_Start(t *testing.T) {
+	// Spans are not recorded for requests which are not traced.
+	ctx, span := Start(context.Background(), "checkDataDisks")
+	if span != nil {
+		t.Fatalf("span == %#v, want nil", span)
+	}
+	if ctx != context.Background() {
+		t.Fatalf("context was changed")
+	}
+	span.SetAttributes(String("key", "value"))
+	span.RecordError(errors.New("failed"))
+	span.End()
+
+	tracer, exporter := newTestTracer(t)
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, child := Start(ctx, "child", Int("count", 3))
+	child.RecordError(errors.New("failed"))
+	child.End()
+	child.End()
+	root.End()
+
+	err := tracer.Flush(context.Background())
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+
+	if len(exporter.spans) != 2 {
+		t.Fatalf("%d spans, want 2", len(exporter.spans))
+	}
+	if exporter.spans[0].StatusCode != StatusError || exporter.spans[0].StatusMessage != "failed" {
+		t.Fatalf("status == %d %q, want error", exporter.spans[0].StatusCode, exporter.spans[0].StatusMessage)
+	}
+	if exporter.spans[1].ParentSpanID.IsValid() {
+		t.Fatalf("root span has parent %s", exporter.spans[1].ParentSpanID)
+	}
+}
+
+func Test_Extract(t *testing.T) {
+	testCases := []struct {
+		name        string
+		traceparent string
+		valid       bool
+	}{
+		{
+			name:        "case 0: valid",
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			valid:       true,
+		},
+		{
+			name:        "case 1: future version with more fields",
+			traceparent: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+			valid:       true,
+		},
+		{
+			name: "case 2: missing",
+		},
+		{
+			name:        "case 3: invalid version",
+			traceparent: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		},
+		{
+			name:        "case 4: zero trace ID",
+			traceparent: "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		},
+		{
+			name:        "case 5: upper case",
+			traceparent: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		},
+		{
+			name:        "case 6: short span ID",
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa-01",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			header := http.Header{}
+			if tc.traceparent != "" {
+				header.Set(TraceparentHeader, tc.traceparent)
+			}
+
+			_, ok := Extract(header)
+			if ok != tc.valid {
+				t.Fatalf("Extract() == %t, want %t", ok, tc.valid)
+			}
+		})
+	}
+}
+
+func Test_OTLPExporter(t *testing.T) {
+	var request otlpRequest
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			writer.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Content-Type") != "application/json" {
+			writer.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+
+		err := json.NewDecoder(r.Body).Decode(&request)
+		if err != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}))
+	defer server.Close()
+
+	exporter, err := NewOTLPExporter(OTLPExporterConfig{
+		Endpoint:    server.URL + "/",
+		ServiceName: "azure-admission-controller",
+	})
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+
+	tracer, err := New(Config{Exporter: exporter, Logger: microloggerDiscard(t)})
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, child := StartClient(ctx, "kubernetes Get Release", String("k8s.name", "v13.0.0"), Bool("cached", true))
+	child.End()
+	root.End()
+
+	err = tracer.Flush(context.Background())
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+
+	if len(request.ResourceSpans) != 1 || len(request.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("request == %#v, want one resource and scope", request)
+	}
+	if *request.ResourceSpans[0].Resource.Attributes[0].Value.StringValue != "azure-admission-controller" {
+		t.Fatalf("resource == %#v, want service name", request.ResourceSpans[0].Resource)
+	}
+	spans := request.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("%d spans, want 2", len(spans))
+	}
+	if spans[0].Kind != SpanKindClient || spans[0].ParentSpanID != spans[1].SpanID || spans[0].TraceID != spans[1].TraceID {
+		t.Fatalf("span == %#v, want client span child of %#v", spans[0], spans[1])
+	}
+	if len(spans[0].TraceID) != 32 || len(spans[0].SpanID) != 16 {
+		t.Fatalf("span IDs %q and %q, want hex encoded", spans[0].TraceID, spans[0].SpanID)
+	}
+	if spans[1].ParentSpanID != "" {
+		t.Fatalf("root span has parent %q", spans[1].ParentSpanID)
+	}
+
+	// Failed exports are reported.
+	exporter.url = server.URL + "/missing"
+	_, span := tracer.Start(context.Background(), "root")
+	span.End()
+	err = tracer.Flush(context.Background())
+	if !IsExportFailed(err) {
+		t.Fatalf("error == %#v, want exportFailedError", err)
+	}
+}
+
+func Test_NewClient(t *testing.T) {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "enforcement",
+			Namespace: "giantswarm",
+		},
+	}
+	c := NewClient(fake.NewFakeClientWithScheme(scheme.Scheme, configMap))
+
+	tracer, exporter := newTestTracer(t)
+	ctx, root := tracer.Start(context.Background(), "root")
+
+	err := c.Get(ctx, client.ObjectKey{Namespace: "giantswarm", Name: "enforcement"}, &corev1.ConfigMap{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Get(ctx, client.ObjectKey{Namespace: "giantswarm", Name: "missing"}, &corev1.ConfigMap{})
+	if err == nil {
+		t.Fatal("error == nil, want not found")
+	}
+	err = c.List(ctx, &corev1.ConfigMapList{}, client.InNamespace("giantswarm"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Calls outside of traced requests are not recorded.
+	err = c.Get(context.Background(), client.ObjectKey{Namespace: "giantswarm", Name: "enforcement"}, &corev1.ConfigMap{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root.End()
+
+	err = tracer.Flush(context.Background())
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+
+	var spans []string
+	for _, s := range exporter.spans {
+		var attributes []string
+		for _, a := range s.Attributes {
+			attributes = append(attributes, a.Key+"="+a.Value)
+		}
+		sort.Strings(attributes)
+		spans = append(spans, s.Name+" "+strings.Join(attributes, ",")+" "+s.StatusMessage)
+	}
+	expected := []string{
+		"kubernetes Get ConfigMap k8s.kind=ConfigMap,k8s.name=enforcement,k8s.namespace=giantswarm,k8s.verb=Get ",
+		`kubernetes Get ConfigMap k8s.kind=ConfigMap,k8s.name=missing,k8s.namespace=giantswarm,k8s.verb=Get configmaps "missing" not found`,
+		"kubernetes List ConfigMapList k8s.items=1,k8s.kind=ConfigMapList,k8s.namespace=giantswarm,k8s.verb=List ",
+		"root  ",
+	}
+	if !reflect.DeepEqual(spans, expected) {
+		t.Fatalf("spans == %#v, want %#v", spans, expected)
+	}
+}
+
+func microloggerDiscard(t *testing.T) micrologger.Logger {
+	logger, err := micrologger.New(micrologger.Config{IOWriter: ioutil.Discard})
+	if err != nil {
+		t.Fatal(microerror.JSON(err))
+	}
+
+	return logger
+}
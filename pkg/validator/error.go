@@ -12,3 +12,12 @@ var invalidConfigError = &microerror.Error{
 func IsInvalidConfig(err error) bool {
 	return microerror.Cause(err) == invalidConfigError
 }
+
+var shadowPanicError = &microerror.Error{
+	Kind: "shadowPanicError",
+}
+
+// IsShadowPanic asserts shadowPanicError.
+func IsShadowPanic(err error) bool {
+	return microerror.Cause(err) == shadowPanicError
+}
@@ -0,0 +1,183 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	internalerrors "github.com/giantswarm/azure-admission-controller/internal/errors"
+	"github.com/giantswarm/azure-admission-controller/pkg/enforcement"
+	"github.com/giantswarm/azure-admission-controller/pkg/generic"
+	"github.com/giantswarm/azure-admission-controller/pkg/tracing"
+)
+
+// FieldError is a violation found by a validation check together with the
+// path of the field it refers to, e.g. "spec.template.vmSize". The field path
+// is empty when the violation is not about a single field.
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+// ErrorList collects all the violations found by a validation chain, in the
+// style of field.ErrorList, so that the user gets all of them in one denial
+// instead of fixing and re-applying one field at a time.
+type ErrorList []FieldError
+
+// Add adds the specified error for the specified field path to the list.
+// Nil errors are ignored, so the result of a check can be added directly.
+func (l *ErrorList) Add(field string, err error) {
+	if err == nil {
+		return
+	}
+
+	*l = append(*l, FieldError{Field: field, Err: err})
+}
+
+// Check runs the named check and adds its error for the specified field path
+// to the list. The check is not run at all when it is disabled in the
+// enforcement configuration carried by the context. When the check is in
+// audit mode or bypassed, its error is returned as an admission warning and
+// recorded with enforcement.AddViolation instead, so it does not deny the
+// request. Every check run is recorded as a span named after it when the
+// request is traced.
+//
+// Check returns false when the check found a violation, also when it is in
+// audit mode or bypassed, so that the checks depending on it can be skipped,
+// e.g. the capability checks of a VM size which does not exist. It returns
+// true when the check passed or is disabled.
+func (l *ErrorList) Check(ctx context.Context, name, field string, check func() error) bool {
+	if !enforcement.CheckEnabled(ctx, name) {
+		return true
+	}
+
+	_, span := tracing.Start(ctx, name, tracing.String("admission.check", name), tracing.String("admission.check.mode", enforcement.CheckMode(ctx, name)))
+	err := check()
+	span.RecordError(err)
+	span.End()
+	if err == nil {
+		return true
+	}
+
+	if enforcement.CheckMode(ctx, name) == enforcement.ModeAudit {
+		enforcement.AddViolation(ctx, enforcement.Violation{Check: name, Field: field, Err: err})
+		generic.AddWarning(ctx, "check %s is in audit mode, this request will be denied once it is enforced: %s", name, err.Error())
+		return false
+	}
+
+	if enforcement.CheckBypassed(ctx, name) {
+		enforcement.AddViolation(ctx, enforcement.Violation{Check: name, Field: field, Err: err, Bypassed: true})
+		generic.AddWarning(ctx, "check %s is bypassed with the %s annotation: %s", name, enforcement.BypassAnnotation, err.Error())
+		return false
+	}
+
+	l.Add(field, err)
+	return false
+}
+
+// ToAggregate returns nil when the list is empty and an error carrying all
+// the violations otherwise. microerror.Cause, and therefore the Is...Error
+// matchers, returns the microerror kind of the first violation having one,
+// skipping e.g. the errors of the Cluster API webhooks, while errors.Is and
+// errors.As match any of the violations.
+func (l ErrorList) ToAggregate() error {
+	if len(l) == 0 {
+		return nil
+	}
+
+	return &aggregateError{
+		errors: append(ErrorList(nil), l...),
+	}
+}
+
+type aggregateError struct {
+	errors ErrorList
+}
+
+func (e *aggregateError) Error() string {
+	if len(e.errors) == 1 {
+		return e.errors[0].Err.Error()
+	}
+
+	messages := make([]string, 0, len(e.errors))
+	for _, fieldError := range e.errors {
+		messages = append(messages, fieldError.Err.Error())
+	}
+
+	return "[" + strings.Join(messages, ", ") + "]"
+}
+
+// As makes errors.As, and therefore microerror.Cause, find the target in the
+// aggregated violations, in the order they were added.
+func (e *aggregateError) As(target interface{}) bool {
+	for _, fieldError := range e.errors {
+		if errors.As(fieldError.Err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Is reports whether any of the aggregated violations matches the target.
+func (e *aggregateError) Is(target error) bool {
+	for _, fieldError := range e.errors {
+		if errors.Is(fieldError.Err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// causes returns a status cause for every violation. Violations which are API
+// status errors already carrying causes, e.g. the ones returned by the
+// Cluster API webhooks, are flattened into their causes.
+func (e *aggregateError) causes() []metav1.StatusCause {
+	var causes []metav1.StatusCause
+	for _, fieldError := range e.errors {
+		var status apierrors.APIStatus
+		if errors.As(fieldError.Err, &status) {
+			details := status.Status().Details
+			if details != nil && len(details.Causes) > 0 {
+				causes = append(causes, details.Causes...)
+				continue
+			}
+		}
+
+		causes = append(causes, newStatusCause(fieldError.Field, fieldError.Err))
+	}
+
+	return causes
+}
+
+// StatusCauses returns the status causes for a request denied with the
+// specified error, one for every violation when they were collected with an
+// ErrorList.
+func StatusCauses(err error) []metav1.StatusCause {
+	var aggregate *aggregateError
+	if errors.As(err, &aggregate) {
+		return aggregate.causes()
+	}
+
+	return []metav1.StatusCause{newStatusCause("", err)}
+}
+
+// newStatusCause returns the status cause for a single violation. The cause
+// type is the stable error code of the violation, so that clients can branch
+// on it without parsing the message.
+func newStatusCause(field string, err error) metav1.StatusCause {
+	causeType := metav1.CauseTypeFieldValueInvalid
+	if code := internalerrors.ErrorCode(err); code != "" {
+		causeType = metav1.CauseType(code)
+	}
+
+	return metav1.StatusCause{
+		Type:    causeType,
+		Message: err.Error(),
+		Field:   field,
+	}
+}
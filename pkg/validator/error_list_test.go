@@ -0,0 +1,261 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/giantswarm/microerror"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/giantswarm/azure-admission-controller/pkg/enforcement"
+	"github.com/giantswarm/azure-admission-controller/pkg/generic"
+)
+
+var firstTestError = &microerror.Error{
+	Kind: "firstTestError",
+}
+
+var secondTestError = &microerror.Error{
+	Kind: "secondTestError",
+}
+
+func TestErrorList(t *testing.T) {
+	capiError := apierrors.NewInvalid(schema.GroupKind{Group: "cluster.x-k8s.io", Kind: "Cluster"}, "ab123", field.ErrorList{
+		field.Invalid(field.NewPath("spec", "paused"), true, "must not be set"),
+	})
+
+	testCases := []struct {
+		name            string
+		errors          []FieldError
+		expectedMessage string
+		expectedCauses  []metav1.StatusCause
+		expectedCause   error
+		// expectedIs are the errors matched with errors.Is.
+		expectedIs []error
+	}{
+		{
+			name: "case 0: no violations",
+			errors: []FieldError{
+				{Field: "spec.location", Err: nil},
+			},
+		},
+		{
+			name: "case 1: single violation",
+			errors: []FieldError{
+				{Field: "spec.location", Err: microerror.Maskf(firstTestError, "location changed")},
+			},
+			expectedMessage: "first test error: location changed",
+			expectedCauses: []metav1.StatusCause{
+				{Type: "firstTestError", Message: "first test error: location changed", Field: "spec.location"},
+			},
+			expectedCause: firstTestError,
+		},
+		{
+			name: "case 2: multiple violations",
+			errors: []FieldError{
+				{Field: "spec.location", Err: microerror.Maskf(firstTestError, "location changed")},
+				{Field: "spec.template.vmSize", Err: nil},
+				{Field: "spec.template.sshPublicKey", Err: microerror.Maskf(secondTestError, "ssh key set")},
+			},
+			expectedMessage: "[first test error: location changed, second test error: ssh key set]",
+			expectedCauses: []metav1.StatusCause{
+				{Type: "firstTestError", Message: "first test error: location changed", Field: "spec.location"},
+				{Type: "secondTestError", Message: "second test error: ssh key set", Field: "spec.template.sshPublicKey"},
+			},
+			expectedCause: firstTestError,
+			expectedIs:    []error{firstTestError, secondTestError},
+		},
+		{
+			name: "case 3: Cluster API error causes are flattened",
+			errors: []FieldError{
+				{Field: "", Err: capiError},
+				{Field: "spec.location", Err: microerror.Maskf(firstTestError, "location changed")},
+			},
+			expectedMessage: "[" + capiError.Error() + ", first test error: location changed]",
+			expectedCauses: []metav1.StatusCause{
+				{Type: metav1.CauseTypeFieldValueInvalid, Message: "Invalid value: true: must not be set", Field: "spec.paused"},
+				{Type: "firstTestError", Message: "first test error: location changed", Field: "spec.location"},
+			},
+			expectedCause: firstTestError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var errs ErrorList
+			for _, fieldError := range tc.errors {
+				errs.Add(fieldError.Field, fieldError.Err)
+			}
+
+			err := microerror.Mask(errs.ToAggregate())
+			if tc.expectedMessage == "" {
+				if err != nil {
+					t.Fatalf("expected nil error, got %#v", err)
+				}
+				return
+			}
+
+			if err.Error() != tc.expectedMessage {
+				t.Fatalf("expected message %q, got %q", tc.expectedMessage, err.Error())
+			}
+
+			// The kind of the first microerror must be preserved for the Is...Error matchers.
+			if microerror.Cause(err) != tc.expectedCause {
+				t.Fatalf("expected cause %#v, got %#v", tc.expectedCause, microerror.Cause(err))
+			}
+			for _, target := range tc.expectedIs {
+				if !errors.Is(err, target) {
+					t.Fatalf("expected error to match %#v", target)
+				}
+			}
+
+			response := errorResponse("abc", err)
+			if response.Result.Details == nil {
+				t.Fatalf("expected status details, got nil")
+			}
+			if !reflect.DeepEqual(response.Result.Details.Causes, tc.expectedCauses) {
+				t.Fatalf("expected causes %#v, got %#v", tc.expectedCauses, response.Result.Details.Causes)
+			}
+		})
+	}
+}
+
+func TestErrorList_Check(t *testing.T) {
+	disabled := false
+
+	testCases := []struct {
+		name             string
+		config           *enforcement.Config
+		bypass           []string
+		pass             bool
+		expectedChecks   []string
+		expectedErrors   int
+		expectedWarnings int
+	}{
+		{
+			name:           "case 0: no enforcement configuration",
+			expectedChecks: []string{"checkLocation", "checkSSHKey"},
+			expectedErrors: 2,
+		},
+		{
+			name: "case 1: check disabled for the handler",
+			config: &enforcement.Config{
+				Handlers: map[string]enforcement.HandlerConfig{
+					"azuremachinepool": {
+						Checks: map[string]enforcement.CheckConfig{
+							"checkSSHKey": {Enabled: &disabled},
+						},
+					},
+				},
+			},
+			expectedChecks: []string{"checkLocation"},
+			expectedErrors: 1,
+		},
+		{
+			name: "case 2: check disabled for another handler",
+			config: &enforcement.Config{
+				Handlers: map[string]enforcement.HandlerConfig{
+					"azuremachine": {
+						Checks: map[string]enforcement.CheckConfig{
+							"checkSSHKey": {Enabled: &disabled},
+						},
+					},
+				},
+			},
+			expectedChecks: []string{"checkLocation", "checkSSHKey"},
+			expectedErrors: 2,
+		},
+		{
+			name: "case 3: check in audit mode",
+			config: &enforcement.Config{
+				Checks: map[string]enforcement.CheckConfig{
+					"checkSSHKey": {Mode: enforcement.ModeAudit},
+				},
+			},
+			expectedChecks:   []string{"checkLocation", "checkSSHKey"},
+			expectedErrors:   1,
+			expectedWarnings: 1,
+		},
+		{
+			name: "case 4: handler in audit mode",
+			config: &enforcement.Config{
+				Handlers: map[string]enforcement.HandlerConfig{
+					"azuremachinepool": {
+						Mode: enforcement.ModeAudit,
+					},
+				},
+			},
+			expectedChecks:   []string{"checkLocation", "checkSSHKey"},
+			expectedErrors:   0,
+			expectedWarnings: 2,
+		},
+		{
+			name:             "case 5: bypassed check",
+			config:           &enforcement.Config{},
+			bypass:           []string{"checkSSHKey"},
+			expectedChecks:   []string{"checkLocation", "checkSSHKey"},
+			expectedErrors:   1,
+			expectedWarnings: 1,
+		},
+		{
+			name:           "case 6: no violations",
+			pass:           true,
+			expectedChecks: []string{"checkLocation", "checkSSHKey"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := generic.WithWarnings(context.Background())
+			if tc.config != nil {
+				ctx = enforcement.NewContext(ctx, tc.config, "azuremachinepool")
+			}
+			if tc.bypass != nil {
+				ctx = enforcement.WithBypass(ctx, tc.bypass)
+			}
+
+			var checks []string
+			var errs ErrorList
+			var passed []bool
+			for _, name := range []string{"checkLocation", "checkSSHKey"} {
+				name := name
+				passed = append(passed, errs.Check(ctx, name, "spec."+name, func() error {
+					checks = append(checks, name)
+					if tc.pass {
+						return nil
+					}
+					return microerror.Maskf(firstTestError, name)
+				}))
+			}
+
+			if !reflect.DeepEqual(checks, tc.expectedChecks) {
+				t.Fatalf("checks run == %v, want %v", checks, tc.expectedChecks)
+			}
+			// Checks pass when they are disabled or found no violation,
+			// violations in audit mode or bypassed don't count.
+			for i, name := range []string{"checkLocation", "checkSSHKey"} {
+				ran := false
+				for _, c := range checks {
+					ran = ran || c == name
+				}
+				if passed[i] != (!ran || tc.pass) {
+					t.Fatalf("check %s passed == %t", name, passed[i])
+				}
+			}
+			if len(errs) != tc.expectedErrors {
+				t.Fatalf("errors == %d, want %d", len(errs), tc.expectedErrors)
+			}
+			if len(generic.Warnings(ctx)) != tc.expectedWarnings {
+				t.Fatalf("warnings == %d, want %d", len(generic.Warnings(ctx)), tc.expectedWarnings)
+			}
+			if len(enforcement.Violations(ctx)) != tc.expectedWarnings {
+				t.Fatalf("violations == %d, want %d", len(enforcement.Violations(ctx)), tc.expectedWarnings)
+			}
+		})
+	}
+}
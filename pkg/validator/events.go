@@ -0,0 +1,66 @@
+package validator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/giantswarm/microerror"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha3"
+
+	"github.com/giantswarm/azure-admission-controller/internal/errors"
+	"github.com/giantswarm/azure-admission-controller/pkg/generic"
+)
+
+// recordDenial records a Warning Event about the denied request, so that
+// denials of requests sent by controllers are visible with kubectl describe.
+// Updates are recorded on the object itself. Objects being created don't
+// exist yet, so creates are recorded on their owner Cluster and dropped when
+// there is none. Dry run requests are never recorded.
+//
+// Failures are only logged, they must not change the admission decision.
+func (h *HttpHandlerFactory) recordDenial(ctx context.Context, webhookHandler WebhookHandlerBase, request *admissionv1.AdmissionRequest, object metav1.ObjectMetaAccessor, err error) {
+	if generic.IsDryRun(ctx) {
+		return
+	}
+
+	var involvedObject corev1.ObjectReference
+	if request.Operation == admissionv1.Create {
+		ownerCluster, ok, err := generic.TryGetOwnerCluster(ctx, h.ctrlReader, object)
+		if err != nil {
+			webhookHandler.Log("level", "error", "message", "unable to get owner cluster for denial event", "stack", microerror.JSON(err))
+			return
+		} else if !ok {
+			return
+		}
+
+		involvedObject = corev1.ObjectReference{
+			APIVersion:      capi.GroupVersion.String(),
+			Kind:            "Cluster",
+			Namespace:       ownerCluster.Namespace,
+			Name:            ownerCluster.Name,
+			UID:             ownerCluster.UID,
+			ResourceVersion: ownerCluster.ResourceVersion,
+		}
+	} else {
+		objectMeta := object.GetObjectMeta()
+		involvedObject = corev1.ObjectReference{
+			APIVersion:      schema.GroupVersion{Group: request.Kind.Group, Version: request.Kind.Version}.String(),
+			Kind:            request.Kind.Kind,
+			Namespace:       objectMeta.GetNamespace(),
+			Name:            objectMeta.GetName(),
+			UID:             objectMeta.GetUID(),
+			ResourceVersion: objectMeta.GetResourceVersion(),
+		}
+	}
+
+	message := fmt.Sprintf("%s of %s %s/%s denied: %s", request.Operation, request.Kind.Kind, request.Namespace, request.Name, err.Error())
+
+	err = h.recorder.Warn(ctx, involvedObject, string(errors.StatusReason(err)), message)
+	if err != nil {
+		webhookHandler.Log("level", "error", "message", "unable to record denial event", "stack", microerror.JSON(err))
+	}
+}
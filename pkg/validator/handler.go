@@ -2,23 +2,24 @@ package validator
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 
 	"github.com/giantswarm/microerror"
-	"k8s.io/api/admission/v1beta1"
+	admissionv1 "k8s.io/api/admission/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	"k8s.io/apimachinery/pkg/types"
 
+	"github.com/giantswarm/azure-admission-controller/internal/admissionreview"
+	"github.com/giantswarm/azure-admission-controller/internal/errors"
 	"github.com/giantswarm/azure-admission-controller/pkg/generic"
 )
 
 type Validator interface {
-	Validate(ctx context.Context, request *v1beta1.AdmissionRequest) error
+	Validate(ctx context.Context, request *admissionv1.AdmissionRequest) error
 	Log(keyVals ...interface{})
 }
 
@@ -43,37 +44,34 @@ func Handler(validator Validator) http.HandlerFunc {
 			return
 		}
 
-		review := v1beta1.AdmissionReview{}
-		if _, _, err := Deserializer.Decode(data, nil, &review); err != nil {
+		admissionRequest, version, err := admissionreview.Decode(data)
+		if err != nil {
 			validator.Log("level", "error", "message", "unable to parse admission review request")
 			writer.WriteHeader(http.StatusBadRequest)
 			return
 		}
 
-		err = validator.Validate(request.Context(), review.Request)
+		ctx := generic.WithWarnings(request.Context())
+
+		err = validator.Validate(ctx, admissionRequest)
 		if err != nil {
-			writeResponse(validator, writer, errorResponse(review.Request.UID, microerror.Mask(err)))
+			writeResponse(validator, writer, version, errorResponse(admissionRequest.UID, microerror.Mask(err)), generic.Warnings(ctx))
 			return
 		}
 
-		writeResponse(validator, writer, &v1beta1.AdmissionResponse{
+		writeResponse(validator, writer, version, &admissionv1.AdmissionResponse{
 			Allowed: true,
-			UID:     review.Request.UID,
-		})
+			UID:     admissionRequest.UID,
+		}, generic.Warnings(ctx))
 	}
 }
 
-func writeResponse(logger generic.Logger, writer http.ResponseWriter, response *v1beta1.AdmissionResponse) {
-	resp, err := json.Marshal(v1beta1.AdmissionReview{
-		TypeMeta: metav1.TypeMeta{
-			Kind:       "AdmissionReview",
-			APIVersion: "admission.k8s.io/v1",
-		},
-		Response: response,
-	})
+func writeResponse(logger generic.Logger, writer http.ResponseWriter, version string, response *admissionv1.AdmissionResponse, warnings []string) {
+	resp, err := admissionreview.Encode(version, response, warnings)
 	if err != nil {
 		logger.Log("level", "error", "message", "unable to serialize response", "stack", microerror.JSON(err))
 		writer.WriteHeader(http.StatusInternalServerError)
+		return
 	}
 
 	if _, err := writer.Write(resp); err != nil {
@@ -83,14 +81,24 @@ func writeResponse(logger generic.Logger, writer http.ResponseWriter, response *
 	logger.Log("level", "info", "message", fmt.Sprintf("Validated request responded with result: %t", response.Allowed))
 }
 
-func errorResponse(uid types.UID, err error) *v1beta1.AdmissionResponse {
-	return &v1beta1.AdmissionResponse{
+// errorResponse returns the response denying a request with the specified
+// error. The status reason and code are derived from the error kind, see
+// errors.RegisterStatusReason, and every violation is listed as a cause with
+// its field path and error code.
+func errorResponse(uid types.UID, err error) *admissionv1.AdmissionResponse {
+	reason := errors.StatusReason(err)
+
+	return &admissionv1.AdmissionResponse{
 		Allowed: false,
 		UID:     uid,
 		Result: &metav1.Status{
-			Reason:  metav1.StatusReasonBadRequest,
-			Code:    http.StatusBadRequest,
+			Status:  metav1.StatusFailure,
+			Reason:  reason,
+			Code:    errors.StatusCode(reason),
 			Message: err.Error(),
+			Details: &metav1.StatusDetails{
+				Causes: StatusCauses(err),
+			},
 		},
 	}
 }
@@ -5,62 +5,129 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
-	"k8s.io/api/admission/v1beta1"
+	admissionv1 "k8s.io/api/admission/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	capi "sigs.k8s.io/cluster-api/api/v1alpha3"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/giantswarm/azure-admission-controller/internal/admissionreview"
+	"github.com/giantswarm/azure-admission-controller/pkg/audit"
+	"github.com/giantswarm/azure-admission-controller/pkg/enforcement"
+	"github.com/giantswarm/azure-admission-controller/pkg/events"
 	"github.com/giantswarm/azure-admission-controller/pkg/filter"
 	"github.com/giantswarm/azure-admission-controller/pkg/generic"
+	"github.com/giantswarm/azure-admission-controller/pkg/metrics"
+	"github.com/giantswarm/azure-admission-controller/pkg/requestcache"
+	"github.com/giantswarm/azure-admission-controller/pkg/tracing"
 )
 
 type HttpHandlerFactoryConfig struct {
-	CtrlReader client.Reader
-	CtrlClient client.Client
-	Logger     micrologger.Logger
+	AuditSink   *audit.Sink
+	CtrlReader  client.Reader
+	CtrlClient  client.Client
+	Enforcement *enforcement.Store
+	Logger      micrologger.Logger
+
+	// CandidateEnforcement is the enforcement configuration candidate handlers
+	// are evaluated with in shadow mode instead of the live one. It is
+	// optional.
+	CandidateEnforcement *enforcement.Store
 }
 
 // HttpHandlerFactory creates HTTP handlers for validating create and update requests.
 type HttpHandlerFactory struct {
-	ctrlReader client.Reader
-	ctrlClient client.Client
-	logger     micrologger.Logger
+	auditSink            *audit.Sink
+	ctrlReader           client.Reader
+	enforcement          *enforcement.Store
+	logger               micrologger.Logger
+	recorder             *events.Recorder
+	candidateEnforcement *enforcement.Store
+
+	// shadows tracks the comparisons of candidate handlers running in the
+	// background, see compareShadow.
+	shadows sync.WaitGroup
 }
 
 func NewHttpHandlerFactory(config HttpHandlerFactoryConfig) (*HttpHandlerFactory, error) {
+	if config.AuditSink == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.AuditSink must not be empty", config)
+	}
 	if config.CtrlReader == nil {
 		return nil, microerror.Maskf(invalidConfigError, "%T.CtrlReader must not be empty", config)
 	}
 	if config.CtrlClient == nil {
 		return nil, microerror.Maskf(invalidConfigError, "%T.CtrlClient must not be empty", config)
 	}
+	if config.Enforcement == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Enforcement must not be empty", config)
+	}
 	if config.Logger == nil {
 		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
 	}
 
+	var err error
+
+	var recorder *events.Recorder
+	{
+		c := events.RecorderConfig{
+			CtrlClient: config.CtrlClient,
+		}
+		recorder, err = events.NewRecorder(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	h := &HttpHandlerFactory{
-		ctrlReader: config.CtrlReader,
-		ctrlClient: config.CtrlClient,
-		logger:     config.Logger,
+		auditSink:            config.AuditSink,
+		ctrlReader:           config.CtrlReader,
+		enforcement:          config.Enforcement,
+		logger:               config.Logger,
+		candidateEnforcement: config.CandidateEnforcement,
+		recorder:             recorder,
 	}
 
 	return h, nil
 }
 
+// Wait waits for the comparisons of candidate handlers running in the
+// background. Call it once the server stopped handling requests, so that their
+// logs and metrics are not lost on shutdown.
+func (h *HttpHandlerFactory) Wait() {
+	h.shadows.Wait()
+}
+
 // NewCreateHandler returns a HTTP handler for validating create requests.
 func (h *HttpHandlerFactory) NewCreateHandler(webhookCreateHandler WebhookCreateHandler) http.HandlerFunc {
-	validateFunc := func(ctx context.Context, review v1beta1.AdmissionReview) error {
+	return h.newCreateHandler(webhookCreateHandler, nil)
+}
+
+// NewShadowCreateHandler returns a HTTP handler for validating create requests
+// with the live handler, which also validates them with the candidate handler
+// in shadow mode. The live handler decides. The candidate handler validates a
+// copy of the same object concurrently, and is compared in the background
+// without delaying the response. When it decides otherwise, the divergence is
+// logged and counted, but never returned to the API server.
+func (h *HttpHandlerFactory) NewShadowCreateHandler(live WebhookCreateHandler, candidate WebhookCreateHandler) http.HandlerFunc {
+	return h.newCreateHandler(live, candidate)
+}
+
+func (h *HttpHandlerFactory) newCreateHandler(webhookCreateHandler WebhookCreateHandler, candidate WebhookCreateHandler) http.HandlerFunc {
+	validateFunc := func(ctx context.Context, request *admissionv1.AdmissionRequest) (string, error) {
 		// Decode the new CR from the request.
-		object, err := webhookCreateHandler.Decode(review.Request.Object)
+		object, err := webhookCreateHandler.Decode(request.Object)
 		if err != nil {
-			return microerror.Mask(err)
+			return metrics.OutcomeError, microerror.Mask(err)
 		}
 
 		ownerClusterGetter := func(objectMeta metav1.ObjectMetaAccessor) (capi.Cluster, bool, error) {
-			ownerCluster, ok, err := generic.TryGetOwnerCluster(ctx, h.ctrlClient, object)
+			ownerCluster, ok, err := generic.TryGetOwnerCluster(ctx, h.ctrlReader, object)
 			if err != nil {
 				return capi.Cluster{}, false, microerror.Mask(err)
 			}
@@ -71,34 +138,59 @@ func (h *HttpHandlerFactory) NewCreateHandler(webhookCreateHandler WebhookCreate
 		// Check if the CR should be validated by the azure-admission-controller.
 		ok, err := filter.IsObjectReconciledByLegacyRelease(ctx, h.logger, h.ctrlReader, object, ownerClusterGetter)
 		if err != nil {
-			return microerror.Mask(err)
+			return metrics.OutcomeError, microerror.Mask(err)
 		}
 
-		if ok {
-			// Validate the CR.
-			err = webhookCreateHandler.OnCreateValidate(ctx, object)
-			if err != nil {
-				return microerror.Mask(err)
-			}
+		if !ok {
+			return metrics.OutcomeSkipped, nil
+		}
+
+		// Validate the CR, bypassing the checks listed in the bypass annotation
+		// when the user may.
+		ctx = withBypass(ctx, webhookCreateHandler, request, object)
+		var shadow <-chan error
+		if candidate != nil {
+			shadow = h.startShadow(ctx, candidate.Resource(), object, func(ctx context.Context, object interface{}) error {
+				return candidate.OnCreateValidate(ctx, object)
+			})
+		}
+		err = webhookCreateHandler.OnCreateValidate(ctx, object)
+		if shadow != nil {
+			h.compareShadow(webhookCreateHandler, metrics.OperationCreate, request, err, shadow)
+		}
+		if err != nil {
+			h.recordDenial(ctx, webhookCreateHandler, request, object, err)
+			return metrics.OutcomeDenied, microerror.Mask(err)
 		}
 
-		return nil
+		return metrics.OutcomeAllowed, nil
 	}
 
-	return h.newHttpHandler(webhookCreateHandler, validateFunc)
+	return h.newHttpHandler(webhookCreateHandler, metrics.OperationCreate, validateFunc)
 }
 
 // NewUpdateHandler returns a HTTP handler for validating update requests.
 func (h *HttpHandlerFactory) NewUpdateHandler(webhookUpdateHandler WebhookUpdateHandler) http.HandlerFunc {
-	validateFunc := func(ctx context.Context, review v1beta1.AdmissionReview) error {
+	return h.newUpdateHandler(webhookUpdateHandler, nil)
+}
+
+// NewShadowUpdateHandler returns a HTTP handler for validating update requests
+// with the live handler, which also validates them with the candidate handler
+// in shadow mode, like NewShadowCreateHandler.
+func (h *HttpHandlerFactory) NewShadowUpdateHandler(live WebhookUpdateHandler, candidate WebhookUpdateHandler) http.HandlerFunc {
+	return h.newUpdateHandler(live, candidate)
+}
+
+func (h *HttpHandlerFactory) newUpdateHandler(webhookUpdateHandler WebhookUpdateHandler, candidate WebhookUpdateHandler) http.HandlerFunc {
+	validateFunc := func(ctx context.Context, request *admissionv1.AdmissionRequest) (string, error) {
 		// Decode the new updated CR from the request.
-		object, err := webhookUpdateHandler.Decode(review.Request.Object)
+		object, err := webhookUpdateHandler.Decode(request.Object)
 		if err != nil {
-			return microerror.Mask(err)
+			return metrics.OutcomeError, microerror.Mask(err)
 		}
 
 		ownerClusterGetter := func(objectMeta metav1.ObjectMetaAccessor) (capi.Cluster, bool, error) {
-			ownerCluster, ok, err := generic.TryGetOwnerCluster(ctx, h.ctrlClient, object)
+			ownerCluster, ok, err := generic.TryGetOwnerCluster(ctx, h.ctrlReader, object)
 			if err != nil {
 				return capi.Cluster{}, false, microerror.Mask(err)
 			}
@@ -109,27 +201,42 @@ func (h *HttpHandlerFactory) NewUpdateHandler(webhookUpdateHandler WebhookUpdate
 		// Check if the CR should be validated by the azure-admission-controller.
 		ok, err := filter.IsObjectReconciledByLegacyRelease(ctx, h.logger, h.ctrlReader, object, ownerClusterGetter)
 		if err != nil {
-			return microerror.Mask(err)
+			return metrics.OutcomeError, microerror.Mask(err)
 		}
 
-		if ok {
-			// Decode the old CR from the request (before the update).
-			oldObject, err := webhookUpdateHandler.Decode(review.Request.OldObject)
-			if err != nil {
-				return microerror.Mask(err)
-			}
+		if !ok {
+			return metrics.OutcomeSkipped, nil
+		}
 
-			// Validate the CR.
-			err = webhookUpdateHandler.OnUpdateValidate(ctx, oldObject, object)
-			if err != nil {
-				return microerror.Mask(err)
-			}
+		// Decode the old CR from the request (before the update).
+		oldObject, err := webhookUpdateHandler.Decode(request.OldObject)
+		if err != nil {
+			return metrics.OutcomeError, microerror.Mask(err)
+		}
+
+		// Validate the CR, bypassing the checks listed in the bypass annotation
+		// when the user may.
+		ctx = withBypass(ctx, webhookUpdateHandler, request, object)
+		var shadow <-chan error
+		if candidate != nil {
+			oldObject := generic.DeepCopy(oldObject)
+			shadow = h.startShadow(ctx, candidate.Resource(), object, func(ctx context.Context, object interface{}) error {
+				return candidate.OnUpdateValidate(ctx, oldObject, object)
+			})
+		}
+		err = webhookUpdateHandler.OnUpdateValidate(ctx, oldObject, object)
+		if shadow != nil {
+			h.compareShadow(webhookUpdateHandler, metrics.OperationUpdate, request, err, shadow)
+		}
+		if err != nil {
+			h.recordDenial(ctx, webhookUpdateHandler, request, object, err)
+			return metrics.OutcomeDenied, microerror.Mask(err)
 		}
 
-		return nil
+		return metrics.OutcomeAllowed, nil
 	}
 
-	return h.newHttpHandler(webhookUpdateHandler, validateFunc)
+	return h.newHttpHandler(webhookUpdateHandler, metrics.OperationUpdate, validateFunc)
 }
 
 // newHttpHandler returns a HTTP handler for validating a request with the specified validation
@@ -137,8 +244,38 @@ func (h *HttpHandlerFactory) NewUpdateHandler(webhookUpdateHandler WebhookUpdate
 // This function is basically the same as the existing Handler func, with the only difference that
 // it is now wrapped into the New...Handler funcs above in order to first decode the CR and check
 // if it should be validated by azure-admission-controller.
-func (h *HttpHandlerFactory) newHttpHandler(webhookHandler WebhookHandlerBase, validateFunc func(ctx context.Context, review v1beta1.AdmissionReview) error) http.HandlerFunc {
+//
+// The AdmissionReview is decoded in whatever admission.k8s.io version the API server sent and the
+// response is encoded with the same version.
+//
+// Every request is recorded in the metrics with the outcome returned by the validation function.
+func (h *HttpHandlerFactory) newHttpHandler(webhookHandler WebhookHandlerBase, operation string, validateFunc func(ctx context.Context, request *admissionv1.AdmissionRequest) (string, error)) http.HandlerFunc {
 	return func(writer http.ResponseWriter, request *http.Request) {
+		start := time.Now()
+		outcome := metrics.OutcomeError
+		var admissionRequest *admissionv1.AdmissionRequest
+		var admissionResponse *admissionv1.AdmissionResponse
+		defer func() {
+			metrics.ObserveRequest(webhookHandler.Resource(), operation, metrics.WebhookValidate, outcome, start)
+
+			span := tracing.FromContext(request.Context())
+			span.SetAttributes(tracing.String("admission.resource", webhookHandler.Resource()), tracing.String("admission.operation", operation), tracing.String("admission.outcome", outcome))
+			if admissionRequest != nil {
+				span.SetAttributes(tracing.String("admission.uid", string(admissionRequest.UID)), tracing.String("admission.object", admissionRequest.Namespace+"/"+admissionRequest.Name))
+			}
+
+			// Requests which could not be decoded are only logged. Requests
+			// whose handler panicked have no response yet, they are recorded
+			// by recovery.Middleware.
+			if admissionRequest != nil && admissionResponse != nil {
+				entry := audit.NewEntry(webhookHandler.Resource(), metrics.WebhookValidate, operation, outcome, admissionRequest, admissionResponse)
+				err := h.auditSink.Record(entry)
+				if err != nil {
+					webhookHandler.Log("level", "error", "message", "unable to write audit log entry", "stack", microerror.JSON(err))
+				}
+			}
+		}()
+
 		if request.Header.Get("Content-Type") != "application/json" {
 			webhookHandler.Log("level", "error", "message", fmt.Sprintf("invalid content-type: %s", request.Header.Get("Content-Type")))
 			writer.WriteHeader(http.StatusBadRequest)
@@ -152,22 +289,74 @@ func (h *HttpHandlerFactory) newHttpHandler(webhookHandler WebhookHandlerBase, v
 			return
 		}
 
-		review := v1beta1.AdmissionReview{}
-		if _, _, err := Deserializer.Decode(data, nil, &review); err != nil {
-			webhookHandler.Log("level", "error", "message", "unable to parse admission review request")
+		admissionRequest, version, err := admissionreview.Decode(data)
+		if err != nil {
+			webhookHandler.Log("level", "error", "message", "unable to parse admission review request", "stack", microerror.JSON(err))
 			writer.WriteHeader(http.StatusBadRequest)
 			return
 		}
 
-		err = validateFunc(request.Context(), review)
+		ctx := generic.WithWarnings(request.Context())
+		ctx = generic.WithDryRun(ctx, admissionRequest.DryRun != nil && *admissionRequest.DryRun)
+
+		// The enforcement configuration is captured once, so that a reload
+		// doesn't change it in the middle of the request.
+		ctx = enforcement.NewContext(ctx, h.enforcement.Config(), webhookHandler.Resource())
+
+		// Every object is read at most once while handling the request.
+		ctx = requestcache.NewContext(ctx)
+
+		if enforcement.HandlerEnabled(ctx) {
+			outcome, err = validateFunc(ctx, admissionRequest)
+		} else {
+			webhookHandler.Log("level", "debug", "message", "webhook handler is disabled in the enforcement configuration")
+			outcome = metrics.OutcomeSkipped
+		}
+		observeViolations(ctx, webhookHandler, operation, admissionRequest)
 		if err != nil {
-			writeResponse(webhookHandler, writer, errorResponse(review.Request.UID, microerror.Mask(err)))
+			admissionResponse = errorResponse(admissionRequest.UID, microerror.Mask(err))
+			writeResponse(webhookHandler, writer, version, admissionResponse, generic.Warnings(ctx))
 			return
 		}
 
-		writeResponse(webhookHandler, writer, &v1beta1.AdmissionResponse{
+		admissionResponse = &admissionv1.AdmissionResponse{
 			Allowed: true,
-			UID:     review.Request.UID,
-		})
+			UID:     admissionRequest.UID,
+		}
+		writeResponse(webhookHandler, writer, version, admissionResponse, generic.Warnings(ctx))
+	}
+}
+
+// observeViolations logs and counts the violations found by checks in audit
+// mode and by bypassed checks, which did not deny the request.
+func observeViolations(ctx context.Context, webhookHandler WebhookHandlerBase, operation string, request *admissionv1.AdmissionRequest) {
+	for _, violation := range enforcement.Violations(ctx) {
+		if violation.Bypassed {
+			metrics.ObserveBypass(webhookHandler.Resource(), operation, violation.Check)
+			webhookHandler.Log("level", "warning", "message", fmt.Sprintf("check %s bypassed by %s in %s of %s %s/%s", violation.Check, request.UserInfo.Username, operation, request.Kind.Kind, request.Namespace, request.Name), "field", violation.Field, "violation", violation.Err.Error(), "uid", request.UID, "groups", strings.Join(request.UserInfo.Groups, ","))
+			continue
+		}
+
+		metrics.ObserveAuditViolation(webhookHandler.Resource(), operation, violation.Check)
+		webhookHandler.Log("level", "warning", "message", fmt.Sprintf("check %s in audit mode found a violation in %s of %s %s/%s", violation.Check, operation, request.Kind.Kind, request.Namespace, request.Name), "field", violation.Field, "violation", violation.Err.Error(), "uid", request.UID)
 	}
 }
+
+// withBypass returns a copy of the specified context in which the checks
+// listed in the bypass annotation of the object are bypassed, when the user
+// sending the request may bypass checks. Ignored annotations are logged too.
+func withBypass(ctx context.Context, webhookHandler WebhookHandlerBase, request *admissionv1.AdmissionRequest, object metav1.ObjectMetaAccessor) context.Context {
+	checks := enforcement.BypassChecks(object.GetObjectMeta().GetAnnotations())
+	if len(checks) == 0 {
+		return ctx
+	}
+
+	if !enforcement.BypassAllowed(ctx, request.UserInfo) {
+		webhookHandler.Log("level", "warning", "message", fmt.Sprintf("ignoring %s annotation set by %s, who may not bypass checks", enforcement.BypassAnnotation, request.UserInfo.Username), "checks", strings.Join(checks, ","), "uid", request.UID, "groups", strings.Join(request.UserInfo.Groups, ","))
+		return ctx
+	}
+
+	webhookHandler.Log("level", "warning", "message", fmt.Sprintf("%s bypasses checks %s in %s of %s %s/%s", request.UserInfo.Username, strings.Join(checks, ","), strings.ToLower(string(request.Operation)), request.Kind.Kind, request.Namespace, request.Name), "uid", request.UID, "groups", strings.Join(request.UserInfo.Groups, ","))
+
+	return enforcement.WithBypass(ctx, checks)
+}
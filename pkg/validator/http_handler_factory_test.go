@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -16,13 +17,20 @@ import (
 	"github.com/giantswarm/apiextensions/v3/pkg/label"
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
+	"github.com/prometheus/client_golang/prometheus"
 	admission "k8s.io/api/admission/v1beta1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/yaml"
 
+	"github.com/giantswarm/azure-admission-controller/internal/admissionreview"
 	builder "github.com/giantswarm/azure-admission-controller/internal/test/cluster"
+	"github.com/giantswarm/azure-admission-controller/pkg/audit"
+	"github.com/giantswarm/azure-admission-controller/pkg/enforcement"
+	"github.com/giantswarm/azure-admission-controller/pkg/metrics"
 	"github.com/giantswarm/azure-admission-controller/pkg/release"
 	"github.com/giantswarm/azure-admission-controller/pkg/unittest"
 )
@@ -41,6 +49,10 @@ const (
 	capiRelease = "20.0.0-v1alpha3"
 )
 
+var testDeniedError = &microerror.Error{
+	Kind: "testDeniedError",
+}
+
 type object interface {
 	runtime.Object
 	metav1.ObjectMetaAccessor
@@ -48,11 +60,28 @@ type object interface {
 
 func TestHttpHandler(t *testing.T) {
 	type testCase struct {
-		name          string
-		object        object
-		oldObject     object
-		operation     admission.Operation
-		expectedError *microerror.Error
+		name           string
+		object         object
+		oldObject      object
+		operation      admission.Operation
+		warnings       []string
+		validationErr  error
+		dryRun         bool
+		userGroups     []string
+		enforcement    string
+		expectedError  *microerror.Error
+		expectedEvents int
+		// bypassWarnings is the number of warnings added for bypassed checks.
+		bypassWarnings int
+		// candidateErr is returned by the candidate handler evaluated in
+		// shadow mode when shadow is set to the expected result.
+		candidateErr error
+		shadow       string
+		// candidateBlocks makes the candidate handler wait until the
+		// response was returned.
+		candidateBlocks bool
+		// candidateEnforcement is the candidate enforcement configuration.
+		candidateEnforcement string
 	}
 
 	testCases := []testCase{
@@ -84,119 +113,419 @@ func TestHttpHandler(t *testing.T) {
 			expectedError: release.ReleaseNotFoundError,
 			operation:     admission.Create,
 		},
+		{
+			name: "Validate Cluster creation with warnings",
+			object: builder.BuildCluster(
+				builder.Name("ab123"),
+				builder.Labels(map[string]string{
+					label.ReleaseVersion: legacyRelease,
+				})),
+			operation: admission.Create,
+			warnings:  []string{"first warning", "second warning"},
+		},
+		{
+			name: "Record an event on the Cluster when its update is denied",
+			object: builder.BuildCluster(
+				builder.Name("ab123"),
+				builder.Labels(map[string]string{
+					label.ReleaseVersion: legacyRelease,
+				})),
+			oldObject: builder.BuildCluster(
+				builder.Name("ab123"),
+				builder.Labels(map[string]string{
+					label.ReleaseVersion: legacyRelease,
+				})),
+			operation:      admission.Update,
+			validationErr:  microerror.Maskf(testDeniedError, "update denied"),
+			expectedError:  testDeniedError,
+			expectedEvents: 1,
+		},
+		{
+			name: "Don't record an event when a dry run update is denied",
+			object: builder.BuildCluster(
+				builder.Name("ab123"),
+				builder.Labels(map[string]string{
+					label.ReleaseVersion: legacyRelease,
+				})),
+			oldObject: builder.BuildCluster(
+				builder.Name("ab123"),
+				builder.Labels(map[string]string{
+					label.ReleaseVersion: legacyRelease,
+				})),
+			operation:      admission.Update,
+			dryRun:         true,
+			validationErr:  microerror.Maskf(testDeniedError, "update denied"),
+			expectedError:  testDeniedError,
+			expectedEvents: 0,
+		},
+		{
+			name: "Allow Cluster update when the handler is disabled in the enforcement configuration",
+			object: builder.BuildCluster(
+				builder.Name("ab123"),
+				builder.Labels(map[string]string{
+					label.ReleaseVersion: legacyRelease,
+				})),
+			oldObject: builder.BuildCluster(
+				builder.Name("ab123"),
+				builder.Labels(map[string]string{
+					label.ReleaseVersion: legacyRelease,
+				})),
+			operation:      admission.Update,
+			validationErr:  microerror.Maskf(testDeniedError, "update denied"),
+			enforcement:    "handlers:\n  mock_type:\n    enabled: false\n",
+			expectedEvents: 0,
+		},
+		{
+			name: "Allow Cluster update bypassing a check as a member of a bypass group",
+			object: builder.BuildCluster(
+				builder.Name("ab123"),
+				builder.Labels(map[string]string{
+					label.ReleaseVersion: legacyRelease,
+				}),
+				builder.Annotation(enforcement.BypassAnnotation, "mockCheck")),
+			oldObject: builder.BuildCluster(
+				builder.Name("ab123"),
+				builder.Labels(map[string]string{
+					label.ReleaseVersion: legacyRelease,
+				})),
+			operation:      admission.Update,
+			userGroups:     []string{"system:authenticated", "giantswarm:sre"},
+			validationErr:  microerror.Maskf(testDeniedError, "update denied"),
+			enforcement:    "bypass:\n  groups:\n  - giantswarm:sre\n",
+			expectedEvents: 0,
+			bypassWarnings: 1,
+		},
+		{
+			name: "Deny Cluster update with the bypass annotation sent by other users",
+			object: builder.BuildCluster(
+				builder.Name("ab123"),
+				builder.Labels(map[string]string{
+					label.ReleaseVersion: legacyRelease,
+				}),
+				builder.Annotation(enforcement.BypassAnnotation, "mockCheck")),
+			oldObject: builder.BuildCluster(
+				builder.Name("ab123"),
+				builder.Labels(map[string]string{
+					label.ReleaseVersion: legacyRelease,
+				})),
+			operation:      admission.Update,
+			userGroups:     []string{"system:authenticated", "customer:admins"},
+			validationErr:  microerror.Maskf(testDeniedError, "update denied"),
+			enforcement:    "bypass:\n  groups:\n  - giantswarm:sre\n",
+			expectedError:  testDeniedError,
+			expectedEvents: 1,
+		},
+		{
+			name: "Allow Cluster update the candidate handler in shadow mode denies",
+			object: builder.BuildCluster(
+				builder.Name("ab123"),
+				builder.Labels(map[string]string{
+					label.ReleaseVersion: legacyRelease,
+				})),
+			oldObject: builder.BuildCluster(
+				builder.Name("ab123"),
+				builder.Labels(map[string]string{
+					label.ReleaseVersion: legacyRelease,
+				})),
+			operation:    admission.Update,
+			candidateErr: microerror.Maskf(testDeniedError, "update denied by candidate"),
+			shadow:       metrics.ShadowDecisionDivergence,
+		},
+		{
+			name: "Deny Cluster creation the candidate handler in shadow mode denies too",
+			object: builder.BuildCluster(
+				builder.Name("ab123"),
+				builder.Labels(map[string]string{
+					label.ReleaseVersion: legacyRelease,
+				})),
+			operation:     admission.Create,
+			validationErr: microerror.Maskf(testDeniedError, "create denied"),
+			expectedError: testDeniedError,
+			candidateErr:  microerror.Maskf(testDeniedError, "create denied by candidate"),
+			shadow:        metrics.ShadowMatch,
+		},
+		{
+			name: "Allow Cluster creation without waiting for the candidate handler in shadow mode",
+			object: builder.BuildCluster(
+				builder.Name("ab123"),
+				builder.Labels(map[string]string{
+					label.ReleaseVersion: legacyRelease,
+				})),
+			operation:       admission.Create,
+			candidateBlocks: true,
+			shadow:          metrics.ShadowMatch,
+		},
+		{
+			name: "Allow Cluster creation the candidate handler disabled in the candidate enforcement configuration would deny",
+			object: builder.BuildCluster(
+				builder.Name("ab123"),
+				builder.Labels(map[string]string{
+					label.ReleaseVersion: legacyRelease,
+				})),
+			operation:            admission.Create,
+			candidateErr:         microerror.Maskf(testDeniedError, "create denied by candidate"),
+			candidateEnforcement: "handlers:\n  mock_type:\n    enabled: false\n",
+			shadow:               metrics.ShadowMatch,
+		},
 	}
 
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			var err error
-			ctx := context.Background()
-			logger, _ := micrologger.New(micrologger.Config{})
-			fakeK8sClient := unittest.FakeK8sClient()
-			ctrlClient := fakeK8sClient.CtrlClient()
-			loadReleases(t, ctx, ctrlClient)
-
-			//
-			// We want to test webhook HTTP handler for validating create/update requests. For creating
-			// the handler we will use HttpHandlerFactory to create the HTTP handler, so here we
-			// are basically testing the handlers created by the factory.
-			//
-			// Validation logic itself here does not matter, so we are using a generic
-			// WebhookHandlerMock as a WebhookCreateHandler/WebhookUpdateHandler interface implementation.
-			//
-			var httpHandlerFactory *HttpHandlerFactory
-			{
-				c := HttpHandlerFactoryConfig{
-					CtrlReader: ctrlClient, // Passing client here, for the sake of simpler test code
-					CtrlClient: ctrlClient,
-					Logger:     logger,
+	for _, version := range []string{admissionreview.V1, admissionreview.V1beta1} {
+		for _, tc := range testCases {
+			t.Run(fmt.Sprintf("%s %s", version, tc.name), func(t *testing.T) {
+				var err error
+				ctx := context.Background()
+				logger, _ := micrologger.New(micrologger.Config{})
+				fakeK8sClient := unittest.FakeK8sClient()
+				ctrlClient := fakeK8sClient.CtrlClient()
+				loadReleases(t, ctx, ctrlClient)
+
+				//
+				// We want to test webhook HTTP handler for validating create/update requests. For creating
+				// the handler we will use HttpHandlerFactory to create the HTTP handler, so here we
+				// are basically testing the handlers created by the factory.
+				//
+				// Validation logic itself here does not matter, so we are using a generic
+				// WebhookHandlerMock as a WebhookCreateHandler/WebhookUpdateHandler interface implementation.
+				//
+				var httpHandlerFactory *HttpHandlerFactory
+				{
+					auditSink, err := audit.NewSink(audit.SinkConfig{
+						Writer: ioutil.Discard,
+					})
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					var enforcementConfigFile string
+					if tc.enforcement != "" {
+						enforcementConfigFile = filepath.Join(t.TempDir(), "enforcement.yaml")
+						err = ioutil.WriteFile(enforcementConfigFile, []byte(tc.enforcement), 0600)
+						if err != nil {
+							t.Fatal(err)
+						}
+					}
+
+					enforcementStore, err := enforcement.NewStore(enforcement.StoreConfig{
+						Logger: logger,
+						Path:   enforcementConfigFile,
+					})
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					c := HttpHandlerFactoryConfig{
+						AuditSink:   auditSink,
+						CtrlReader:  ctrlClient, // Passing client here, for the sake of simpler test code
+						CtrlClient:  ctrlClient,
+						Enforcement: enforcementStore,
+						Logger:      logger,
+					}
+					if tc.candidateEnforcement != "" {
+						candidateEnforcementConfigFile := filepath.Join(t.TempDir(), "candidate-enforcement.yaml")
+						err = ioutil.WriteFile(candidateEnforcementConfigFile, []byte(tc.candidateEnforcement), 0600)
+						if err != nil {
+							t.Fatal(err)
+						}
+
+						c.CandidateEnforcement, err = enforcement.NewStore(enforcement.StoreConfig{
+							Logger: logger,
+							Path:   candidateEnforcementConfigFile,
+						})
+						if err != nil {
+							t.Fatal(err)
+						}
+					}
+					httpHandlerFactory, err = NewHttpHandlerFactory(c)
+					if err != nil {
+						t.Fatal(err)
+					}
 				}
-				httpHandlerFactory, err = NewHttpHandlerFactory(c)
+
+				webhookHandlerMock := WebhookHandlerMock{
+					DecodeFunc: func(runtime.RawExtension) (metav1.ObjectMetaAccessor, error) {
+						return tc.object, nil
+					},
+					Err:      tc.validationErr,
+					Warnings: tc.warnings,
+				}
+
+				// The warnings of the candidate handler must not be returned.
+				candidateMock := WebhookHandlerMock{
+					Err:      tc.candidateErr,
+					Warnings: []string{"candidate warning"},
+				}
+				release := make(chan struct{})
+				if tc.candidateBlocks {
+					candidateMock.Release = release
+				}
+
+				var httpHandler http.HandlerFunc
+				var operation string
+				switch {
+				case tc.operation == admission.Create && tc.shadow == "":
+					httpHandler = httpHandlerFactory.NewCreateHandler(&webhookHandlerMock)
+					operation = metrics.OperationCreate
+				case tc.operation == admission.Create:
+					httpHandler = httpHandlerFactory.NewShadowCreateHandler(&webhookHandlerMock, &candidateMock)
+					operation = metrics.OperationCreate
+				case tc.operation == admission.Update && tc.shadow == "":
+					httpHandler = httpHandlerFactory.NewUpdateHandler(&webhookHandlerMock)
+					operation = metrics.OperationUpdate
+				case tc.operation == admission.Update:
+					httpHandler = httpHandlerFactory.NewShadowUpdateHandler(&webhookHandlerMock, &candidateMock)
+					operation = metrics.OperationUpdate
+				default:
+					t.Fatal("Unsupported operation")
+				}
+				shadowEvaluationsBefore := shadowEvaluations(t, operation, tc.shadow)
+
+				//
+				// Now that we have an HTTP handler to test, we want to send a request to it.
+				//
+				// That request will contain admission review for creating/updating an object. This is
+				// basically the request body that API server would send to the webhook.
+				//
+				admissionReviewJson := getAdmissionReview(t, version, tc.operation, tc.dryRun, tc.userGroups, tc.object, tc.oldObject)
+				request := getHttpRequest(t, admissionReviewJson)
+
+				//
+				// Finally let's call the webhook HTTP handler and get the response.
+				//
+				// Since we this a test, we will not call the handler with the ResponseWriter, but with
+				// a ResponseRecorder from httptest package, so we can easily check the response.
+				//
+				httpRecorder := httptest.NewRecorder()
+				httpHandler.ServeHTTP(httpRecorder, request)
+
+				var admissionReview admission.AdmissionReview
+				err = json.Unmarshal(httpRecorder.Body.Bytes(), &admissionReview)
 				if err != nil {
 					t.Fatal(err)
 				}
-			}
-
-			webhookHandlerMock := WebhookHandlerMock{
-				DecodeFunc: func(runtime.RawExtension) (metav1.ObjectMetaAccessor, error) {
-					return tc.object, nil
-				},
-			}
 
-			var httpHandler http.HandlerFunc
-			switch tc.operation {
-			case admission.Create:
-				httpHandler = httpHandlerFactory.NewCreateHandler(&webhookHandlerMock)
-			case admission.Update:
-				httpHandler = httpHandlerFactory.NewUpdateHandler(&webhookHandlerMock)
-			default:
-				t.Fatal("Unsupported operation")
-			}
+				// The response must be sent with the same apiVersion as the request.
+				if admissionReview.APIVersion != version {
+					t.Fatalf("expected response apiVersion %q, got %q", version, admissionReview.APIVersion)
+				}
 
-			//
-			// Now that we have an HTTP handler to test, we want to send a request to it.
-			//
-			// That request will contain admission review for creating/updating an object. This is
-			// basically the request body that API server would send to the webhook.
-			//
-			admissionReviewJson := getAdmissionReview(t, tc.operation, tc.object, tc.oldObject)
-			request := getHttpRequest(t, admissionReviewJson)
-
-			//
-			// Finally let's call the webhook HTTP handler and get the response.
-			//
-			// Since we this a test, we will not call the handler with the ResponseWriter, but with
-			// a ResponseRecorder from httptest package, so we can easily check the response.
-			//
-			httpRecorder := httptest.NewRecorder()
-			httpHandler.ServeHTTP(httpRecorder, request)
-
-			var admissionReview admission.AdmissionReview
-			err = json.Unmarshal(httpRecorder.Body.Bytes(), &admissionReview)
-			if err != nil {
-				t.Fatal(err)
-			}
+				// Warnings are not part of the AdmissionResponse type we depend on, so
+				// they are decoded separately.
+				var warnings struct {
+					Response struct {
+						Warnings []string `json:"warnings"`
+					} `json:"response"`
+				}
+				err = json.Unmarshal(httpRecorder.Body.Bytes(), &warnings)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(warnings.Response.Warnings) != len(tc.warnings)+tc.bypassWarnings {
+					t.Fatalf("expected %d warnings, got %v", len(tc.warnings)+tc.bypassWarnings, warnings.Response.Warnings)
+				}
+				if len(tc.warnings) > 0 && !reflect.DeepEqual(warnings.Response.Warnings[:len(tc.warnings)], tc.warnings) {
+					t.Fatalf("expected warnings %v, got %v", tc.warnings, warnings.Response.Warnings)
+				}
 
-			//
-			// Now let's check the handler response.
-			//
-			if !admissionReview.Response.Allowed {
-				// webhook handler returned an error and it is rejecting the request
+				//
+				// Now let's check the handler response.
+				//
+				if !admissionReview.Response.Allowed {
+					// webhook handler returned an error and it is rejecting the request
 
-				if tc.expectedError == nil {
-					// we did not expect any errors
-					msg := "Request is not allowed."
+					if tc.expectedError == nil {
+						// we did not expect any errors
+						msg := "Request is not allowed."
 
-					if admissionReview.Response.Result != nil {
-						msg += fmt.Sprintf(" Got status code %d. Error message: %s.",
-							admissionReview.Response.Result.Code,
-							admissionReview.Response.Result.Message)
+						if admissionReview.Response.Result != nil {
+							msg += fmt.Sprintf(" Got status code %d. Error message: %s.",
+								admissionReview.Response.Result.Code,
+								admissionReview.Response.Result.Message)
+						}
+
+						t.Error(msg)
+					} else {
+						// we expected an error, let's check if we got what we wanted
+						expectedErrorMessage := microerror.Mask(tc.expectedError).Error()
+						returnedErrorMessage := ""
+
+						if admissionReview.Response.Result != nil {
+							// we set error messages in the Result, let's get it from there
+							returnedErrorMessage = admissionReview.Response.Result.Message
+						}
+
+						// Full error message can have more info, but it contains expected error message.
+						if !strings.Contains(returnedErrorMessage, expectedErrorMessage) {
+							msg := "Request is not allowed."
+							msg += fmt.Sprintf(" Got status code %d. Expected error message '%s', got '%s'.",
+								admissionReview.Response.Result.Code,
+								expectedErrorMessage,
+								returnedErrorMessage)
+
+							t.Fatalf(msg)
+						}
 					}
+				}
 
-					t.Error(msg)
-				} else {
-					// we expected an error, let's check if we got what we wanted
-					expectedErrorMessage := microerror.Mask(tc.expectedError).Error()
-					returnedErrorMessage := ""
-
-					if admissionReview.Response.Result != nil {
-						// we set error messages in the Result, let's get it from there
-						returnedErrorMessage = admissionReview.Response.Result.Message
+				// The response must not wait for the candidate handler.
+				close(release)
+				httpHandlerFactory.Wait()
+				if tc.shadow != "" {
+					evaluations := shadowEvaluations(t, operation, tc.shadow) - shadowEvaluationsBefore
+					if evaluations != 1 {
+						t.Fatalf("expected 1 shadow evaluation with result %q, got %f", tc.shadow, evaluations)
 					}
+				}
 
-					// Full error message can have more info, but it contains expected error message.
-					if !strings.Contains(returnedErrorMessage, expectedErrorMessage) {
-						msg := "Request is not allowed."
-						msg += fmt.Sprintf(" Got status code %d. Expected error message '%s', got '%s'.",
-							admissionReview.Response.Result.Code,
-							expectedErrorMessage,
-							returnedErrorMessage)
-
-						t.Fatalf(msg)
+				// Denials are recorded as events on the object, unless it is a dry run.
+				var eventList corev1.EventList
+				err = ctrlClient.List(ctx, &eventList)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(eventList.Items) != tc.expectedEvents {
+					t.Fatalf("expected %d events, got %d", tc.expectedEvents, len(eventList.Items))
+				}
+				for _, event := range eventList.Items {
+					if event.Type != corev1.EventTypeWarning {
+						t.Fatalf("expected event type %q, got %q", corev1.EventTypeWarning, event.Type)
+					}
+					if event.InvolvedObject.Kind != "Cluster" || event.InvolvedObject.Name != tc.object.GetObjectMeta().GetName() {
+						t.Fatalf("expected event on Cluster %q, got %#v", tc.object.GetObjectMeta().GetName(), event.InvolvedObject)
+					}
+					if event.Reason != string(metav1.StatusReasonInvalid) {
+						t.Fatalf("expected event reason %q, got %q", metav1.StatusReasonInvalid, event.Reason)
 					}
 				}
+			})
+		}
+	}
+}
+
+// shadowEvaluations returns the number of shadow evaluations of the mock
+// handler for the specified operation with the specified result.
+func shadowEvaluations(t *testing.T, operation, result string) float64 {
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, family := range families {
+		if family.GetName() != "azure_admission_controller_webhook_shadow_evaluations_total" {
+			continue
+		}
+
+		for _, m := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
 			}
-		})
+			if labels["resource"] == "mock_type" && labels["operation"] == operation && labels["webhook"] == metrics.WebhookValidate && labels["result"] == result {
+				return m.GetCounter().GetValue()
+			}
+		}
 	}
+
+	return 0
 }
 
 func getHttpRequest(t *testing.T, admissionReview []byte) *http.Request {
@@ -210,18 +539,29 @@ func getHttpRequest(t *testing.T, admissionReview []byte) *http.Request {
 	return request
 }
 
-func getAdmissionReview(t *testing.T, operation admission.Operation, object runtime.Object, oldObject runtime.Object) []byte {
+func getAdmissionReview(t *testing.T, version string, operation admission.Operation, dryRun bool, userGroups []string, object runtime.Object, oldObject runtime.Object) []byte {
 	objectJson, err := json.Marshal(object)
 	if err != nil {
 		t.Fatal(err)
 	}
 
+	gvk := object.GetObjectKind().GroupVersionKind()
 	admissionRequest := &admission.AdmissionRequest{
+		Kind: metav1.GroupVersionKind{
+			Group:   gvk.Group,
+			Version: gvk.Version,
+			Kind:    gvk.Kind,
+		},
 		Resource: metav1.GroupVersionResource{
 			Version:  object.GetObjectKind().GroupVersionKind().GroupVersion().String(),
 			Resource: object.GetObjectKind().GroupVersionKind().Kind,
 		},
 		Operation: operation,
+		DryRun:    &dryRun,
+		UserInfo: authenticationv1.UserInfo{
+			Username: "jane",
+			Groups:   userGroups,
+		},
 		Object: runtime.RawExtension{
 			Raw:    objectJson,
 			Object: nil,
@@ -241,8 +581,11 @@ func getAdmissionReview(t *testing.T, operation admission.Operation, object runt
 	}
 
 	admissionReview := admission.AdmissionReview{
-		TypeMeta: metav1.TypeMeta{},
-		Request:  admissionRequest,
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "AdmissionReview",
+			APIVersion: version,
+		},
+		Request: admissionRequest,
 	}
 
 	admissionReviewJson, err := json.Marshal(admissionReview)
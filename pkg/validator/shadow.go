@@ -0,0 +1,107 @@
+package validator
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/azure-admission-controller/pkg/enforcement"
+	"github.com/giantswarm/azure-admission-controller/pkg/generic"
+	"github.com/giantswarm/azure-admission-controller/pkg/metrics"
+)
+
+// shadowTimeout is how long the candidate handler may take. Slower candidates
+// are counted as timeouts and not compared.
+const shadowTimeout = 10 * time.Second
+
+// startShadow validates a copy of the specified object with the specified
+// validation function of a candidate handler in the background and returns
+// the channel its result is sent to. The candidate has its own warnings and
+// violations, so it can't change the response, and it always runs as a dry
+// run. It is not cancelled with the request, but bounded by shadowTimeout, and
+// its result is not sent when it runs out of time. Panics are turned into
+// errors. The candidate is evaluated with the candidate enforcement
+// configuration when the factory has one, and skipped like the live handler
+// when it disables the handler of the specified resource.
+func (h *HttpHandlerFactory) startShadow(ctx context.Context, resource string, object metav1.ObjectMetaAccessor, validate func(ctx context.Context, object interface{}) error) <-chan error {
+	ctx = generic.Detach(ctx)
+	ctx = generic.WithWarnings(ctx)
+	ctx = generic.WithDryRun(ctx, true)
+	if h.candidateEnforcement != nil {
+		ctx = enforcement.WithConfig(ctx, h.candidateEnforcement.Config(), resource)
+	} else {
+		ctx = enforcement.WithViolations(ctx)
+	}
+	object = generic.DeepCopy(object)
+
+	ctx, cancel := context.WithTimeout(ctx, shadowTimeout)
+	result := make(chan error, 1)
+	go func() {
+		defer cancel()
+		defer func() {
+			if r := recover(); r != nil {
+				result <- microerror.Maskf(shadowPanicError, "%v\n%s", r, debug.Stack())
+			}
+		}()
+
+		var err error
+		if enforcement.HandlerEnabled(ctx) {
+			err = validate(ctx, object)
+		}
+		if ctx.Err() == nil {
+			result <- err
+		}
+	}()
+
+	return result
+}
+
+// compareShadow compares the result of the candidate handler started with
+// startShadow with the decision of the live handler in the background, so
+// that the response is never delayed by the candidate, and logs and counts it
+// when it diverges.
+func (h *HttpHandlerFactory) compareShadow(webhookHandler WebhookHandlerBase, operation string, request *admissionv1.AdmissionRequest, liveErr error, shadow <-chan error) {
+	h.shadows.Add(1)
+	go func() {
+		defer h.shadows.Done()
+
+		var candidateErr error
+		select {
+		case candidateErr = <-shadow:
+		case <-time.After(shadowTimeout):
+			metrics.ObserveShadowEvaluation(webhookHandler.Resource(), operation, metrics.WebhookValidate, metrics.ShadowTimeout)
+			webhookHandler.Log("level", "warning", "message", fmt.Sprintf("candidate handler did not validate %s of %s %s/%s within %s", operation, request.Kind.Kind, request.Namespace, request.Name, shadowTimeout), "uid", request.UID)
+			return
+		}
+
+		if (liveErr == nil) == (candidateErr == nil) {
+			metrics.ObserveShadowEvaluation(webhookHandler.Resource(), operation, metrics.WebhookValidate, metrics.ShadowMatch)
+			return
+		}
+
+		metrics.ObserveShadowEvaluation(webhookHandler.Resource(), operation, metrics.WebhookValidate, metrics.ShadowDecisionDivergence)
+		live, candidate := decision(liveErr), decision(candidateErr)
+		webhookHandler.Log("level", "warning", "message", fmt.Sprintf("candidate handler %s %s of %s %s/%s, live handler %s it", candidate, operation, request.Kind.Kind, request.Namespace, request.Name, live), "uid", request.UID, "live", errorMessage(liveErr), "candidate", errorMessage(candidateErr))
+	}()
+}
+
+func decision(err error) string {
+	if err != nil {
+		return "denied"
+	}
+
+	return "allowed"
+}
+
+func errorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	return err.Error()
+}
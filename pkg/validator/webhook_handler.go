@@ -12,11 +12,19 @@ type WebhookHandlerBase interface {
 	Resource() string
 }
 
+// WebhookCreateHandler validates create requests. OnCreateValidate denies the
+// request by returning an error. Non-fatal findings can be reported with
+// generic.AddWarning on the passed context, they are returned to the user as
+// admission warnings whether the request is allowed or not.
 type WebhookCreateHandler interface {
 	WebhookHandlerBase
 	OnCreateValidate(ctx context.Context, object interface{}) error
 }
 
+// WebhookUpdateHandler validates update requests. OnUpdateValidate denies the
+// request by returning an error. Non-fatal findings can be reported with
+// generic.AddWarning on the passed context, they are returned to the user as
+// admission warnings whether the request is allowed or not.
 type WebhookUpdateHandler interface {
 	WebhookHandlerBase
 	OnUpdateValidate(ctx context.Context, oldObject interface{}, object interface{}) error
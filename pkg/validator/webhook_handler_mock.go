@@ -5,10 +5,16 @@ import (
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/giantswarm/azure-admission-controller/pkg/generic"
 )
 
 type WebhookHandlerMock struct {
 	DecodeFunc func(runtime.RawExtension) (metav1.ObjectMetaAccessor, error)
+	Err        error
+	Warnings   []string
+	// Release, when set, blocks the validation until it is closed.
+	Release <-chan struct{}
 }
 
 func (h *WebhookHandlerMock) Log(_ ...interface{}) {}
@@ -21,10 +27,30 @@ func (h *WebhookHandlerMock) Decode(object runtime.RawExtension) (metav1.ObjectM
 	return h.DecodeFunc(object)
 }
 
-func (h *WebhookHandlerMock) OnCreateValidate(_ context.Context, _ interface{}) error {
-	return nil
+func (h *WebhookHandlerMock) OnCreateValidate(ctx context.Context, _ interface{}) error {
+	return h.validate(ctx)
+}
+
+func (h *WebhookHandlerMock) OnUpdateValidate(ctx context.Context, _ interface{}, _ interface{}) error {
+	return h.validate(ctx)
+}
+
+// validate returns Err from a check named "mockCheck", so that it can be
+// configured in the enforcement configuration.
+func (h *WebhookHandlerMock) validate(ctx context.Context) error {
+	if h.Release != nil {
+		<-h.Release
+	}
+	h.addWarnings(ctx)
+
+	var errs ErrorList
+	errs.Check(ctx, "mockCheck", "", func() error { return h.Err })
+
+	return errs.ToAggregate()
 }
 
-func (h *WebhookHandlerMock) OnUpdateValidate(_ context.Context, _ interface{}, _ interface{}) error {
-	return nil
+func (h *WebhookHandlerMock) addWarnings(ctx context.Context) {
+	for _, w := range h.Warnings {
+		generic.AddWarning(ctx, "%s", w)
+	}
 }